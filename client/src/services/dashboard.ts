@@ -0,0 +1,13 @@
+import axiosClient from "./api-client";
+
+export const fetchDashboard = async (schoolYear?: string) => {
+  try {
+    const response = await axiosClient.get("/dashboard", {
+      params: schoolYear ? { school_year: schoolYear } : undefined,
+    });
+    return response.data;
+  } catch (error) {
+    console.error("Error fetching dashboard data", error);
+    throw error;
+  }
+};
@@ -34,10 +34,8 @@ export const enrollStudent = async (
   } catch (err) {
     const error = err as AxiosError<APIerror>;
 
-    if (
-      error.response?.data.error === "student with that record already exist"
-    ) {
-      throw new Error(error.response.data.error);
+    if (error.response?.data.code === "duplicate_student") {
+      throw new Error(error.response.data.detail);
     } else {
       console.error("Error enrolling new student", error);
       throw new Error("Something went wrong while enrolling the student");
@@ -53,10 +51,8 @@ export const deleteEnrollment = async (enrollmentID: string | undefined) => {
   } catch (err) {
     const error = err as AxiosError<APIerror>;
 
-    if (
-      error.response?.data.error === "student with that record already exist"
-    ) {
-      throw new Error(error.response.data.error);
+    if (error.response?.data.code === "duplicate_student") {
+      throw new Error(error.response.data.detail);
     } else {
       console.error("Error enrolling new student", error);
       throw new Error("Something went wrong while enrolling the student");
@@ -0,0 +1,23 @@
+import axiosClient from "./api-client";
+
+export const fetchSections = async (schoolYear?: string, gradeLevel?: string) => {
+  try {
+    const response = await axiosClient.get("/sections", {
+      params: { school_year: schoolYear, grade_level: gradeLevel },
+    });
+    return response.data;
+  } catch (error) {
+    console.error("Error fetching sections", error);
+    throw error;
+  }
+};
+
+export const fetchClassList = async (sectionID: string) => {
+  try {
+    const response = await axiosClient.get(`/sections/${sectionID}/class-list`);
+    return response.data;
+  } catch (error) {
+    console.error("Error fetching class list", error);
+    throw error;
+  }
+};
@@ -1,4 +1,11 @@
+// APIerror is the RFC 9457 problem details body returned for every error.
 export interface APIerror {
-  error: string;
-  message: string;
+  type: string;
+  title: string;
+  status: number;
+  detail?: string;
+  instance?: string;
+  code: string;
+  request_id?: string;
+  errors?: Record<string, { code: string; message: string }>;
 }
@@ -0,0 +1,33 @@
+import { Student } from "./student";
+
+export type ApplicantStatus =
+  | "inquiry"
+  | "applied"
+  | "assessed"
+  | "accepted"
+  | "rejected"
+  | "converted";
+
+export type ApplicantDocumentType = "birth_certificate" | "report_card";
+
+export interface ApplicantDocument {
+  applicant_id: string;
+  type: ApplicantDocumentType;
+  received_on: string | null;
+  notes: string;
+}
+
+export interface Applicant {
+  id: string;
+  school_year: string;
+  grade_level: string;
+  status: ApplicantStatus;
+  student: Student;
+  notes: string;
+  enrollment_id: string | null;
+  documents?: ApplicantDocument[];
+  missing_documents: ApplicantDocumentType[];
+  status_changed_at: string;
+  created_at: string;
+  updated_at: string;
+}
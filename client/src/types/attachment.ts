@@ -0,0 +1,18 @@
+export type AttachmentContentType =
+  | "application/pdf"
+  | "image/jpeg"
+  | "image/png"
+  | "image/webp";
+
+export interface Attachment {
+  id: string;
+  student_id: string | null;
+  enrollment_id: string | null;
+  payment_id: string | null;
+  file_name: string;
+  content_type: AttachmentContentType;
+  size: number;
+  checksum_sha256: string;
+  description: string;
+  created_at: string;
+}
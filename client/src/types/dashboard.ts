@@ -0,0 +1,39 @@
+export interface GroupCount {
+  group: string;
+  count: number;
+}
+
+export interface MethodTotal {
+  payment_method: "cash" | "gcash" | "bank";
+  amount: string;
+}
+
+export interface Dashboard {
+  school_year: string;
+  enrollments: {
+    total: number;
+    withdrawn: number;
+    by_grade_level: GroupCount[] | null;
+    by_type: GroupCount[] | null;
+    by_gender: GroupCount[] | null;
+  };
+  collections: {
+    total_billed: string;
+    total_collected: string;
+    total_outstanding: string;
+    collection_rate: string;
+    today: MethodTotal[];
+    this_month: MethodTotal[];
+  };
+  top_debtors: {
+    enrollment_id: string;
+    full_name: string;
+    grade_level: string;
+    remaining_amount: string;
+  }[] | null;
+  discounts: {
+    type: string;
+    count: number;
+    amount: string;
+  }[] | null;
+}
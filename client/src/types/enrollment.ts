@@ -15,6 +15,8 @@ export interface EnrollmentTable {
 }
 
 export interface StudentEnrollmentDetails extends EnrollmentTable {
+  section_id: string | null;
+  section_name: string | null;
   student: Student;
 }
 
@@ -37,4 +39,5 @@ export interface EnrollStudent extends DiscountTypes {
   pta_fee: number;
   lms_books_fee: number;
   discounts: string[];
+  waitlist?: boolean;
 }
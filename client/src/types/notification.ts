@@ -0,0 +1,49 @@
+export type GuardianRelationship = "mother" | "father" | "guardian";
+
+export interface Guardian {
+  id: string;
+  student_id: string;
+  name: string;
+  relationship: GuardianRelationship;
+  email: string | null;
+  phone: string | null;
+  email_opt_out: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export type NotificationKind = "receipt" | "upcoming_due" | "overdue";
+
+export type NotificationStatus = "pending" | "sent" | "failed" | "cancelled";
+
+export interface NotificationData {
+  guardian_name: string;
+  student_name: string;
+  school_year: string;
+  grade_level: string;
+  total_paid: string;
+  remaining_amount: string;
+  invoice_number?: string;
+  payment_date?: string;
+  payment_method?: string;
+  amount?: string;
+  installment?: number;
+  due_date?: string;
+  amount_due?: string;
+}
+
+export interface Notification {
+  id: string;
+  kind: NotificationKind;
+  guardian_id: string | null;
+  enrollment_id: string;
+  payment_id: string | null;
+  recipient: string;
+  data: NotificationData;
+  status: NotificationStatus;
+  attempts: number;
+  next_attempt_at: string;
+  last_error: string;
+  sent_at: string | null;
+  created_at: string;
+}
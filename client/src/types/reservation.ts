@@ -0,0 +1,32 @@
+import { GroupCount } from "./dashboard";
+
+export type ReservationStatus = "reserved" | "converted" | "expired" | "forfeited";
+
+export interface Reservation {
+  id: string;
+  student_id: string;
+  full_name: string;
+  school_year: string;
+  grade_level: string;
+  invoice_number: string;
+  fee: string;
+  payment_method: "cash" | "gcash" | "bank";
+  reserved_on: string;
+  expires_on: string;
+  status: ReservationStatus;
+  enrollment_id: string | null;
+  notes: string;
+  closed_at: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ReservationReport {
+  school_year: string;
+  count: number;
+  total_fees: string;
+  expiring_soon: number;
+  overdue: number;
+  by_grade_level: GroupCount[] | null;
+  reservations: Reservation[] | null;
+}
@@ -0,0 +1,36 @@
+export interface Section {
+  id: string;
+  school_year: string;
+  grade_level: string;
+  name: string;
+  adviser: string;
+  capacity: number;
+  enrolled: number;
+  male: number;
+  female: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ClassListStudent {
+  enrollment_id: string;
+  student_id: string;
+  last_name: string;
+  first_name: string;
+  middle_name: string;
+  suffix: string;
+  gender: "male" | "female";
+  birthdate: string;
+  contact_numbers: string[] | null;
+}
+
+export interface ClassList {
+  section: Section;
+  students: ClassListStudent[];
+}
+
+export interface SectionBalance {
+  assigned: number;
+  unassigned: number;
+  sections: Section[] | null;
+}
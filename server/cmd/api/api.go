@@ -6,10 +6,15 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/edzhabs/bookkeeping/internal/env"
+	"github.com/edzhabs/bookkeeping/internal/clientip"
+	"github.com/edzhabs/bookkeeping/internal/config"
+	"github.com/edzhabs/bookkeeping/internal/filestore"
+	"github.com/edzhabs/bookkeeping/internal/metrics"
+	"github.com/edzhabs/bookkeeping/internal/notify"
 	"github.com/edzhabs/bookkeeping/internal/ratelimiter"
 	"github.com/edzhabs/bookkeeping/internal/store"
 	"github.com/go-chi/chi/middleware"
@@ -19,44 +24,48 @@ import (
 )
 
 type application struct {
-	config      config
+	config      config.Config
 	logger      *zap.SugaredLogger
 	ratelimiter ratelimiter.Limiter
-	store       store.Storage
-}
-
-type config struct {
-	addr        string
-	env         string
-	db          dbConfig
-	rateLimiter ratelimiter.Config
-}
-
-type dbConfig struct {
-	addr         string
-	maxOpenConns int
-	maxIdleConns int
-	maxIdleTime  string
+	// authRatelimiter applies the stricter policy to routes that check
+	// credentials, to slow down password guessing.
+	authRatelimiter ratelimiter.Limiter
+	allowList       clientip.Prefixes
+	denyList        clientip.Prefixes
+	throttled       *ratelimiter.ThrottleTracker
+	clientIP        *clientip.Resolver
+	metrics         *metrics.Metrics
+	// schemaVersion is the latest embedded migration, checked at startup.
+	schemaVersion uint
+	// draining is set once shutdown starts so readiness fails while
+	// in-flight requests finish.
+	draining atomic.Bool
+	store    store.Storage
+	files    filestore.Store
+	mailer   notify.Sender
 }
 
 func (app *application) mount() http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
+	r.Use(app.ClientIPMiddleware)
+	r.Use(app.TracingMiddleware)
+	r.Use(app.AccessLogMiddleware)
+	r.Use(app.MetricsMiddleware)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{env.GetString("CORS_ALLOWED_ORIGIN", "http://localhost:5173")},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedOrigins: app.config.CORS.AllowedOrigins,
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "Idempotency-Key"},
+		ExposedHeaders: []string{"Link", "ETag", "Idempotent-Replayed", "Retry-After", "X-Request-Id",
+			"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
 
-	if app.config.rateLimiter.Enabled {
-		r.Use(app.RateLimiterMiddleware)
+	if app.config.RateLimiter.Enabled {
+		r.Use(app.RateLimiterMiddleware(app.ratelimiter))
 	}
 
 	// Set a timeout value on the request context (ctx), that will signal
@@ -64,13 +73,17 @@ func (app *application) mount() http.Handler {
 	// processing should be stopped.
 	r.Use(middleware.Timeout(60 * time.Second))
 
+	r.Handle("/metrics", app.metrics.Handler())
+
 	r.Route("/api", func(r chi.Router) {
 		r.Get("/health", app.healthCheckHandler)
+		r.Get("/health/live", app.healthCheckHandler)
+		r.Get("/health/ready", app.readinessHandler)
 
 		r.Route("/enrollments", func(r chi.Router) {
 			r.Get("/", app.getEnrollmentsHandler)
-			r.Post("/new", app.createNewEnrollmentHandler)
-			r.Post("/existing", app.createOldEnrollmentHandler)
+			r.With(app.IdempotencyMiddleware).Post("/new", app.createNewEnrollmentHandler)
+			r.With(app.IdempotencyMiddleware).Post("/existing", app.createOldEnrollmentHandler)
 
 			r.Route("/{enrollmentID}", func(r chi.Router) {
 				r.Use(app.enrollmentIDfromURLContextMiddleware)
@@ -79,6 +92,101 @@ func (app *application) mount() http.Handler {
 				r.With(app.editEnrollmentContextMiddleware).Get("/edit", app.getEditEnrollmentHandler)
 				r.Patch("/", app.updateEnrollmentHandler)
 				r.Delete("/", app.deleteEnrollmentHandler)
+				r.Get("/withdrawal", app.getWithdrawalHandler)
+				r.Post("/withdrawal", app.withdrawEnrollmentHandler)
+				r.Get("/payments", app.getEnrollmentPaymentsHandler)
+				r.With(app.IdempotencyMiddleware).Post("/payments", app.createPaymentHandler)
+				r.Post("/promote", app.promoteEnrollmentHandler)
+				r.Put("/section", app.assignSectionHandler)
+			})
+		})
+
+		r.Get("/dashboard", app.getDashboardHandler)
+
+		r.Route("/capacities", func(r chi.Router) {
+			r.Get("/", app.getCapacityUtilizationHandler)
+			r.With(app.RateLimiterMiddleware(app.authRatelimiter), app.BasicAuthMiddleware()).Put("/", app.setCapacityHandler)
+		})
+
+		r.Route("/waitlist", func(r chi.Router) {
+			r.Get("/", app.getWaitlistHandler)
+			r.Post("/promote", app.promoteWaitlistHandler)
+		})
+
+		r.Route("/reservations", func(r chi.Router) {
+			r.Get("/", app.getReservationsHandler)
+			r.With(app.IdempotencyMiddleware).Post("/", app.createReservationHandler)
+			r.Get("/report", app.getOpenReservationsReportHandler)
+
+			r.Route("/{reservationID}", func(r chi.Router) {
+				r.Use(app.reservationContextMiddleware)
+
+				r.Get("/", app.getReservationHandler)
+				r.With(app.IdempotencyMiddleware).Post("/convert", app.convertReservationHandler)
+				r.Post("/forfeit", app.forfeitReservationHandler)
+			})
+		})
+
+		r.Route("/applicants", func(r chi.Router) {
+			r.Get("/", app.getApplicantsHandler)
+			r.Post("/", app.createApplicantHandler)
+			r.Get("/pipeline", app.getApplicantPipelineHandler)
+
+			r.Route("/{applicantID}", func(r chi.Router) {
+				r.Use(app.applicantContextMiddleware)
+
+				r.Get("/", app.getApplicantHandler)
+				r.Patch("/", app.updateApplicantHandler)
+				r.Put("/status", app.setApplicantStatusHandler)
+				r.Put("/documents/{documentType}", app.setApplicantDocumentHandler)
+				r.Delete("/documents/{documentType}", app.removeApplicantDocumentHandler)
+				r.With(app.IdempotencyMiddleware).Post("/convert", app.convertApplicantHandler)
+			})
+		})
+
+		r.Route("/attachments", func(r chi.Router) {
+			r.Get("/", app.getAttachmentsHandler)
+			r.Post("/", app.uploadAttachmentHandler)
+
+			r.Route("/{attachmentID}", func(r chi.Router) {
+				r.Use(app.attachmentContextMiddleware)
+
+				r.Get("/", app.getAttachmentHandler)
+				r.Get("/download", app.downloadAttachmentHandler)
+				r.Delete("/", app.deleteAttachmentHandler)
+			})
+		})
+
+		r.Route("/guardians", func(r chi.Router) {
+			r.Get("/", app.getGuardiansHandler)
+			r.Post("/", app.createGuardianHandler)
+
+			r.Route("/{guardianID}", func(r chi.Router) {
+				r.Use(app.guardianContextMiddleware)
+
+				r.Get("/", app.getGuardianHandler)
+				r.Patch("/", app.updateGuardianHandler)
+				r.Delete("/", app.deleteGuardianHandler)
+			})
+		})
+
+		r.Route("/notifications", func(r chi.Router) {
+			r.Get("/", app.getNotificationsHandler)
+			r.Post("/{notificationID}/retry", app.retryNotificationHandler)
+		})
+
+		r.Route("/sections", func(r chi.Router) {
+			r.Get("/", app.getSectionsHandler)
+			r.Post("/", app.createSectionHandler)
+			r.Post("/auto-assign", app.autoAssignSectionsHandler)
+
+			r.Route("/{sectionID}", func(r chi.Router) {
+				r.Use(app.sectionContextMiddleware)
+
+				r.Get("/", app.getSectionHandler)
+				r.Patch("/", app.updateSectionHandler)
+				r.Delete("/", app.deleteSectionHandler)
+				r.Get("/class-list", app.getClassListHandler)
 			})
 		})
 
@@ -86,6 +194,49 @@ func (app *application) mount() http.Handler {
 			r.Get("/dropdown", app.getStudentsDropdownHandler)
 			r.Post("/", app.createStudentHandler)
 		})
+
+		r.Route("/payments/{paymentID}", func(r chi.Router) {
+			r.Use(app.paymentContextMiddleware)
+
+			r.Get("/", app.getPaymentHandler)
+			r.Patch("/", app.updatePaymentHandler)
+			r.Delete("/", app.deletePaymentHandler)
+		})
+
+		r.Route("/expenses", func(r chi.Router) {
+			r.Get("/", app.getExpensesHandler)
+			r.Post("/", app.createExpenseHandler)
+		})
+
+		r.Route("/ledger", func(r chi.Router) {
+			r.Get("/accounts", app.getAccountsHandler)
+			r.Get("/trial-balance", app.getTrialBalanceHandler)
+			r.With(app.RateLimiterMiddleware(app.authRatelimiter), app.BasicAuthMiddleware()).Post("/adjustments", app.createAdjustmentHandler)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(app.RateLimiterMiddleware(app.authRatelimiter))
+			r.Use(app.BasicAuthMiddleware())
+
+			r.Get("/rate-limits/throttled", app.getThrottledClientsHandler)
+
+			r.Route("/balances", func(r chi.Router) {
+				r.Get("/drift", app.getBalanceDriftHandler)
+				r.Post("/rebuild", app.rebuildBalancesHandler)
+			})
+		})
+
+		r.Route("/periods", func(r chi.Router) {
+			r.Get("/", app.getClosedPeriodsHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.RateLimiterMiddleware(app.authRatelimiter))
+				r.Use(app.BasicAuthMiddleware())
+
+				r.Post("/", app.closePeriodHandler)
+				r.Delete("/{periodID}", app.reopenPeriodHandler)
+			})
+		})
 	})
 
 	return r
@@ -93,7 +244,7 @@ func (app *application) mount() http.Handler {
 
 func (app *application) run(mux http.Handler) error {
 	srv := &http.Server{
-		Addr:         app.config.addr,
+		Addr:         app.config.Addr,
 		Handler:      mux,
 		WriteTimeout: time.Second * 30,
 		ReadTimeout:  time.Second * 10,
@@ -112,11 +263,12 @@ func (app *application) run(mux http.Handler) error {
 		defer cancel()
 
 		app.logger.Infow("signal caught", "signal", s.String())
+		app.draining.Store(true)
 
 		shutdown <- srv.Shutdown(ctx)
 	}()
 
-	app.logger.Infow("server started", "addr", app.config.addr, "env", app.config.env)
+	app.logger.Infow("server started", "addr", app.config.Addr, "env", app.config.Env)
 
 	err := srv.ListenAndServe()
 	if !errors.Is(err, http.ErrServerClosed) {
@@ -128,7 +280,7 @@ func (app *application) run(mux http.Handler) error {
 		return err
 	}
 
-	app.logger.Infow("server has stopped", "addr", app.config.addr, "env", app.config.env)
+	app.logger.Infow("server has stopped", "addr", app.config.Addr, "env", app.config.Env)
 
 	return nil
 }
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/clientip"
+	"github.com/edzhabs/bookkeeping/internal/metrics"
+	"github.com/edzhabs/bookkeeping/internal/ratelimiter"
+	"go.uber.org/zap"
+)
+
+// newTestApplication returns an application with a silent logger and no
+// stores; tests fill in the dependencies they exercise.
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+
+	app := &application{
+		logger:    zap.NewNop().Sugar(),
+		throttled: ratelimiter.NewThrottleTracker(),
+		clientIP:  clientip.NewResolver(nil),
+		metrics:   metrics.New(nil, nil),
+	}
+	app.config.Idempotency.TTL = time.Hour
+
+	return app
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type applicantKey string
+
+const (
+	applicantID               = "applicantID"
+	documentType              = "documentType"
+	applicantCtx applicantKey = "applicant"
+)
+
+type ApplicantPayload struct {
+	SchoolYear string  `json:"school_year" validate:"required,schoolyear"`
+	GradeLevel string  `json:"grade_level" validate:"oneofci=nursery-1 nursery-2 kinder-1 kinder-2 grade-1 grade-2 grade-3 grade-4 grade-5 grade-6 grade-7"`
+	Student    Student `json:"student"`
+	Notes      string  `json:"notes" validate:"omitempty,max=255"`
+}
+
+type ApplicantQuery struct {
+	SchoolYear string `json:"school_year" validate:"schoolyear"`
+	Status     string `json:"status" validate:"omitempty,oneofci=inquiry applied assessed accepted rejected converted"`
+}
+
+type ApplicantStatusPayload struct {
+	Status string `json:"status" validate:"oneofci=applied assessed accepted rejected"`
+}
+
+type ApplicantDocumentPayload struct {
+	Type       string `json:"-" validate:"oneofci=birth_certificate report_card"`
+	ReceivedOn string `json:"received_on" validate:"required,datetime=2006-01-02"`
+	Notes      string `json:"notes" validate:"omitempty,max=255"`
+}
+
+// ConvertApplicantPayload carries the billing of the enrollment an accepted
+// applicant is converted into. The student, school year and grade level come
+// from the applicant.
+type ConvertApplicantPayload struct {
+	MonthlyTuition decimal.Decimal `json:"monthly_tuition" validate:"required,decimalGt"`
+	EnrollmentFee  decimal.Decimal `json:"enrollment_fee" validate:"required,decimalGt"`
+	MiscFee        decimal.Decimal `json:"misc_fee" validate:"required,decimalGt"`
+	PtaFee         decimal.Decimal `json:"pta_fee" validate:"required,decimalGt"`
+	LmsFee         decimal.Decimal `json:"lms_books_fee" validate:"required,decimalGt"`
+	AvailDiscounts []string        `json:"discounts" validate:"omitempty,discounts"`
+	Waitlist       bool            `json:"waitlist"`
+}
+
+func (app *application) createApplicantHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ApplicantPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	applicant, err := newApplicant(payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Applicants.Create(r.Context(), applicant); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusCreated, applicant); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getApplicantsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	query := ApplicantQuery{
+		SchoolYear: qs.Get("school_year"),
+		Status:     strings.ToLower(qs.Get("status")),
+	}
+	if query.SchoolYear == "" {
+		query.SchoolYear = currentSchoolYear(time.Now())
+	}
+
+	if err := utils.Validate.Struct(query); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	applicants, err := app.store.Applicants.GetAll(r.Context(), query.SchoolYear, query.Status)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, applicants); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getApplicantPipelineHandler(w http.ResponseWriter, r *http.Request) {
+	query := SchoolYearQuery{SchoolYear: r.URL.Query().Get("school_year")}
+	if query.SchoolYear == "" {
+		query.SchoolYear = currentSchoolYear(time.Now())
+	}
+
+	if err := utils.Validate.Struct(query); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	pipeline, err := app.store.Applicants.GetPipeline(r.Context(), query.SchoolYear)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, pipeline); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getApplicantHandler(w http.ResponseWriter, r *http.Request) {
+	applicant := app.getApplicantFromCtx(r)
+
+	if err := utils.ResponseJSON(w, http.StatusOK, applicant); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateApplicantHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ApplicantPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	applicant, err := newApplicant(payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	applicant.ID = app.getApplicantFromCtx(r).ID
+
+	if err := app.store.Applicants.Update(r.Context(), applicant); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	app.respondWithApplicant(w, r, applicant.ID, http.StatusOK)
+}
+
+func (app *application) setApplicantStatusHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ApplicantStatusPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	id := app.getApplicantFromCtx(r).ID
+
+	if err := app.store.Applicants.SetStatus(r.Context(), id, strings.ToLower(payload.Status)); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	app.respondWithApplicant(w, r, id, http.StatusOK)
+}
+
+func (app *application) setApplicantDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ApplicantDocumentPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payload.Type = chi.URLParam(r, documentType)
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	receivedOn, err := time.Parse(dateLayout, payload.ReceivedOn)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	document := &models.ApplicantDocument{
+		ApplicantID: app.getApplicantFromCtx(r).ID,
+		Type:        strings.ToLower(payload.Type),
+		ReceivedOn:  &receivedOn,
+		Notes:       payload.Notes,
+	}
+
+	if err := app.store.Applicants.SetDocument(r.Context(), document); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	app.respondWithApplicant(w, r, document.ApplicantID, http.StatusOK)
+}
+
+func (app *application) removeApplicantDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	docType := chi.URLParam(r, documentType)
+
+	if err := utils.Validate.Var(docType, "oneofci=birth_certificate report_card"); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	id := app.getApplicantFromCtx(r).ID
+
+	if err := app.store.Applicants.RemoveDocument(r.Context(), id, strings.ToLower(docType)); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	app.respondWithApplicant(w, r, id, http.StatusOK)
+}
+
+func (app *application) convertApplicantHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ConvertApplicantPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	enrollment := &models.Enrollment{
+		MonthlyTuition: payload.MonthlyTuition,
+		EnrollmentFee:  payload.EnrollmentFee,
+		MiscFee:        payload.MiscFee,
+		PtaFee:         payload.PtaFee,
+		LmsFee:         payload.LmsFee,
+		Discounts:      getDiscounts(payload.AvailDiscounts, payload.MonthlyTuition, payload.LmsFee),
+		AllowWaitlist:  payload.Waitlist,
+	}
+
+	if err := app.store.Applicants.Convert(r.Context(), app.getApplicantFromCtx(r).ID, enrollment); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusCreated, enrollment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) respondWithApplicant(w http.ResponseWriter, r *http.Request, id uuid.UUID, status int) {
+	applicant, err := app.store.Applicants.GetByID(r.Context(), id)
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, status, applicant); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func newApplicant(payload ApplicantPayload) (*models.Applicant, error) {
+	birthdate, err := time.Parse(dateLayout, payload.Student.Birthdate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Applicant{
+		SchoolYear: payload.SchoolYear,
+		GradeLevel: strings.ToLower(payload.GradeLevel),
+		Notes:      payload.Notes,
+		Student: &models.Student{
+			FirstName:       payload.Student.FirstName,
+			MiddleName:      payload.Student.MiddleName,
+			LastName:        payload.Student.LastName,
+			Suffix:          payload.Student.Suffix,
+			Gender:          strings.ToLower(payload.Student.Gender),
+			Birthdate:       birthdate,
+			Address:         payload.Student.Address,
+			MotherName:      payload.Student.MotherName,
+			MotherJob:       payload.Student.MotherJob,
+			MotherEducation: payload.Student.MotherEducation,
+			FatherName:      payload.Student.FatherName,
+			FatherJob:       payload.Student.FatherJob,
+			FatherEducation: payload.Student.FatherEducation,
+			ContactNumbers:  payload.Student.ContactNumbers,
+			LivingWith:      payload.Student.LivingWith,
+		},
+	}, nil
+}
+
+func (app *application) applicantContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idString := chi.URLParam(r, applicantID)
+
+		id, err := uuid.Parse(idString)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		applicant, err := app.store.Applicants.GetByID(ctx, id)
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, applicantCtx, applicant)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getApplicantFromCtx(r *http.Request) models.Applicant {
+	applicant, _ := r.Context().Value(applicantCtx).(models.Applicant)
+	return applicant
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type attachmentKey string
+
+const (
+	attachmentID                = "attachmentID"
+	attachmentCtx attachmentKey = "attachment"
+
+	// attachmentFormMemory is how much of a multipart upload is kept in
+	// memory; the rest is spooled to a temporary file.
+	attachmentFormMemory = 1 << 20
+)
+
+// attachmentContentTypes are the file types accepted for upload, as detected
+// from the file's contents rather than trusted from the client.
+var attachmentContentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+}
+
+var (
+	errAttachmentOwner = errors.New("exactly one of student_id, enrollment_id or payment_id is required")
+	errAttachmentEmpty = errors.New("file is empty")
+	errAttachmentType  = errors.New("file must be a PDF, JPEG, PNG or WebP")
+)
+
+type AttachmentPayload struct {
+	StudentID    string `json:"student_id" validate:"omitempty,uuid"`
+	EnrollmentID string `json:"enrollment_id" validate:"omitempty,uuid"`
+	PaymentID    string `json:"payment_id" validate:"omitempty,uuid"`
+	Description  string `json:"description" validate:"omitempty,max=255"`
+}
+
+// owner returns the student, enrollment or payment the payload names.
+func (p AttachmentPayload) owner() (models.AttachmentOwner, error) {
+	var owner models.AttachmentOwner
+	var count int
+
+	for _, id := range []struct {
+		value  string
+		target **uuid.UUID
+	}{
+		{p.StudentID, &owner.StudentID},
+		{p.EnrollmentID, &owner.EnrollmentID},
+		{p.PaymentID, &owner.PaymentID},
+	} {
+		if id.value == "" {
+			continue
+		}
+
+		parsed, err := uuid.Parse(id.value)
+		if err != nil {
+			return owner, err
+		}
+
+		*id.target = &parsed
+		count++
+	}
+
+	if count != 1 {
+		return owner, errAttachmentOwner
+	}
+
+	return owner, nil
+}
+
+func (app *application) uploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	maxSize := int64(app.config.Attachments.MaxSize)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+attachmentFormMemory)
+	if err := r.ParseMultipartForm(attachmentFormMemory); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			app.payloadTooLargeResponse(w, r, attachmentTooLarge(maxSize))
+			return
+		}
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	payload := AttachmentPayload{
+		StudentID:    r.FormValue("student_id"),
+		EnrollmentID: r.FormValue("enrollment_id"),
+		PaymentID:    r.FormValue("payment_id"),
+		Description:  r.FormValue("description"),
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	owner, err := payload.owner()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	if header.Size == 0 {
+		app.badRequestResponse(w, r, errAttachmentEmpty)
+		return
+	}
+	if header.Size > maxSize {
+		app.payloadTooLargeResponse(w, r, attachmentTooLarge(maxSize))
+		return
+	}
+
+	contentType, err := detectContentType(file)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if !attachmentContentTypes[contentType] {
+		app.unsupportedMediaTypeResponse(w, r, errAttachmentType)
+		return
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	id := uuid.New()
+	attachment := &models.Attachment{
+		ID:           id,
+		StudentID:    owner.StudentID,
+		EnrollmentID: owner.EnrollmentID,
+		PaymentID:    owner.PaymentID,
+		StorageKey:   attachmentStorageKey(id),
+		FileName:     attachmentFileName(header.Filename),
+		ContentType:  contentType,
+		Size:         header.Size,
+		Checksum:     hex.EncodeToString(hash.Sum(nil)),
+		Description:  payload.Description,
+	}
+
+	if err := app.files.Put(r.Context(), attachment.StorageKey, file, attachment.Size, contentType); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Attachments.Create(r.Context(), attachment); err != nil {
+		app.removeAttachmentFile(attachment.StorageKey)
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusCreated, attachment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	payload := AttachmentPayload{
+		StudentID:    qs.Get("student_id"),
+		EnrollmentID: qs.Get("enrollment_id"),
+		PaymentID:    qs.Get("payment_id"),
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	owner, err := payload.owner()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	attachments, err := app.store.Attachments.GetAll(r.Context(), owner)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, attachments); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	attachment := app.getAttachmentFromCtx(r)
+
+	if err := utils.ResponseJSON(w, http.StatusOK, attachment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) downloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	attachment := app.getAttachmentFromCtx(r)
+
+	body, err := app.files.Get(r.Context(), attachment.StorageKey)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	defer body.Close()
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", `"`+attachment.Checksum+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, body); err != nil {
+		app.logger.Warnw("failed to send attachment", "id", attachment.ID, "error", err)
+	}
+}
+
+func (app *application) deleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	attachment, err := app.store.Attachments.Delete(r.Context(), app.getAttachmentFromCtx(r).ID)
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	app.removeAttachmentFile(attachment.StorageKey)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeAttachmentFile deletes a file whose record is gone or was never
+// written. Failures only leave an orphaned file behind, so they are logged.
+func (app *application) removeAttachmentFile(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := app.files.Delete(ctx, key); err != nil {
+		app.logger.Errorw("failed to remove attachment file", "key", key, "error", err)
+	}
+}
+
+// detectContentType sniffs the media type of file from its first bytes and
+// rewinds it.
+func detectContentType(file io.ReadSeeker) (string, error) {
+	head := make([]byte, 512)
+
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil {
+		return "", err
+	}
+
+	return mediaType, nil
+}
+
+// attachmentStorageKey spreads files over directories named after the first
+// two characters of their ID, so no directory grows too large.
+func attachmentStorageKey(id uuid.UUID) string {
+	name := id.String()
+	return path.Join("attachments", name[:2], name)
+}
+
+// attachmentFileName keeps the name the file was uploaded with, without any
+// directory, trimmed to fit its column.
+func attachmentFileName(name string) string {
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, `\`, "/")))
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+
+	if utf8.RuneCountInString(name) > 255 {
+		name = string([]rune(name)[:255])
+	}
+
+	return name
+}
+
+func attachmentTooLarge(maxSize int64) error {
+	return fmt.Errorf("file must not be larger than %d bytes", maxSize)
+}
+
+func (app *application) attachmentContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idString := chi.URLParam(r, attachmentID)
+
+		id, err := uuid.Parse(idString)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		attachment, err := app.store.Attachments.GetByID(ctx, id)
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, attachmentCtx, attachment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getAttachmentFromCtx(r *http.Request) models.Attachment {
+	attachment, _ := r.Context().Value(attachmentCtx).(models.Attachment)
+	return attachment
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/utils"
+)
+
+type balanceRebuild struct {
+	Rebuilt int64 `json:"rebuilt"`
+}
+
+type balanceDriftReport struct {
+	Drifted int                   `json:"drifted"`
+	Drifts  []models.BalanceDrift `json:"drifts"`
+}
+
+func (app *application) rebuildBalancesHandler(w http.ResponseWriter, r *http.Request) {
+	rebuilt, err := app.store.Balances.Rebuild(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.logger.Infow("rebuilt enrollment balances", "count", rebuilt, "user", getUserFromCtx(r))
+
+	if err := utils.ResponseJSON(w, http.StatusOK, balanceRebuild{Rebuilt: rebuilt}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getBalanceDriftHandler(w http.ResponseWriter, r *http.Request) {
+	drifts, err := app.store.Balances.CheckDrift(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	report := balanceDriftReport{Drifted: len(drifts), Drifts: drifts}
+	if err := utils.ResponseJSON(w, http.StatusOK, report); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// checkBalanceDrift periodically compares the stored enrollment balances with
+// the computed ones and logs any that have drifted, so they can be rebuilt.
+func (app *application) checkBalanceDrift(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			drifts, err := app.store.Balances.CheckDrift(ctx)
+			if err != nil {
+				app.logger.Errorw("failed to check enrollment balances", "error", err)
+				continue
+			}
+			for _, drift := range drifts {
+				app.logger.Warnw("enrollment balance drifted",
+					"enrollment_id", drift.EnrollmentID,
+					"stored", drift.Stored,
+					"expected", drift.Expected,
+				)
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/utils"
+)
+
+type CapacityPayload struct {
+	SchoolYear string `json:"school_year" validate:"required,schoolyear"`
+	GradeLevel string `json:"grade_level" validate:"oneofci=nursery-1 nursery-2 kinder-1 kinder-2 grade-1 grade-2 grade-3 grade-4 grade-5 grade-6 grade-7"`
+	Capacity   *int   `json:"capacity" validate:"required,gte=0"`
+}
+
+type WaitlistQuery struct {
+	SchoolYear string `json:"school_year" validate:"schoolyear"`
+	GradeLevel string `json:"grade_level" validate:"omitempty,oneofci=nursery-1 nursery-2 kinder-1 kinder-2 grade-1 grade-2 grade-3 grade-4 grade-5 grade-6 grade-7"`
+}
+
+type PromoteWaitlistPayload struct {
+	SchoolYear string `json:"school_year" validate:"required,schoolyear"`
+	GradeLevel string `json:"grade_level" validate:"oneofci=nursery-1 nursery-2 kinder-1 kinder-2 grade-1 grade-2 grade-3 grade-4 grade-5 grade-6 grade-7"`
+}
+
+func (app *application) setCapacityHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CapacityPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	capacity := &models.GradeCapacity{
+		SchoolYear: payload.SchoolYear,
+		GradeLevel: strings.ToLower(payload.GradeLevel),
+		Capacity:   *payload.Capacity,
+	}
+
+	if err := app.store.Capacities.Set(r.Context(), capacity); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, capacity); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getCapacityUtilizationHandler(w http.ResponseWriter, r *http.Request) {
+	query := SchoolYearQuery{SchoolYear: r.URL.Query().Get("school_year")}
+	if query.SchoolYear == "" {
+		query.SchoolYear = currentSchoolYear(time.Now())
+	}
+
+	if err := utils.Validate.Struct(query); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	report, err := app.store.Capacities.GetUtilization(r.Context(), query.SchoolYear)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, report); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	query := WaitlistQuery{
+		SchoolYear: qs.Get("school_year"),
+		GradeLevel: strings.ToLower(qs.Get("grade_level")),
+	}
+	if query.SchoolYear == "" {
+		query.SchoolYear = currentSchoolYear(time.Now())
+	}
+
+	if err := utils.Validate.Struct(query); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	waitlist, err := app.store.Enrollments.GetWaitlist(r.Context(), query.SchoolYear, query.GradeLevel)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, waitlist); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) promoteWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PromoteWaitlistPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	promoted, err := app.store.Enrollments.PromoteWaitlist(r.Context(), payload.SchoolYear, strings.ToLower(payload.GradeLevel))
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, promoted); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) promoteEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
+	enrollmentID := app.getEnrollmentIDFromCtx(r)
+
+	if err := app.store.Enrollments.Promote(r.Context(), enrollmentID); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	enrollment, err := app.store.Enrollments.GetEnrollmentByID(r.Context(), enrollmentID)
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, enrollment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/utils"
+)
+
+type SchoolYearQuery struct {
+	SchoolYear string `json:"school_year" validate:"schoolyear"`
+}
+
+func (app *application) getDashboardHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+
+	query := SchoolYearQuery{SchoolYear: r.URL.Query().Get("school_year")}
+	if query.SchoolYear == "" {
+		query.SchoolYear = currentSchoolYear(now)
+	}
+
+	if err := utils.Validate.Struct(query); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	dashboard, err := app.store.Dashboard.Get(r.Context(), query.SchoolYear, now)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, dashboard); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// currentSchoolYear returns the school year in session on now, e.g.
+// "2025-2026" for any date from June 2025 to May 2026.
+func currentSchoolYear(now time.Time) string {
+	start := now.Year()
+	if now.Month() < constants.SchoolYearStartMonth {
+		start--
+	}
+
+	return fmt.Sprintf("%d-%d", start, start+1)
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -45,6 +47,12 @@ type Enrollment struct {
 	PtaFee         decimal.Decimal `json:"pta_fee" validate:"required,decimalGt"`
 	LmsFee         decimal.Decimal `json:"lms_books_fee" validate:"required,decimalGt"`
 	AvailDiscounts []string        `json:"discounts" validate:"omitempty,discounts"`
+	Waitlist       bool            `json:"waitlist"`
+}
+
+type WithdrawEnrollmentPayload struct {
+	WithdrawalDate string `json:"withdrawal_date" validate:"required,datetime=2006-01-02"`
+	Reason         string `json:"reason" validate:"required,trimmedSpace,max=255"`
 }
 
 type Student struct {
@@ -116,17 +124,11 @@ func (app *application) createNewEnrollmentHandler(w http.ResponseWriter, r *htt
 		PtaFee:         payload.PtaFee,
 		LmsFee:         payload.LmsFee,
 		Discounts:      discounts,
+		AllowWaitlist:  payload.Waitlist,
 	}
 
 	if err := app.store.Enrollments.Create(r.Context(), enrollment); err != nil {
-		switch err {
-		case store.ErrDuplicate:
-			app.badRequestResponse(w, r, err)
-		case store.ErrRequiredFees:
-			app.badRequestResponse(w, r, err)
-		default:
-			app.internalServerError(w, r, err)
-		}
+		app.storeErrorResponse(w, r, err)
 		return
 	}
 
@@ -164,17 +166,11 @@ func (app *application) createOldEnrollmentHandler(w http.ResponseWriter, r *htt
 		PtaFee:         payload.PtaFee,
 		LmsFee:         payload.LmsFee,
 		Discounts:      discounts,
+		AllowWaitlist:  payload.Waitlist,
 	}
 
 	if err := app.store.Enrollments.Create(r.Context(), enrollment); err != nil {
-		switch err {
-		case store.ErrDuplicate:
-			app.badRequestResponse(w, r, err)
-		case store.ErrRequiredFees:
-			app.badRequestResponse(w, r, err)
-		default:
-			app.internalServerError(w, r, err)
-		}
+		app.storeErrorResponse(w, r, err)
 		return
 	}
 
@@ -201,6 +197,8 @@ func (app *application) getEnrollmentsHandler(w http.ResponseWriter, r *http.Req
 func (app *application) getEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
 	enrollment := app.getEnrollmentFromCtx(r)
 
+	w.Header().Set("ETag", versionETag(enrollment.Version))
+
 	if err := utils.ResponseJSON(w, http.StatusOK, enrollment); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -210,6 +208,8 @@ func (app *application) getEnrollmentHandler(w http.ResponseWriter, r *http.Requ
 func (app *application) getEditEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
 	enrollment := app.getEditEnrollmentFromCtx(r)
 
+	w.Header().Set("ETag", versionETag(enrollment.Version))
+
 	if err := utils.ResponseJSON(w, http.StatusOK, enrollment); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -221,6 +221,13 @@ func (app *application) updateEnrollmentHandler(w http.ResponseWriter, r *http.R
 
 	enrollmentID := app.getEnrollmentIDFromCtx(r)
 
+	// Without If-Match the update is applied unconditionally.
+	version, err := parseIfMatchVersion(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
 	if err := utils.ReadJSON(w, r, &payload); err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -270,40 +277,99 @@ func (app *application) updateEnrollmentHandler(w http.ResponseWriter, r *http.R
 		PtaFee:         payload.PtaFee,
 		LmsFee:         payload.LmsFee,
 		Discounts:      discounts,
+		Version:        version,
 	}
 
 	if err := app.store.Enrollments.Update(r.Context(), enrollment, enrollmentID); err != nil {
-		switch err {
-		case store.ErrDuplicate:
-			app.badRequestResponse(w, r, err)
-		case store.ErrRequiredFees:
-			app.badRequestResponse(w, r, err)
-		case store.ErrNotFound:
-			app.badRequestResponse(w, r, err)
-		default:
-			app.internalServerError(w, r, err)
+		if errors.Is(err, store.ErrVersionConflict) {
+			app.versionConflictResponse(w, r, err, enrollmentID)
+			return
 		}
+		app.storeErrorResponse(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", versionETag(enrollment.Version))
+
 	if err := utils.ResponseJSON(w, http.StatusOK, enrollment); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+}
+
+// versionConflictResponse answers a stale update with the enrollment as it is
+// now stored so the client can merge and retry.
+func (app *application) versionConflictResponse(w http.ResponseWriter, r *http.Request, err error, enrollmentID uuid.UUID) {
+	current, getErr := app.store.Enrollments.GetEditEnrollmentDetails(r.Context(), enrollmentID)
+	if getErr != nil {
+		app.internalServerError(w, r, getErr)
+		return
+	}
 
+	w.Header().Set("ETag", versionETag(current.Version))
+	app.preconditionFailedResponse(w, r, err, current)
 }
 
 func (app *application) deleteEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
 	enrollmentID := app.getEnrollmentIDFromCtx(r)
 
 	if err := app.store.Enrollments.Delete(r.Context(), enrollmentID); err != nil {
-		app.internalServerError(w, r, err)
+		app.storeErrorResponse(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (app *application) withdrawEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
+	var payload WithdrawEnrollmentPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	withdrawalDate, err := time.Parse(dateLayout, payload.WithdrawalDate)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	withdrawal := &models.Withdrawal{
+		EnrollmentID:   app.getEnrollmentIDFromCtx(r),
+		WithdrawalDate: withdrawalDate,
+		Reason:         payload.Reason,
+	}
+
+	if err := app.store.Enrollments.Withdraw(r.Context(), withdrawal); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, withdrawal); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getWithdrawalHandler(w http.ResponseWriter, r *http.Request) {
+	withdrawal, err := app.store.Enrollments.GetWithdrawal(r.Context(), app.getEnrollmentIDFromCtx(r))
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, withdrawal); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
 func (app *application) enrollmentIDfromURLContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		idString := chi.URLParam(r, enrollmentID)
@@ -332,12 +398,7 @@ func (app *application) enrollmentContextMiddleware(next http.Handler) http.Hand
 
 		enrollment, err := app.store.Enrollments.GetEnrollmentByID(ctx, id)
 		if err != nil {
-			switch err {
-			case store.ErrNotFound:
-				app.notFoundResponse(w, r, err)
-			default:
-				app.internalServerError(w, r, err)
-			}
+			app.storeErrorResponse(w, r, err)
 			return
 		}
 
@@ -354,12 +415,7 @@ func (app *application) editEnrollmentContextMiddleware(next http.Handler) http.
 
 		enrollment, err := app.store.Enrollments.GetEditEnrollmentDetails(ctx, id)
 		if err != nil {
-			switch err {
-			case store.ErrNotFound:
-				app.notFoundResponse(w, r, err)
-			default:
-				app.internalServerError(w, r, err)
-			}
+			app.storeErrorResponse(w, r, err)
 			return
 		}
 
@@ -415,3 +471,25 @@ func getDiscounts(availDiscounts []string, monthlyTuition, lmsFee decimal.Decima
 
 	return discounts
 }
+
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatchVersion reads the enrollment version from an If-Match header,
+// returning 0 when the header is absent or matches any version.
+func parseIfMatchVersion(r *http.Request) (int, error) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, nil
+	}
+
+	ifMatch = strings.TrimPrefix(ifMatch, "W/")
+
+	version, err := strconv.Atoi(strings.Trim(ifMatch, `"`))
+	if err != nil || version < 1 {
+		return 0, errors.New("If-Match must be an enrollment ETag")
+	}
+
+	return version, nil
+}
@@ -1,61 +1,133 @@
 package main
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/edzhabs/bookkeeping/internal/store"
 	"github.com/edzhabs/bookkeeping/internal/utils"
+	"github.com/go-chi/chi/middleware"
 )
 
+// storeErrorResponse is the single place where errors returned by the store
+// become HTTP responses. Errors the store does not classify are 500s.
+func (app *application) storeErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch store.KindOf(err) {
+	case store.KindNotFound:
+		app.notFoundResponse(w, r, err)
+	case store.KindConflict:
+		app.conflictResponse(w, r, err)
+	case store.KindInvalid:
+		app.badRequestResponse(w, r, err)
+	case store.KindPrecondition:
+		app.preconditionFailedResponse(w, r, err, nil)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+// writeProblem fills in the request-specific members of problem and sends it.
+func (app *application) writeProblem(w http.ResponseWriter, r *http.Request, problem *utils.Problem) {
+	problem.Instance = r.URL.Path
+	problem.RequestID = middleware.GetReqID(r.Context())
+
+	utils.ProblemJSON(w, problem)
+}
+
+// errorCode returns the stable code of a store error, or fallback for errors
+// raised outside the store.
+func errorCode(err error, fallback string) string {
+	var storeErr *store.Error
+	if errors.As(err, &storeErr) {
+		return storeErr.Code
+	}
+
+	return fallback
+}
+
 // Warn
 func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
-	app.logger.Warnw("bad request", "method", r.Method, "path", r.URL.Path, "error", err)
+	app.logger.Warnw("bad request", "method", r.Method, "path", r.URL.Path, "request_id", middleware.GetReqID(r.Context()), "error", err)
+
+	if fields, ok := utils.FieldErrors(err); ok {
+		problem := utils.NewProblem(http.StatusBadRequest, "validation_failed", "validation failed")
+		problem.Errors = fields
+		app.writeProblem(w, r, problem)
+		return
+	}
 
-	utils.ErrorJSON(w, http.StatusBadRequest, err.Error())
+	app.writeProblem(w, r, utils.NewProblem(http.StatusBadRequest, errorCode(err, "bad_request"), err.Error()))
 }
 
 func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request, err error) {
-	app.logger.Warnw("not found error", "method", r.Method, "path", r.URL.Path, "error", err)
+	app.logger.Warnw("not found error", "method", r.Method, "path", r.URL.Path, "request_id", middleware.GetReqID(r.Context()), "error", err)
 
-	utils.ErrorJSON(w, http.StatusNotFound, "not found")
+	app.writeProblem(w, r, utils.NewProblem(http.StatusNotFound, "not_found", "not found"))
 }
 
 func (app *application) unauthorizedErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
-	app.logger.Warnw("unauthorized error", "method", r.Method, "path", r.URL.Path, "error", err)
+	app.logger.Warnw("unauthorized error", "method", r.Method, "path", r.URL.Path, "request_id", middleware.GetReqID(r.Context()), "error", err)
 
-	utils.ErrorJSON(w, http.StatusUnauthorized, "unauthorized")
+	app.writeProblem(w, r, utils.NewProblem(http.StatusUnauthorized, "unauthorized", "unauthorized"))
 }
 
 func (app *application) unauthorizedBasicErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
-	app.logger.Warnw("basic unauthorized error", "method", r.Method, "path", r.URL.Path, "error", err)
+	app.logger.Warnw("basic unauthorized error", "method", r.Method, "path", r.URL.Path, "request_id", middleware.GetReqID(r.Context()), "error", err)
 
 	w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
 
-	utils.ErrorJSON(w, http.StatusUnauthorized, "basic unauthorized")
+	app.writeProblem(w, r, utils.NewProblem(http.StatusUnauthorized, "unauthorized", "basic unauthorized"))
 }
 
 func (app *application) forbiddenResponse(w http.ResponseWriter, r *http.Request) {
-	app.logger.Warnw("forbidden", "method", r.Method, "path", r.URL.Path, "error")
+	app.logger.Warnw("forbidden", "method", r.Method, "path", r.URL.Path, "request_id", middleware.GetReqID(r.Context()))
 
-	utils.ErrorJSON(w, http.StatusForbidden, "forbidden")
+	app.writeProblem(w, r, utils.NewProblem(http.StatusForbidden, "forbidden", "forbidden"))
 }
 
 func (app *application) rateLimitReachedResponse(w http.ResponseWriter, r *http.Request, retryAfter string) {
-	app.logger.Warnw("rate limit exceeded", "method", r.Method, "path", r.URL.Path)
+	app.logger.Warnw("rate limit exceeded", "method", r.Method, "path", r.URL.Path, "request_id", middleware.GetReqID(r.Context()))
 
 	w.Header().Set("Retry-After", retryAfter)
 
-	utils.ErrorJSON(w, http.StatusTooManyRequests, "rate limit exceeded, retry after: "+retryAfter)
+	app.writeProblem(w, r, utils.NewProblem(http.StatusTooManyRequests, "rate_limited", "rate limit exceeded, retry after: "+retryAfter))
+}
+
+func (app *application) payloadTooLargeResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw("payload too large", "method", r.Method, "path", r.URL.Path, "request_id", middleware.GetReqID(r.Context()), "error", err)
+
+	app.writeProblem(w, r, utils.NewProblem(http.StatusRequestEntityTooLarge, "payload_too_large", err.Error()))
+}
+
+func (app *application) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw("unsupported media type", "method", r.Method, "path", r.URL.Path, "request_id", middleware.GetReqID(r.Context()), "error", err)
+
+	app.writeProblem(w, r, utils.NewProblem(http.StatusUnsupportedMediaType, "unsupported_media_type", err.Error()))
+}
+
+func (app *application) unprocessableEntityResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw("unprocessable entity", "method", r.Method, "path", r.URL.Path, "request_id", middleware.GetReqID(r.Context()), "error", err)
+
+	app.writeProblem(w, r, utils.NewProblem(http.StatusUnprocessableEntity, errorCode(err, "unprocessable_entity"), err.Error()))
+}
+
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request, err error, current any) {
+	app.logger.Warnw("precondition failed", "method", r.Method, "path", r.URL.Path, "request_id", middleware.GetReqID(r.Context()), "error", err)
+
+	problem := utils.NewProblem(http.StatusPreconditionFailed, errorCode(err, "precondition_failed"), err.Error())
+	problem.Data = current
+	app.writeProblem(w, r, problem)
 }
 
 // Error
 func (app *application) conflictResponse(w http.ResponseWriter, r *http.Request, err error) {
-	app.logger.Errorw("conflict error", "method", r.Method, "path", r.URL.Path, "error", err)
+	app.logger.Errorw("conflict error", "method", r.Method, "path", r.URL.Path, "request_id", middleware.GetReqID(r.Context()), "error", err)
 
-	utils.ErrorJSON(w, http.StatusConflict, err.Error())
+	app.writeProblem(w, r, utils.NewProblem(http.StatusConflict, errorCode(err, "conflict"), err.Error()))
 }
 
 func (app *application) internalServerError(w http.ResponseWriter, r *http.Request, err error) {
-	app.logger.Errorw("internal error", "method", r.Method, "path", r.URL.Path, "error", err)
+	app.logger.Errorw("internal error", "method", r.Method, "path", r.URL.Path, "request_id", middleware.GetReqID(r.Context()), "error", err)
 
-	utils.ErrorJSON(w, http.StatusInternalServerError, "the server encountered a problem")
+	app.writeProblem(w, r, utils.NewProblem(http.StatusInternalServerError, "internal_error", "the server encountered a problem"))
 }
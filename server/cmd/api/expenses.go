@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/utils"
+	"github.com/shopspring/decimal"
+)
+
+type ExpensePayload struct {
+	ExpenseDate   string          `json:"expense_date" validate:"required,datetime=2006-01-02"`
+	Category      string          `json:"category" validate:"required,trimmedSpace,max=50"`
+	Description   string          `json:"description" validate:"required,max=255"`
+	Amount        decimal.Decimal `json:"amount" validate:"required,decimalGt"`
+	PaymentMethod string          `json:"payment_method" validate:"oneofci=cash gcash bank"`
+}
+
+func (app *application) createExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ExpensePayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	expenseDate, err := time.Parse(dateLayout, payload.ExpenseDate)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	expense := &models.Expense{
+		ExpenseDate:   expenseDate,
+		Category:      strings.ToLower(payload.Category),
+		Description:   payload.Description,
+		Amount:        payload.Amount,
+		PaymentMethod: strings.ToLower(payload.PaymentMethod),
+	}
+
+	if err := app.store.Expenses.Create(r.Context(), expense); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusCreated, expense); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getExpensesHandler(w http.ResponseWriter, r *http.Request) {
+	expenses, err := app.store.Expenses.GetAll(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, expenses); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
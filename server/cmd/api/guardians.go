@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type guardianKey string
+
+const (
+	guardianID              = "guardianID"
+	guardianCtx guardianKey = "guardian"
+)
+
+var errGuardianStudent = errors.New("student_id is required")
+
+type GuardianPayload struct {
+	Name         string  `json:"name" validate:"required,alpha_with_spaces,trimmedSpace,max=100"`
+	Relationship string  `json:"relationship" validate:"oneofci=mother father guardian"`
+	Email        *string `json:"email" validate:"omitempty,email,max=255"`
+	Phone        *string `json:"phone" validate:"omitempty,max=30"`
+	EmailOptOut  bool    `json:"email_opt_out"`
+}
+
+type CreateGuardianPayload struct {
+	StudentID uuid.UUID `json:"student_id" validate:"required"`
+	GuardianPayload
+}
+
+func (app *application) createGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateGuardianPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	guardian := newGuardian(payload.GuardianPayload)
+	guardian.StudentID = payload.StudentID
+
+	if err := app.store.Guardians.Create(r.Context(), guardian); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusCreated, guardian); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getGuardiansHandler(w http.ResponseWriter, r *http.Request) {
+	idString := r.URL.Query().Get("student_id")
+	if idString == "" {
+		app.badRequestResponse(w, r, errGuardianStudent)
+		return
+	}
+
+	studentID, err := uuid.Parse(idString)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	guardians, err := app.store.Guardians.GetByStudentID(r.Context(), studentID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, guardians); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	guardian := app.getGuardianFromCtx(r)
+
+	if err := utils.ResponseJSON(w, http.StatusOK, guardian); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	var payload GuardianPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	guardian := newGuardian(payload)
+	guardian.ID = app.getGuardianFromCtx(r).ID
+
+	if err := app.store.Guardians.Update(r.Context(), guardian); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, guardian); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.store.Guardians.Delete(r.Context(), app.getGuardianFromCtx(r).ID); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newGuardian(payload GuardianPayload) *models.Guardian {
+	return &models.Guardian{
+		Name:         payload.Name,
+		Relationship: strings.ToLower(payload.Relationship),
+		Email:        trimmedOrNil(payload.Email),
+		Phone:        trimmedOrNil(payload.Phone),
+		EmailOptOut:  payload.EmailOptOut,
+	}
+}
+
+// trimmedOrNil treats a blank optional field the same as a missing one.
+func trimmedOrNil(value *string) *string {
+	if value == nil {
+		return nil
+	}
+
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+
+	return &trimmed
+}
+
+func (app *application) guardianContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idString := chi.URLParam(r, guardianID)
+
+		id, err := uuid.Parse(idString)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		guardian, err := app.store.Guardians.GetByID(ctx, id)
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, guardianCtx, guardian)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getGuardianFromCtx(r *http.Request) models.Guardian {
+	guardian, _ := r.Context().Value(guardianCtx).(models.Guardian)
+	return guardian
+}
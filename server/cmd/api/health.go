@@ -1,20 +1,159 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/edzhabs/bookkeeping/internal/utils"
 )
 
+const (
+	healthUp       = "up"
+	healthDown     = "down"
+	healthDegraded = "degraded"
+
+	readinessTimeout = 2 * time.Second
+	// poolSaturation is the share of open connections in use above which the
+	// pool is reported as degraded.
+	poolSaturation = 0.9
+)
+
+type healthStatus struct {
+	Status     string                     `json:"status"`
+	Env        string                     `json:"env"`
+	Version    string                     `json:"version"`
+	Components map[string]componentStatus `json:"components"`
+}
+
+type componentStatus struct {
+	Status  string         `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// healthCheckHandler reports liveness: the process is running and serving
+// requests. It deliberately ignores dependencies so a database outage does
+// not get the server restarted.
 func (app *application) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	data := map[string]string{
-		"status":  "ok",
-		"env":     app.config.env,
-		"version": version,
+	health := app.newHealthStatus()
+	health.Components["server"] = componentStatus{Status: healthUp}
+
+	app.writeHealth(w, r, health)
+}
+
+// readinessHandler reports whether the server can take traffic: the database
+// answers, its schema is at the expected migration and the server is not
+// shutting down.
+func (app *application) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	health := app.newHealthStatus()
+
+	server := componentStatus{Status: healthUp}
+	if app.draining.Load() {
+		server = componentStatus{Status: healthDown, Error: "server is shutting down"}
+	}
+	health.Components["server"] = server
+
+	database := componentStatus{Status: healthUp}
+	start := time.Now()
+	if err := app.store.Health.Ping(ctx); err != nil {
+		database = componentStatus{Status: healthDown, Error: err.Error()}
+	}
+	database.Details = map[string]any{"latency_ms": time.Since(start).Milliseconds()}
+	health.Components["database"] = database
+
+	health.Components["migrations"] = app.migrationStatus(ctx)
+	health.Components["pool"] = app.poolStatus()
+
+	for _, component := range health.Components {
+		if component.Status == healthDown {
+			health.Status = healthDown
+			break
+		}
+	}
+
+	app.writeHealth(w, r, health)
+}
+
+func (app *application) migrationStatus(ctx context.Context) componentStatus {
+	version, dirty, err := app.store.Health.SchemaVersion(ctx)
+	if err != nil {
+		return componentStatus{Status: healthDown, Error: err.Error()}
+	}
+
+	status := componentStatus{
+		Status: healthUp,
+		Details: map[string]any{
+			"version":  version,
+			"expected": app.schemaVersion,
+			"dirty":    dirty,
+		},
+	}
+
+	switch {
+	case dirty:
+		status.Status = healthDown
+		status.Error = fmt.Sprintf("migration %d did not complete", version)
+	case uint(version) < app.schemaVersion:
+		status.Status = healthDown
+		status.Error = fmt.Sprintf("schema is at version %d, expected %d", version, app.schemaVersion)
+	case uint(version) > app.schemaVersion:
+		// A newer schema is expected while a rolling deploy is in progress.
+		status.Status = healthDegraded
+	}
+
+	return status
+}
+
+func (app *application) poolStatus() componentStatus {
+	stats := app.store.Health.Stats()
+
+	saturation := 0.0
+	if stats.MaxOpenConnections > 0 {
+		saturation = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+	}
+
+	status := componentStatus{
+		Status: healthUp,
+		Details: map[string]any{
+			"open":          stats.OpenConnections,
+			"in_use":        stats.InUse,
+			"idle":          stats.Idle,
+			"max_open":      stats.MaxOpenConnections,
+			"wait_count":    stats.WaitCount,
+			"wait_duration": stats.WaitDuration.String(),
+			"saturation":    saturation,
+		},
+	}
+
+	if saturation >= poolSaturation {
+		status.Status = healthDegraded
+	}
+
+	return status
+}
+
+func (app *application) newHealthStatus() healthStatus {
+	return healthStatus{
+		Status:     healthUp,
+		Env:        app.config.Env,
+		Version:    version,
+		Components: make(map[string]componentStatus),
+	}
+}
+
+func (app *application) writeHealth(w http.ResponseWriter, r *http.Request, health healthStatus) {
+	status := http.StatusOK
+	if health.Status == healthDown {
+		status = http.StatusServiceUnavailable
 	}
 
-	if err := utils.ResponseJSON(w, http.StatusOK, data); err != nil {
-		app.badRequestResponse(w, r, err)
+	if err := utils.ResponseJSON(w, status, health); err != nil {
+		app.internalServerError(w, r, err)
 		return
 	}
 }
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/store"
+	"github.com/go-chi/chi/middleware"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	idempotencyMaxKeyLen = 255
+	idempotencyMaxBody   = 1_048_578
+	idempotencyWait      = 5 * time.Second
+	idempotencyPoll      = 250 * time.Millisecond
+	// idempotencyLock is how long a claimed key stays locked to its request.
+	// It outlasts the server's write timeout, so a key still in progress after
+	// it has lapsed belongs to a request that died without releasing it.
+	idempotencyLock = time.Minute
+)
+
+var (
+	errIdempotencyKeyTooLong  = errors.New("Idempotency-Key must be at most 255 characters")
+	errIdempotencyKeyReused   = errors.New("Idempotency-Key was already used for a different request")
+	errIdempotencyKeyInFlight = errors.New("a request with this Idempotency-Key is still being processed")
+)
+
+// replayedHeaders are the response headers stored with a completed request and
+// written back when it is replayed.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// IdempotencyMiddleware makes retried requests carrying the same
+// Idempotency-Key return the original response instead of running again.
+func (app *application) IdempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > idempotencyMaxKeyLen {
+			app.badRequestResponse(w, r, errIdempotencyKeyTooLong)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, idempotencyMaxBody))
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		record := &models.IdempotencyRecord{
+			Key:         key,
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			RequestHash: requestHash,
+			LockedUntil: time.Now().Add(idempotencyLock),
+			ExpiresAt:   time.Now().Add(app.config.Idempotency.TTL),
+		}
+
+		claimed, err := app.store.Idempotency.Claim(r.Context(), record)
+		if err != nil {
+			switch err {
+			case store.ErrNotFound:
+				// The key was released between the claim and the lookup.
+				app.idempotencyInFlightResponse(w, r)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		if !claimed {
+			app.replayIdempotentResponse(w, r, record, requestHash)
+			return
+		}
+
+		app.serveIdempotent(w, r, next, record)
+	})
+}
+
+// serveIdempotent runs the request and stores its response under the claimed
+// key. Server errors release the key so the client can retry.
+func (app *application) serveIdempotent(w http.ResponseWriter, r *http.Request, next http.Handler, record *models.IdempotencyRecord) {
+	ctx := context.WithoutCancel(r.Context())
+
+	var buf bytes.Buffer
+	ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	ww.Tee(&buf)
+
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		if err := app.store.Idempotency.Release(ctx, record); err != nil {
+			app.logger.Errorw("failed to release idempotency key", "key", record.Key, "error", err)
+		}
+	}()
+
+	next.ServeHTTP(ww, r)
+
+	status := ww.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if status >= http.StatusInternalServerError {
+		return
+	}
+
+	record.ResponseStatus = status
+	record.ResponseBody = buf.Bytes()
+	record.ResponseHeaders = make(map[string]string)
+	for _, header := range replayedHeaders {
+		if value := ww.Header().Get(header); value != "" {
+			record.ResponseHeaders[header] = value
+		}
+	}
+
+	if err := app.store.Idempotency.Complete(ctx, record); err != nil {
+		app.logger.Errorw("failed to store idempotent response", "key", record.Key, "error", err)
+		return
+	}
+
+	completed = true
+}
+
+// replayIdempotentResponse answers a duplicate request. While the original is
+// still running the duplicate waits briefly for it to finish.
+func (app *application) replayIdempotentResponse(w http.ResponseWriter, r *http.Request, record *models.IdempotencyRecord, requestHash string) {
+	if record.RequestHash != requestHash {
+		app.unprocessableEntityResponse(w, r, errIdempotencyKeyReused)
+		return
+	}
+
+	deadline := time.Now().Add(idempotencyWait)
+	for record.Status == constants.IdempotencyInProgress {
+		if time.Now().After(deadline) {
+			app.idempotencyInFlightResponse(w, r)
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(idempotencyPoll):
+		}
+
+		if err := app.store.Idempotency.Get(r.Context(), record); err != nil {
+			switch err {
+			case store.ErrNotFound:
+				// The original attempt failed and released the key.
+				app.idempotencyInFlightResponse(w, r)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+	}
+
+	for header, value := range record.ResponseHeaders {
+		w.Header().Set(header, value)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.ResponseStatus)
+	w.Write(record.ResponseBody)
+}
+
+// idempotencyInFlightResponse asks the client to retry a request whose key is
+// held, or was just given up, by another attempt.
+func (app *application) idempotencyInFlightResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "1")
+	app.conflictResponse(w, r, errIdempotencyKeyInFlight)
+}
+
+// purgeIdempotencyKeys periodically deletes keys past their retention window.
+func (app *application) purgeIdempotencyKeys(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := app.store.Idempotency.DeleteExpired(ctx)
+			if err != nil {
+				app.logger.Errorw("failed to purge idempotency keys", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				app.logger.Infow("purged idempotency keys", "count", deleted)
+			}
+		}
+	}
+}
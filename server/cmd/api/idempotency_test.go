@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/store"
+)
+
+// fakeIdempotencyStore keeps keys in memory. held makes every claim find its
+// key in progress under an identical request; claimErr and getErr make those
+// calls fail, e.g. with the ErrNotFound of a key released in between.
+type fakeIdempotencyStore struct {
+	records  map[string]models.IdempotencyRecord
+	held     bool
+	claimErr error
+	getErr   error
+}
+
+func newFakeIdempotencyStore() *fakeIdempotencyStore {
+	return &fakeIdempotencyStore{records: make(map[string]models.IdempotencyRecord)}
+}
+
+func (s *fakeIdempotencyStore) Claim(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	if s.claimErr != nil {
+		return false, s.claimErr
+	}
+	if s.held {
+		record.Status = constants.IdempotencyInProgress
+		return false, nil
+	}
+	if stored, ok := s.records[record.Key]; ok {
+		*record = stored
+		return false, nil
+	}
+
+	record.Status = constants.IdempotencyInProgress
+	s.records[record.Key] = *record
+
+	return true, nil
+}
+
+func (s *fakeIdempotencyStore) Get(ctx context.Context, record *models.IdempotencyRecord) error {
+	if s.getErr != nil {
+		return s.getErr
+	}
+
+	stored, ok := s.records[record.Key]
+	if !ok {
+		return store.ErrNotFound
+	}
+	*record = stored
+
+	return nil
+}
+
+func (s *fakeIdempotencyStore) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	record.Status = constants.IdempotencyCompleted
+	s.records[record.Key] = *record
+	return nil
+}
+
+func (s *fakeIdempotencyStore) Release(ctx context.Context, record *models.IdempotencyRecord) error {
+	if s.records[record.Key].Status == constants.IdempotencyInProgress {
+		delete(s.records, record.Key)
+	}
+	return nil
+}
+
+func (s *fakeIdempotencyStore) DeleteExpired(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// idempotentRequest sends a payment with the given key and body through
+// handler.
+func idempotentRequest(handler http.Handler, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/v1/payments", strings.NewReader(body))
+	r.Header.Set(idempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestIdempotencyMiddlewareReplays(t *testing.T) {
+	app := newTestApplication(t)
+	idempotency := newFakeIdempotencyStore()
+	app.store.Idempotency = idempotency
+
+	calls := 0
+	handler := app.IdempotencyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data":{"invoice_number":"INV-001"}}`))
+	}))
+
+	first := idempotentRequest(handler, "key-1", `{"amount":"1000"}`)
+	second := idempotentRequest(handler, "key-1", `{"amount":"1000"}`)
+
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want once", calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" || second.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("replay headers = %v, want the stored headers marked as replayed", second.Header())
+	}
+
+	if w := idempotentRequest(handler, "key-1", `{"amount":"2000"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key with another body: status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestIdempotencyMiddlewareReleasesOnServerError(t *testing.T) {
+	app := newTestApplication(t)
+	idempotency := newFakeIdempotencyStore()
+	app.store.Idempotency = idempotency
+
+	status := http.StatusInternalServerError
+	calls := 0
+	handler := app.IdempotencyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+
+	idempotentRequest(handler, "key-1", `{}`)
+	if _, ok := idempotency.records["key-1"]; ok {
+		t.Fatal("key kept after a server error, want it released")
+	}
+
+	status = http.StatusCreated
+	if w := idempotentRequest(handler, "key-1", `{}`); w.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("retry: status = %d after %d calls, want the request to run again", w.Code, calls)
+	}
+}
+
+func TestIdempotencyMiddlewareKeyInFlight(t *testing.T) {
+	tests := []struct {
+		name       string
+		held       bool
+		claimErr   error
+		getErr     error
+		status     int
+		retryAfter string
+	}{
+		{
+			name:       "released between claim and lookup",
+			claimErr:   store.ErrNotFound,
+			status:     http.StatusConflict,
+			retryAfter: "1",
+		},
+		{
+			name:     "claim fails",
+			claimErr: errors.New("connection reset"),
+			status:   http.StatusInternalServerError,
+		},
+		{
+			name:       "released while waiting",
+			held:       true,
+			getErr:     store.ErrNotFound,
+			status:     http.StatusConflict,
+			retryAfter: "1",
+		},
+		{
+			name:   "lookup fails while waiting",
+			held:   true,
+			getErr: errors.New("connection reset"),
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			idempotency := newFakeIdempotencyStore()
+			idempotency.held = tt.held
+			idempotency.claimErr = tt.claimErr
+			idempotency.getErr = tt.getErr
+			app.store.Idempotency = idempotency
+
+			handler := app.IdempotencyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("handler ran for a key held by another request")
+			}))
+
+			w := idempotentRequest(handler, "key-1", `{}`)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.retryAfter)
+			}
+		})
+	}
+}
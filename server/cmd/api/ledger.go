@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/utils"
+	"github.com/shopspring/decimal"
+)
+
+type AdjustmentPayload struct {
+	EntryDate   string                  `json:"entry_date" validate:"required,datetime=2006-01-02"`
+	Description string                  `json:"description" validate:"required,max=255"`
+	Lines       []AdjustmentLinePayload `json:"lines" validate:"required,min=2,dive"`
+}
+
+type AdjustmentLinePayload struct {
+	AccountCode string          `json:"account_code" validate:"required,numeric,max=10"`
+	Debit       decimal.Decimal `json:"debit"`
+	Credit      decimal.Decimal `json:"credit"`
+}
+
+func (app *application) getAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	accounts, err := app.store.Ledger.GetAccounts(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, accounts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getTrialBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	asOf := time.Now()
+
+	if qs := r.URL.Query().Get("as_of"); qs != "" {
+		date, err := time.Parse(dateLayout, qs)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		asOf = date
+	}
+
+	trialBalance, err := app.store.Ledger.GetTrialBalance(r.Context(), asOf)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, trialBalance); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) createAdjustmentHandler(w http.ResponseWriter, r *http.Request) {
+	var payload AdjustmentPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entryDate, err := time.Parse(dateLayout, payload.EntryDate)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entry := &models.JournalEntry{
+		EntryDate:   entryDate,
+		Description: payload.Description,
+		CreatedBy:   getUserFromCtx(r),
+	}
+
+	for _, line := range payload.Lines {
+		entry.Lines = append(entry.Lines, models.JournalLine{
+			AccountCode: line.AccountCode,
+			Debit:       line.Debit,
+			Credit:      line.Credit,
+		})
+	}
+
+	if err := app.store.Ledger.CreateAdjustment(r.Context(), entry); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusCreated, entry); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
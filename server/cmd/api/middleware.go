@@ -1,16 +1,269 @@
 package main
 
-import "net/http"
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
 
-func (app *application) RateLimiterMiddleware(next http.Handler) http.Handler {
+	"github.com/edzhabs/bookkeeping/internal/ratelimiter"
+	"github.com/edzhabs/bookkeeping/internal/tracing"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type userKey string
+
+type clientIPKey string
+
+const (
+	userCtx     userKey     = "user"
+	clientIPCtx clientIPKey = "clientIP"
+)
+
+var errClientDenied = errors.New("client is not allowed to use this API")
+
+func (app *application) BasicAuthMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, _, ok := r.BasicAuth(); !ok {
+				app.unauthorizedBasicErrorResponse(w, r, errors.New("authorization header is missing"))
+				return
+			}
+
+			username, ok := app.authenticateBasic(r)
+			if !ok {
+				app.unauthorizedBasicErrorResponse(w, r, errors.New("invalid credentials"))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userCtx, username)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// authenticateBasic returns the user of a request whose basic auth
+// credentials are valid.
+func (app *application) authenticateBasic(r *http.Request) (string, bool) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+
+	user := app.config.Auth.BasicUser
+	pass := app.config.Auth.BasicPass
+
+	if user == "" || pass == "" ||
+		subtle.ConstantTimeCompare([]byte(username), []byte(user)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(pass)) != 1 {
+		return "", false
+	}
+
+	return username, true
+}
+
+// ClientIPMiddleware resolves the client address, honouring forwarding
+// headers only from trusted proxies, and replaces RemoteAddr with it so that
+// the request logger reports the same address the rate limiter uses.
+func (app *application) ClientIPMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if app.config.rateLimiter.Enabled {
-			allow, retryAfter := app.ratelimiter.Allow(r.RemoteAddr)
-			if !allow {
-				app.rateLimitReachedResponse(w, r, retryAfter.String())
+		ip := app.clientIP.Resolve(r)
+		if ip.IsValid() {
+			r.RemoteAddr = ip.String()
+		}
+
+		ctx := context.WithValue(r.Context(), clientIPCtx, ip)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RateLimiterMiddleware counts requests per client against the given limiter
+// and reports the remaining quota in RateLimit-* headers. A failing backend
+// lets requests through rather than taking the API down with it.
+func (app *application) RateLimiterMiddleware(limiter ratelimiter.Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !app.config.RateLimiter.Enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ip := getClientIPFromCtx(r)
+
+			if app.denyList.Contains(ip) {
+				app.metrics.RateLimitRejected(limiter.Policy().Name, "denied")
+				app.forbiddenResponse(w, r)
 				return
 			}
+
+			if app.allowList.Contains(ip) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := app.rateLimitKey(r, ip)
+			policy := limiter.Policy()
+
+			result, err := limiter.Allow(r.Context(), key)
+			if err != nil {
+				app.logger.Errorw("rate limiter unavailable", "policy", policy.Name, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Window.Seconds())))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(int(result.ResetAfter.Seconds())))
+
+			if !result.Allowed {
+				app.throttled.Record(policy.Name, key, result.RetryAfter)
+				app.metrics.RateLimitRejected(policy.Name, "limited")
+				app.rateLimitReachedResponse(w, r, strconv.Itoa(int(result.RetryAfter.Seconds())))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AccessLogMiddleware writes one structured log line per request through the
+// application logger and echoes the request ID so clients can quote it.
+func (app *application) AccessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := middleware.GetReqID(r.Context())
+		w.Header().Set(middleware.RequestIDHeader, requestID)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := ""
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			route = rctx.RoutePattern()
 		}
-		next.ServeHTTP(w, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		user, _ := app.authenticateBasic(r)
+
+		fields := []any{
+			"method", r.Method,
+			"route", route,
+			"path", r.URL.Path,
+			"status", status,
+			"bytes", ww.BytesWritten(),
+			"latency", time.Since(start),
+			"request_id", requestID,
+			"user_id", user,
+			"client_ip", getClientIPFromCtx(r).String(),
+		}
+
+		if status >= http.StatusInternalServerError {
+			app.logger.Errorw("request", fields...)
+			return
+		}
+		app.logger.Infow("request", fields...)
 	})
 }
+
+// MetricsMiddleware records request counts and latency by chi route pattern.
+// Requests that match no route share a single label value.
+func (app *application) MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		app.metrics.ObserveRequest(r.Method, route, status, time.Since(start))
+	})
+}
+
+// TracingMiddleware starts a server span for each request, continuing any
+// trace propagated by the caller. The span is renamed to the chi route
+// pattern once routing is done.
+func (app *application) TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := tracing.Tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				attribute.String("request.id", middleware.GetReqID(r.Context())),
+			),
+		)
+		defer span.End()
+
+		if ip := getClientIPFromCtx(r); ip.IsValid() {
+			span.SetAttributes(semconv.ClientAddress(ip.String()))
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// rateLimitKey identifies the client a request is counted against: the
+// authenticated user when the credentials check out, so a user keeps one
+// quota across networks, and the client address otherwise.
+func (app *application) rateLimitKey(r *http.Request, ip netip.Addr) string {
+	if user, ok := app.authenticateBasic(r); ok {
+		return "user:" + user
+	}
+	return "ip:" + ip.String()
+}
+
+func getUserFromCtx(r *http.Request) string {
+	user, _ := r.Context().Value(userCtx).(string)
+	return user
+}
+
+func getClientIPFromCtx(r *http.Request) netip.Addr {
+	ip, _ := r.Context().Value(clientIPCtx).(netip.Addr)
+	return ip
+}
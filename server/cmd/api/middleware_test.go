@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/clientip"
+	"github.com/edzhabs/bookkeeping/internal/ratelimiter"
+)
+
+func TestRateLimiterMiddlewareHeaders(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.RateLimiter.Enabled = true
+	limiter := ratelimiter.NewFixedWindowLimiter(ratelimiter.Policy{Name: "default", Limit: 2, Window: time.Minute})
+
+	handler := app.ClientIPMiddleware(app.RateLimiterMiddleware(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	tests := []struct {
+		status     int
+		remaining  string
+		retryAfter string
+	}{
+		{http.StatusNoContent, "1", ""},
+		{http.StatusNoContent, "0", ""},
+		{http.StatusTooManyRequests, "0", "60"},
+	}
+
+	for i, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/v1/students", nil)
+		r.RemoteAddr = "192.0.2.1:54321"
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, r)
+
+		if w.Code != tt.status {
+			t.Errorf("request %d: status = %d, want %d", i, w.Code, tt.status)
+		}
+
+		want := map[string]string{
+			"RateLimit-Policy":    "2;w=60",
+			"RateLimit-Limit":     "2",
+			"RateLimit-Remaining": tt.remaining,
+			"RateLimit-Reset":     "60",
+			"Retry-After":         tt.retryAfter,
+		}
+		for header, value := range want {
+			if got := w.Header().Get(header); got != value {
+				t.Errorf("request %d: %s = %q, want %q", i, header, got, value)
+			}
+		}
+	}
+
+	// Another client still has its whole quota.
+	r := httptest.NewRequest(http.MethodGet, "/v1/students", nil)
+	r.RemoteAddr = "192.0.2.2:54321"
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent || w.Header().Get("RateLimit-Remaining") != "1" {
+		t.Errorf("other client: status = %d, remaining = %q, want its own quota", w.Code, w.Header().Get("RateLimit-Remaining"))
+	}
+}
+
+func TestRateLimiterMiddlewareLists(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.RateLimiter.Enabled = true
+	app.allowList, _ = clientip.ParsePrefixes("10.0.0.0/8")
+	app.denyList, _ = clientip.ParsePrefixes("203.0.113.7")
+	limiter := ratelimiter.NewFixedWindowLimiter(ratelimiter.Policy{Name: "default", Limit: 1, Window: time.Minute})
+
+	handler := app.ClientIPMiddleware(app.RateLimiterMiddleware(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		status     int
+	}{
+		{"denied address", "203.0.113.7:54321", http.StatusForbidden},
+		{"allowed network skips the limit", "10.1.2.3:54321", http.StatusNoContent},
+		{"allowed network again", "10.1.2.3:54321", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/v1/students", nil)
+		r.RemoteAddr = tt.remoteAddr
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, r)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if got := w.Header().Get("RateLimit-Limit"); got != "" {
+			t.Errorf("%s: RateLimit-Limit = %q, want no rate limit headers", tt.name, got)
+		}
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.Auth.BasicUser = "admin"
+	app.config.Auth.BasicPass = "secret"
+
+	ip := netip.MustParseAddr("192.0.2.1")
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		want     string
+	}{
+		{"anonymous", "", "", "ip:192.0.2.1"},
+		{"authenticated", "admin", "secret", "user:admin"},
+		{"wrong password", "admin", "guess", "ip:192.0.2.1"},
+		{"unknown user", "root", "secret", "ip:192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/students", nil)
+			if tt.user != "" {
+				r.SetBasicAuth(tt.user, tt.password)
+			}
+
+			if got := app.rateLimitKey(r, ip); got != tt.want {
+				t.Errorf("rateLimitKey = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/edzhabs/bookkeeping/cmd/migrate/migrations"
+	"github.com/edzhabs/bookkeeping/internal/db"
+	"go.uber.org/zap"
+)
+
+// ensureSchema brings the database up to the embedded migrations when
+// autoMigrate is set, and otherwise refuses to start against a schema that is
+// behind or was left dirty by a failed migration. It returns the latest
+// embedded version.
+func ensureSchema(ctx context.Context, conn *sql.DB, autoMigrate bool, logger *zap.SugaredLogger) (uint, error) {
+	migrator, err := db.NewMigrator(ctx, conn, migrations.FS)
+	if err != nil {
+		return 0, err
+	}
+
+	defer migrator.Close()
+
+	if autoMigrate {
+		if err := migrator.Up(); err != nil {
+			return 0, fmt.Errorf("applying migrations: %w", err)
+		}
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case status.Dirty:
+		return 0, fmt.Errorf("migration %d did not complete, fix it and run migrate force", status.Version)
+	case status.Pending():
+		return 0, fmt.Errorf("schema is at version %d, expected %d, run migrate up or set DB_AUTO_MIGRATE", status.Version, status.Latest)
+	}
+
+	logger.Infow("database schema is up to date", "version", status.Version)
+
+	return status.Latest, nil
+}
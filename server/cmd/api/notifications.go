@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/notify"
+	"github.com/edzhabs/bookkeeping/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	notificationID = "notificationID"
+
+	// notificationBatch is how many emails one poll of the outbox sends.
+	notificationBatch = 20
+	// notificationLease hides a claimed email from other instances while it
+	// is sent. It must outlast a send, or the email may go out twice.
+	notificationLease = 5 * time.Minute
+)
+
+type NotificationQuery struct {
+	Status       string `json:"status" validate:"omitempty,oneofci=pending sent failed cancelled"`
+	EnrollmentID string `json:"enrollment_id" validate:"omitempty,uuid"`
+}
+
+func (app *application) getNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	query := NotificationQuery{
+		Status:       strings.ToLower(qs.Get("status")),
+		EnrollmentID: qs.Get("enrollment_id"),
+	}
+
+	if err := utils.Validate.Struct(query); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var enrollmentID *uuid.UUID
+	if query.EnrollmentID != "" {
+		id, err := uuid.Parse(query.EnrollmentID)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		enrollmentID = &id
+	}
+
+	notifications, err := app.store.Notifications.GetAll(r.Context(), query.Status, enrollmentID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, notifications); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) retryNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, notificationID))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Notifications.Retry(r.Context(), id); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// queueReminders queues the day's upcoming due and overdue reminders. It
+// runs more often than daily so a restart never skips a day; reminders
+// already queued are not queued again.
+func (app *application) queueReminders(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	cfg := app.config.Notifications
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			queued, err := app.store.Notifications.EnqueueReminders(ctx, time.Now(), cfg.DueDay, cfg.ReminderDays)
+			if err != nil {
+				app.logger.Errorw("failed to queue reminders", "error", err)
+				continue
+			}
+			if queued > 0 {
+				app.logger.Infow("queued reminders", "count", queued)
+			}
+		}
+	}
+}
+
+// sendNotifications drains the outbox. Failed sends are retried with a
+// growing delay until the configured number of attempts is used up.
+func (app *application) sendNotifications(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			notifications, err := app.store.Notifications.Claim(ctx, notificationBatch, notificationLease)
+			if err != nil {
+				app.logger.Errorw("failed to claim notifications", "error", err)
+				continue
+			}
+
+			for _, notification := range notifications {
+				app.sendNotification(ctx, notification)
+			}
+		}
+	}
+}
+
+func (app *application) sendNotification(ctx context.Context, notification models.Notification) {
+	cfg := app.config.Notifications
+
+	err := app.deliverNotification(ctx, notification)
+	if err == nil {
+		if err := app.store.Notifications.MarkSent(ctx, notification.ID); err != nil {
+			app.logger.Errorw("failed to mark notification sent", "id", notification.ID, "error", err)
+		}
+		return
+	}
+
+	app.logger.Warnw("failed to send notification",
+		"id", notification.ID,
+		"kind", notification.Kind,
+		"attempt", notification.Attempts,
+		"error", err,
+	)
+
+	retryAfter := time.Duration(notification.Attempts*notification.Attempts) * time.Minute
+
+	if err := app.store.Notifications.MarkFailed(ctx, notification.ID, err.Error(), retryAfter, cfg.MaxAttempts); err != nil {
+		app.logger.Errorw("failed to mark notification failed", "id", notification.ID, "error", err)
+	}
+}
+
+func (app *application) deliverNotification(ctx context.Context, notification models.Notification) error {
+	subject, body, err := notify.Render(notification.Kind, notification.Data, app.config.Notifications.School)
+	if err != nil {
+		return err
+	}
+
+	return app.mailer.Send(ctx, notify.Message{
+		To:      notification.Recipient,
+		Subject: subject,
+		Body:    body,
+	})
+}
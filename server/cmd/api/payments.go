@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/store"
+	"github.com/edzhabs/bookkeeping/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type paymentKey string
+
+const (
+	paymentID             = "paymentID"
+	paymentCtx paymentKey = "payment"
+)
+
+type PaymentPayload struct {
+	InvoiceNumber  string          `json:"invoice_number" validate:"required,trimmedSpace,max=100"`
+	PaymentDate    string          `json:"payment_date" validate:"required,datetime=2006-01-02"`
+	PaymentMethod  string          `json:"payment_method" validate:"oneofci=cash gcash bank"`
+	ReservationFee decimal.Decimal `json:"reservation_fee"`
+	TuitionFee     decimal.Decimal `json:"tuition_fee"`
+	AdvancePayment decimal.Decimal `json:"advance_payment"`
+	Notes          string          `json:"notes" validate:"omitempty,max=255"`
+}
+
+func (app *application) createPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	payment, ok := app.readPaymentPayload(w, r)
+	if !ok {
+		return
+	}
+
+	payment.EnrollmentID = app.getEnrollmentIDFromCtx(r)
+
+	if err := app.store.Payments.Create(r.Context(), payment); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusCreated, payment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getEnrollmentPaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	payments, err := app.store.Payments.GetByEnrollmentID(r.Context(), app.getEnrollmentIDFromCtx(r))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, payments); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	payment := app.getPaymentFromCtx(r)
+
+	if err := utils.ResponseJSON(w, http.StatusOK, payment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updatePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	payment, ok := app.readPaymentPayload(w, r)
+	if !ok {
+		return
+	}
+
+	payment.ID = app.getPaymentFromCtx(r).ID
+
+	if err := app.store.Payments.Update(r.Context(), payment); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, payment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deletePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	payment := app.getPaymentFromCtx(r)
+
+	if err := app.store.Payments.Delete(r.Context(), payment.ID); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) readPaymentPayload(w http.ResponseWriter, r *http.Request) (*models.Payment, bool) {
+	var payload PaymentPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	paymentDate, err := time.Parse(dateLayout, payload.PaymentDate)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	payment := &models.Payment{
+		InvoiceNumber:  payload.InvoiceNumber,
+		PaymentDate:    paymentDate,
+		PaymentMethod:  strings.ToLower(payload.PaymentMethod),
+		ReservationFee: payload.ReservationFee,
+		TuitionFee:     payload.TuitionFee,
+		AdvancePayment: payload.AdvancePayment,
+		Notes:          payload.Notes,
+	}
+
+	if payment.ReservationFee.IsNegative() || payment.TuitionFee.IsNegative() ||
+		payment.AdvancePayment.IsNegative() || !payment.Amount().IsPositive() {
+		app.badRequestResponse(w, r, store.ErrInvalidPayment)
+		return nil, false
+	}
+
+	return payment, true
+}
+
+func (app *application) paymentContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idString := chi.URLParam(r, paymentID)
+
+		id, err := uuid.Parse(idString)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		payment, err := app.store.Payments.GetByID(ctx, id)
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, paymentCtx, payment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getPaymentFromCtx(r *http.Request) models.Payment {
+	payment, _ := r.Context().Value(paymentCtx).(models.Payment)
+	return payment
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const monthLayout = "2006-01"
+
+type ClosePeriodPayload struct {
+	PeriodType  string `json:"period_type" validate:"oneof=month school_year"`
+	SchoolYear  string `json:"school_year" validate:"omitempty,schoolyear"`
+	PeriodMonth string `json:"period_month" validate:"omitempty,datetime=2006-01"`
+}
+
+func (app *application) closePeriodHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ClosePeriodPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	period := &models.ClosedPeriod{
+		PeriodType: payload.PeriodType,
+		ClosedBy:   getUserFromCtx(r),
+	}
+
+	switch payload.PeriodType {
+	case constants.PeriodMonth:
+		month, err := time.Parse(monthLayout, payload.PeriodMonth)
+		if err != nil {
+			app.badRequestResponse(w, r, errors.New("period_month is required when closing a month"))
+			return
+		}
+		period.PeriodMonth = &month
+	case constants.PeriodSchoolYear:
+		if payload.SchoolYear == "" {
+			app.badRequestResponse(w, r, errors.New("school_year is required when closing a school year"))
+			return
+		}
+		period.SchoolYear = payload.SchoolYear
+	}
+
+	if err := app.store.Periods.Close(r.Context(), period); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusCreated, period); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) reopenPeriodHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "periodID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Periods.Reopen(r.Context(), id); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getClosedPeriodsHandler(w http.ResponseWriter, r *http.Request) {
+	periods, err := app.store.Periods.GetAll(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, periods); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/edzhabs/bookkeeping/internal/utils"
+)
+
+func (app *application) getThrottledClientsHandler(w http.ResponseWriter, r *http.Request) {
+	if err := utils.ResponseJSON(w, http.StatusOK, app.throttled.Throttled()); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
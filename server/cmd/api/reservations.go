@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type reservationKey string
+
+const (
+	reservationID                 = "reservationID"
+	reservationCtx reservationKey = "reservation"
+)
+
+var errReservationExpiry = errors.New("expires_on must not be before reserved_on")
+
+type ReservationPayload struct {
+	StudentID     uuid.UUID       `json:"student_id" validate:"required"`
+	SchoolYear    string          `json:"school_year" validate:"required,schoolyear"`
+	GradeLevel    string          `json:"grade_level" validate:"oneofci=nursery-1 nursery-2 kinder-1 kinder-2 grade-1 grade-2 grade-3 grade-4 grade-5 grade-6 grade-7"`
+	InvoiceNumber string          `json:"invoice_number" validate:"required,trimmedSpace,max=100"`
+	Fee           decimal.Decimal `json:"fee" validate:"required,decimalGt"`
+	PaymentMethod string          `json:"payment_method" validate:"oneofci=cash gcash bank"`
+	ReservedOn    string          `json:"reserved_on" validate:"required,datetime=2006-01-02"`
+	ExpiresOn     string          `json:"expires_on" validate:"required,datetime=2006-01-02"`
+	Notes         string          `json:"notes" validate:"omitempty,max=255"`
+}
+
+type ReservationQuery struct {
+	SchoolYear string `json:"school_year" validate:"schoolyear"`
+	Status     string `json:"status" validate:"omitempty,oneofci=reserved converted expired forfeited"`
+}
+
+// ConvertReservationPayload carries the billing of the enrollment a
+// reservation is converted into. The student, school year and grade level
+// come from the reservation.
+type ConvertReservationPayload struct {
+	MonthlyTuition decimal.Decimal `json:"monthly_tuition" validate:"required,decimalGt"`
+	Type           string          `json:"type" validate:"oneofci=new old"`
+	EnrollmentFee  decimal.Decimal `json:"enrollment_fee" validate:"required,decimalGt"`
+	MiscFee        decimal.Decimal `json:"misc_fee" validate:"required,decimalGt"`
+	PtaFee         decimal.Decimal `json:"pta_fee" validate:"required,decimalGt"`
+	LmsFee         decimal.Decimal `json:"lms_books_fee" validate:"required,decimalGt"`
+	AvailDiscounts []string        `json:"discounts" validate:"omitempty,discounts"`
+}
+
+func (app *application) createReservationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ReservationPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	reservedOn, err := time.Parse(dateLayout, payload.ReservedOn)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	expiresOn, err := time.Parse(dateLayout, payload.ExpiresOn)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if expiresOn.Before(reservedOn) {
+		app.badRequestResponse(w, r, errReservationExpiry)
+		return
+	}
+
+	reservation := &models.Reservation{
+		StudentID:     payload.StudentID,
+		SchoolYear:    payload.SchoolYear,
+		GradeLevel:    strings.ToLower(payload.GradeLevel),
+		InvoiceNumber: payload.InvoiceNumber,
+		Fee:           payload.Fee,
+		PaymentMethod: strings.ToLower(payload.PaymentMethod),
+		ReservedOn:    reservedOn,
+		ExpiresOn:     expiresOn,
+		Notes:         payload.Notes,
+	}
+
+	if err := app.store.Reservations.Create(r.Context(), reservation); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	created, err := app.store.Reservations.GetByID(r.Context(), reservation.ID)
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusCreated, created); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getReservationsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	query := ReservationQuery{
+		SchoolYear: qs.Get("school_year"),
+		Status:     strings.ToLower(qs.Get("status")),
+	}
+	if query.SchoolYear == "" {
+		query.SchoolYear = currentSchoolYear(time.Now())
+	}
+
+	if err := utils.Validate.Struct(query); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	reservations, err := app.store.Reservations.GetAll(r.Context(), query.SchoolYear, query.Status)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, reservations); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getOpenReservationsReportHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+
+	query := SchoolYearQuery{SchoolYear: r.URL.Query().Get("school_year")}
+	if query.SchoolYear == "" {
+		query.SchoolYear = currentSchoolYear(now)
+	}
+
+	if err := utils.Validate.Struct(query); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	report, err := app.store.Reservations.GetOpenReport(r.Context(), query.SchoolYear, now)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, report); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getReservationHandler(w http.ResponseWriter, r *http.Request) {
+	reservation := app.getReservationFromCtx(r)
+
+	if err := utils.ResponseJSON(w, http.StatusOK, reservation); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) convertReservationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ConvertReservationPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	enrollment := &models.Enrollment{
+		Type:           strings.ToLower(payload.Type),
+		MonthlyTuition: payload.MonthlyTuition,
+		EnrollmentFee:  payload.EnrollmentFee,
+		MiscFee:        payload.MiscFee,
+		PtaFee:         payload.PtaFee,
+		LmsFee:         payload.LmsFee,
+		Discounts:      getDiscounts(payload.AvailDiscounts, payload.MonthlyTuition, payload.LmsFee),
+	}
+
+	if err := app.store.Reservations.Convert(r.Context(), app.getReservationFromCtx(r).ID, enrollment); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusCreated, enrollment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) forfeitReservationHandler(w http.ResponseWriter, r *http.Request) {
+	id := app.getReservationFromCtx(r).ID
+
+	if err := app.store.Reservations.Forfeit(r.Context(), id); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	reservation, err := app.store.Reservations.GetByID(r.Context(), id)
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, reservation); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// expireReservations periodically expires the reservations that have passed
+// their expiry date, releasing their seats and keeping their fees.
+func (app *application) expireReservations(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := app.store.Reservations.ExpireDue(ctx, time.Now())
+			if err != nil {
+				app.logger.Errorw("failed to expire reservations", "error", err)
+				continue
+			}
+			if expired > 0 {
+				app.logger.Infow("expired reservations", "count", expired)
+			}
+		}
+	}
+}
+
+func (app *application) reservationContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idString := chi.URLParam(r, reservationID)
+
+		id, err := uuid.Parse(idString)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		reservation, err := app.store.Reservations.GetByID(ctx, id)
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, reservationCtx, reservation)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getReservationFromCtx(r *http.Request) models.Reservation {
+	reservation, _ := r.Context().Value(reservationCtx).(models.Reservation)
+	return reservation
+}
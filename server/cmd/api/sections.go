@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type sectionKey string
+
+const (
+	sectionID             = "sectionID"
+	sectionCtx sectionKey = "section"
+)
+
+type SectionPayload struct {
+	SchoolYear string `json:"school_year" validate:"required,schoolyear"`
+	GradeLevel string `json:"grade_level" validate:"oneofci=nursery-1 nursery-2 kinder-1 kinder-2 grade-1 grade-2 grade-3 grade-4 grade-5 grade-6 grade-7"`
+	UpdateSectionPayload
+}
+
+type UpdateSectionPayload struct {
+	Name     string `json:"name" validate:"required,trimmedSpace,max=100"`
+	Adviser  string `json:"adviser" validate:"omitempty,trimmedSpace,max=100"`
+	Capacity int    `json:"capacity" validate:"required,gt=0"`
+}
+
+type SectionQuery struct {
+	SchoolYear string `json:"school_year" validate:"schoolyear"`
+	GradeLevel string `json:"grade_level" validate:"omitempty,oneofci=nursery-1 nursery-2 kinder-1 kinder-2 grade-1 grade-2 grade-3 grade-4 grade-5 grade-6 grade-7"`
+}
+
+type AutoAssignSectionsPayload struct {
+	SchoolYear string `json:"school_year" validate:"required,schoolyear"`
+	GradeLevel string `json:"grade_level" validate:"oneofci=nursery-1 nursery-2 kinder-1 kinder-2 grade-1 grade-2 grade-3 grade-4 grade-5 grade-6 grade-7"`
+}
+
+type AssignSectionPayload struct {
+	SectionID *uuid.UUID `json:"section_id"`
+}
+
+func (app *application) createSectionHandler(w http.ResponseWriter, r *http.Request) {
+	var payload SectionPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	section := &models.Section{
+		SchoolYear: payload.SchoolYear,
+		GradeLevel: strings.ToLower(payload.GradeLevel),
+		Name:       payload.Name,
+		Adviser:    payload.Adviser,
+		Capacity:   payload.Capacity,
+	}
+
+	if err := app.store.Sections.Create(r.Context(), section); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusCreated, section); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getSectionsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	query := SectionQuery{
+		SchoolYear: qs.Get("school_year"),
+		GradeLevel: strings.ToLower(qs.Get("grade_level")),
+	}
+	if query.SchoolYear == "" {
+		query.SchoolYear = currentSchoolYear(time.Now())
+	}
+
+	if err := utils.Validate.Struct(query); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	sections, err := app.store.Sections.GetAll(r.Context(), query.SchoolYear, query.GradeLevel)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, sections); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getSectionHandler(w http.ResponseWriter, r *http.Request) {
+	section := app.getSectionFromCtx(r)
+
+	if err := utils.ResponseJSON(w, http.StatusOK, section); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateSectionHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateSectionPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	section := app.getSectionFromCtx(r)
+	section.Name = payload.Name
+	section.Adviser = payload.Adviser
+	section.Capacity = payload.Capacity
+
+	if err := app.store.Sections.Update(r.Context(), &section); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, section); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteSectionHandler(w http.ResponseWriter, r *http.Request) {
+	section := app.getSectionFromCtx(r)
+
+	if err := app.store.Sections.Delete(r.Context(), section.ID); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) autoAssignSectionsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload AutoAssignSectionsPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	balance, err := app.store.Sections.AutoAssign(r.Context(), payload.SchoolYear, strings.ToLower(payload.GradeLevel))
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, balance); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getClassListHandler returns the students of a section as JSON, or as a CSV
+// download with ?format=csv.
+func (app *application) getClassListHandler(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format != "" && format != "json" && format != "csv" {
+		app.badRequestResponse(w, r, fmt.Errorf("unsupported format %q", format))
+		return
+	}
+
+	list, err := app.store.Sections.GetClassList(r.Context(), app.getSectionFromCtx(r).ID)
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if format != "csv" {
+		if err := utils.ResponseJSON(w, http.StatusOK, list); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", classListFilename(list.Section)))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"No.", "Last Name", "First Name", "Middle Name", "Suffix", "Gender", "Birthdate", "Contact Numbers"})
+	for i, student := range list.Students {
+		cw.Write([]string{
+			strconv.Itoa(i + 1),
+			student.LastName,
+			student.FirstName,
+			student.MiddleName,
+			student.Suffix,
+			student.Gender,
+			student.Birthdate.Format(dateLayout),
+			strings.Join(student.ContactNumbers, ", "),
+		})
+	}
+	cw.Flush()
+
+	// The status line is already sent, so a failed write can only be logged.
+	if err := cw.Error(); err != nil {
+		app.logger.Errorw("writing class list", "section_id", list.Section.ID, "error", err)
+	}
+}
+
+func (app *application) assignSectionHandler(w http.ResponseWriter, r *http.Request) {
+	var payload AssignSectionPayload
+
+	if err := utils.ReadJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	enrollmentID := app.getEnrollmentIDFromCtx(r)
+
+	if err := app.store.Sections.Assign(r.Context(), enrollmentID, payload.SectionID); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	enrollment, err := app.store.Enrollments.GetEnrollmentByID(r.Context(), enrollmentID)
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if err := utils.ResponseJSON(w, http.StatusOK, enrollment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) sectionContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idString := chi.URLParam(r, sectionID)
+
+		id, err := uuid.Parse(idString)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		section, err := app.store.Sections.GetByID(ctx, id)
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, sectionCtx, section)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getSectionFromCtx(r *http.Request) models.Section {
+	section, _ := r.Context().Value(sectionCtx).(models.Section)
+	return section
+}
+
+// classListFilename names an exported class list after its section, e.g.
+// "2025-2026_grade-1_rizal.csv".
+func classListFilename(section models.Section) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(section.Name))
+
+	return fmt.Sprintf("%s_%s_%s.csv", section.SchoolYear, section.GradeLevel, name)
+}
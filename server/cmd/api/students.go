@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/edzhabs/bookkeeping/internal/models"
-	"github.com/edzhabs/bookkeeping/internal/store"
 	"github.com/edzhabs/bookkeeping/internal/utils"
 )
 
@@ -56,12 +55,7 @@ func (app *application) createStudentHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	if err := app.store.Students.Create(r.Context(), student); err != nil {
-		switch err {
-		case store.ErrDuplicate:
-			app.badRequestResponse(w, r, err)
-		default:
-			app.internalServerError(w, r, err)
-		}
+		app.storeErrorResponse(w, r, err)
 		return
 	}
 
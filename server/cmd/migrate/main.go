@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/edzhabs/bookkeeping/cmd/migrate/migrations"
+	"github.com/edzhabs/bookkeeping/internal/config"
+	"github.com/edzhabs/bookkeeping/internal/db"
+)
+
+const usage = `usage: migrate <command>
+
+commands:
+  up             apply all pending migrations
+  down [N|all]   roll back N migrations (default 1) or all of them
+  status         print the applied and latest migration versions
+  force V        set the version without running migrations, to recover
+                 from a failed migration`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	conn, err := db.New(cfg.DB.Addr, 1, 1, "15m")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	migrator, err := db.NewMigrator(context.Background(), conn, migrations.FS)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer migrator.Close()
+
+	if err := run(migrator, os.Args[1], os.Args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(migrator *db.Migrator, command string, args []string) error {
+	switch command {
+	case "up":
+		if err := migrator.Up(); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			if args[0] == "all" {
+				steps = 0
+			} else {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < 1 {
+					return fmt.Errorf("invalid number of migrations %q", args[0])
+				}
+				steps = n
+			}
+		}
+		if err := migrator.Down(steps); err != nil {
+			return err
+		}
+	case "force":
+		if len(args) != 1 {
+			return fmt.Errorf("force needs a version\n\n%s", usage)
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		if err := migrator.Force(version); err != nil {
+			return err
+		}
+	case "status":
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, usage)
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("version: %d\nlatest:  %d\ndirty:   %t\n", status.Version, status.Latest, status.Dirty)
+
+	return nil
+}
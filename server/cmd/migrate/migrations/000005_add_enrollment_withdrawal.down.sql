@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_enrollments_status;
+
+ALTER TABLE enrollments
+    DROP CONSTRAINT IF EXISTS check_withdrawal_details,
+    DROP CONSTRAINT IF EXISTS check_enrollment_status,
+    DROP COLUMN IF EXISTS months_attended,
+    DROP COLUMN IF EXISTS withdrawal_reason,
+    DROP COLUMN IF EXISTS withdrawal_date,
+    DROP COLUMN IF EXISTS status;
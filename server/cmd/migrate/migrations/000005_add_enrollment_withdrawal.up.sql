@@ -0,0 +1,11 @@
+ALTER TABLE enrollments
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'enrolled',
+    ADD COLUMN withdrawal_date DATE DEFAULT NULL,
+    ADD COLUMN withdrawal_reason TEXT DEFAULT NULL,
+    ADD COLUMN months_attended INTEGER DEFAULT NULL,
+    ADD CONSTRAINT check_enrollment_status CHECK (status IN ('enrolled', 'withdrawn')),
+    ADD CONSTRAINT check_withdrawal_details CHECK (
+        status <> 'withdrawn' OR (withdrawal_date IS NOT NULL AND months_attended IS NOT NULL)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_enrollments_status ON enrollments (status) WHERE deleted_at IS NULL;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS expenses;
+ALTER TABLE tuition_payments DROP CONSTRAINT IF EXISTS check_positive_payment;
+DROP TRIGGER IF EXISTS check_journal_entry_balance ON journal_lines;
+DROP FUNCTION IF EXISTS validate_journal_entry_balance();
+DROP TABLE IF EXISTS journal_lines;
+DROP TABLE IF EXISTS journal_entries;
+DROP TABLE IF EXISTS accounts;
@@ -0,0 +1,211 @@
+-- Chart of accounts
+CREATE TABLE IF NOT EXISTS accounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(10) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('asset', 'liability', 'equity', 'revenue', 'expense')),
+    created_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ(0) NOT NULL DEFAULT now()
+);
+
+INSERT INTO accounts (code, name, type) VALUES
+    ('1000', 'Cash on Hand', 'asset'),
+    ('1010', 'GCash', 'asset'),
+    ('1020', 'Cash in Bank', 'asset'),
+    ('1100', 'Tuition Receivable', 'asset'),
+    ('2000', 'Refunds Payable', 'liability'),
+    ('3000', 'Retained Earnings', 'equity'),
+    ('4000', 'Tuition Revenue', 'revenue'),
+    ('4010', 'Enrollment Fee Revenue', 'revenue'),
+    ('4020', 'Miscellaneous Fee Revenue', 'revenue'),
+    ('4030', 'PTA Fee Revenue', 'revenue'),
+    ('4040', 'LMS and Books Revenue', 'revenue'),
+    ('4900', 'Tuition Discounts', 'revenue'),
+    ('4910', 'LMS and Books Discounts', 'revenue'),
+    ('4920', 'Carpool Discounts', 'revenue'),
+    ('5000', 'Operating Expenses', 'expense')
+ON CONFLICT (code) DO NOTHING;
+
+-- Journal
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entry_date DATE NOT NULL,
+    description TEXT NOT NULL,
+    source_type VARCHAR(30) NOT NULL,
+    source_id UUID NOT NULL,
+    created_at TIMESTAMPTZ(0) NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_journal_entries_source ON journal_entries (source_type, source_id);
+CREATE INDEX IF NOT EXISTS idx_journal_entries_entry_date ON journal_entries (entry_date);
+
+CREATE TABLE IF NOT EXISTS journal_lines (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    journal_entry_id UUID NOT NULL REFERENCES journal_entries(id),
+    account_id UUID NOT NULL REFERENCES accounts(id),
+    debit NUMERIC(12,2) NOT NULL DEFAULT 0,
+    credit NUMERIC(12,2) NOT NULL DEFAULT 0,
+    CONSTRAINT check_journal_line_amount CHECK (
+        debit >= 0 AND credit >= 0 AND (debit = 0) <> (credit = 0)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_journal_lines_entry ON journal_lines (journal_entry_id);
+CREATE INDEX IF NOT EXISTS idx_journal_lines_account ON journal_lines (account_id);
+
+-- Every journal entry must balance once the transaction commits
+CREATE OR REPLACE FUNCTION validate_journal_entry_balance()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM journal_lines jl
+        WHERE jl.journal_entry_id = NEW.journal_entry_id
+        HAVING SUM(jl.debit) <> SUM(jl.credit)
+    ) THEN
+        RAISE EXCEPTION 'Journal entry % is not balanced.', NEW.journal_entry_id
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'journal_entry_balanced';
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER check_journal_entry_balance
+AFTER INSERT OR UPDATE ON journal_lines
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW
+EXECUTE FUNCTION validate_journal_entry_balance();
+
+-- Payments must carry a positive amount to be posted
+ALTER TABLE tuition_payments
+    ADD CONSTRAINT check_positive_payment
+    CHECK (
+        COALESCE(reservation_fee, 0) >= 0 AND
+        COALESCE(tuition_fee, 0) >= 0 AND
+        COALESCE(advance_payment, 0) >= 0 AND
+        COALESCE(reservation_fee, 0) + COALESCE(tuition_fee, 0) + COALESCE(advance_payment, 0) > 0
+    );
+
+-- Expenses
+CREATE TABLE IF NOT EXISTS expenses (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    expense_date DATE NOT NULL,
+    category VARCHAR(50) NOT NULL,
+    description TEXT NOT NULL,
+    amount NUMERIC(10,2) NOT NULL CHECK (amount > 0),
+    payment_method VARCHAR(10) NOT NULL CHECK (payment_method IN ('cash', 'gcash', 'bank')),
+    created_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ(0) DEFAULT NULL
+);
+
+-- Post the records that predate the ledger, so existing databases start with
+-- the same balances the store would have posted: billing, discounts and any
+-- withdrawal refund per enrollment, and each payment against receivables.
+WITH enrollment_amounts AS (
+    SELECT
+        e.id,
+        e.created_at::date AS billed_on,
+        e.withdrawal_date,
+        e.status,
+        e.monthly_tuition * COALESCE(e.months_attended, e.months) AS tuition,
+        e.enrollment_fee,
+        e.misc_fee,
+        e.pta_fee,
+        e.lms_books_fee,
+        e.monthly_tuition * COALESCE(e.months_attended, e.months)
+            + e.enrollment_fee + e.misc_fee + e.pta_fee + e.lms_books_fee AS billed,
+        COALESCE((
+            SELECT SUM(COALESCE(tp.reservation_fee, 0) + COALESCE(tp.tuition_fee, 0) + COALESCE(tp.advance_payment, 0))
+            FROM tuition_payments tp
+            WHERE tp.enrollment_id = e.id AND tp.deleted_at IS NULL
+        ), 0) AS paid
+    FROM enrollments e
+    WHERE e.deleted_at IS NULL
+),
+discount_amounts AS (
+    SELECT
+        d.enrollment_id,
+        CASE d.scope WHEN 'lms_books' THEN '4910' WHEN 'carpool' THEN '4920' ELSE '4900' END AS account_code,
+        SUM(
+            CASE WHEN d.scope = 'tuition'
+                THEN ROUND(COALESCE(d.amount, 0) * COALESCE(e.months_attended, e.months) / e.months, 2)
+                ELSE COALESCE(d.amount, 0)
+            END
+        ) AS amount
+    FROM discounts d
+    JOIN enrollments e ON e.id = d.enrollment_id
+    WHERE d.deleted_at IS NULL AND e.deleted_at IS NULL
+    GROUP BY d.enrollment_id, 2
+),
+discount_totals AS (
+    SELECT enrollment_id, SUM(amount) AS amount
+    FROM discount_amounts
+    GROUP BY enrollment_id
+),
+opening_lines AS (
+    SELECT 'enrollment' AS source_type, ea.id AS source_id, ea.billed_on AS entry_date,
+        'Enrollment billing' AS description, l.account_code, l.amount
+    FROM enrollment_amounts ea
+    CROSS JOIN LATERAL (VALUES
+        ('4000', -ea.tuition),
+        ('4010', -ea.enrollment_fee),
+        ('4020', -ea.misc_fee),
+        ('4030', -ea.pta_fee),
+        ('4040', -ea.lms_books_fee),
+        ('1100', ea.billed)
+    ) AS l (account_code, amount)
+
+    UNION ALL
+
+    SELECT 'discount', ea.id, ea.billed_on, 'Enrollment discounts', da.account_code, da.amount
+    FROM discount_amounts da
+    JOIN enrollment_amounts ea ON ea.id = da.enrollment_id
+
+    UNION ALL
+
+    SELECT 'discount', ea.id, ea.billed_on, 'Enrollment discounts', '1100', -dt.amount
+    FROM discount_totals dt
+    JOIN enrollment_amounts ea ON ea.id = dt.enrollment_id
+
+    UNION ALL
+
+    SELECT 'refund', ea.id, ea.withdrawal_date, 'Withdrawal refund', l.account_code, l.amount
+    FROM enrollment_amounts ea
+    LEFT JOIN discount_totals dt ON dt.enrollment_id = ea.id
+    CROSS JOIN LATERAL (VALUES
+        ('1100', ea.paid - (ea.billed - COALESCE(dt.amount, 0))),
+        ('2000', -(ea.paid - (ea.billed - COALESCE(dt.amount, 0))))
+    ) AS l (account_code, amount)
+    WHERE ea.status = 'withdrawn' AND ea.paid > ea.billed - COALESCE(dt.amount, 0)
+
+    UNION ALL
+
+    SELECT 'payment', tp.id, tp.payment_date, 'Tuition payment ' || tp.invoice_number, l.account_code, l.amount
+    FROM tuition_payments tp
+    CROSS JOIN LATERAL (
+        SELECT COALESCE(tp.reservation_fee, 0) + COALESCE(tp.tuition_fee, 0) + COALESCE(tp.advance_payment, 0) AS amount
+    ) AS p
+    CROSS JOIN LATERAL (VALUES
+        (CASE tp.payment_method WHEN 'gcash' THEN '1010' WHEN 'bank' THEN '1020' ELSE '1000' END, p.amount),
+        ('1100', -p.amount)
+    ) AS l (account_code, amount)
+    WHERE tp.deleted_at IS NULL
+),
+opening_entries AS (
+    INSERT INTO journal_entries (entry_date, description, source_type, source_id)
+    SELECT DISTINCT entry_date, description, source_type, source_id
+    FROM opening_lines
+    WHERE amount <> 0
+    RETURNING id, source_type, source_id
+)
+INSERT INTO journal_lines (journal_entry_id, account_id, debit, credit)
+SELECT
+    oe.id,
+    a.id,
+    GREATEST(ol.amount, 0),
+    GREATEST(-ol.amount, 0)
+FROM opening_lines ol
+JOIN opening_entries oe ON oe.source_type = ol.source_type AND oe.source_id = ol.source_id
+JOIN accounts a ON a.code = ol.account_code
+WHERE ol.amount <> 0;
@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS check_open_period ON journal_entries;
+DROP FUNCTION IF EXISTS validate_open_period();
+
+ALTER TABLE journal_entries
+    DROP COLUMN IF EXISTS created_by,
+    DROP COLUMN IF EXISTS is_adjustment;
+
+DROP TABLE IF EXISTS closed_periods;
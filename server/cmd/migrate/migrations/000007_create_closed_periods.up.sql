@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS closed_periods (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    period_type VARCHAR(20) NOT NULL CHECK (period_type IN ('month', 'school_year')),
+    school_year VARCHAR(20) DEFAULT NULL,
+    period_month DATE DEFAULT NULL,
+    closed_by VARCHAR(100) NOT NULL,
+    closed_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    CONSTRAINT check_closed_period_key CHECK (
+        (period_type = 'month' AND period_month IS NOT NULL AND school_year IS NULL) OR
+        (period_type = 'school_year' AND school_year IS NOT NULL AND period_month IS NULL)
+    )
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_closed_periods_month
+ON closed_periods (period_month) WHERE period_type = 'month';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_closed_periods_school_year
+ON closed_periods (school_year) WHERE period_type = 'school_year';
+
+ALTER TABLE journal_entries
+    ADD COLUMN is_adjustment BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN created_by VARCHAR(100) DEFAULT NULL;
+
+-- Only adjusting entries may be dated inside a closed month
+CREATE OR REPLACE FUNCTION validate_open_period()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NOT NEW.is_adjustment AND EXISTS (
+        SELECT 1 FROM closed_periods cp
+        WHERE cp.period_type = 'month'
+          AND cp.period_month = date_trunc('month', NEW.entry_date)::date
+    ) THEN
+        RAISE EXCEPTION 'Accounting period % is closed.', to_char(NEW.entry_date, 'YYYY-MM')
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'open_accounting_period';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER check_open_period
+BEFORE INSERT ON journal_entries
+FOR EACH ROW
+EXECUTE FUNCTION validate_open_period();
@@ -0,0 +1 @@
+ALTER TABLE enrollments DROP COLUMN IF EXISTS version;
@@ -0,0 +1 @@
+ALTER TABLE enrollments ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(255) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path TEXT NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'in_progress' CHECK (status IN ('in_progress', 'completed')),
+    response_status INTEGER DEFAULT NULL,
+    response_headers JSONB DEFAULT NULL,
+    response_body BYTEA DEFAULT NULL,
+    created_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    locked_until TIMESTAMPTZ(0) NOT NULL,
+    expires_at TIMESTAMPTZ(0) NOT NULL,
+
+    PRIMARY KEY (key, method, path)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,44 @@
+-- Name the discount rule violations so the API can tell them apart from
+-- other errors without matching on the message text.
+CREATE OR REPLACE FUNCTION validate_discount_rules()
+RETURNS TRIGGER AS $$
+BEGIN
+    -- Ensure carpool is alone
+    IF NEW.type = 'carpool' THEN
+        IF EXISTS (
+            SELECT 1 FROM discounts d
+            WHERE d.enrollment_id = NEW.enrollment_id
+              AND d.type != 'carpool'
+              AND d.deleted_at IS NULL
+        ) THEN
+            RAISE EXCEPTION 'Cannot combine carpool discount with other discounts.'
+                USING ERRCODE = 'check_violation', CONSTRAINT = 'discount_carpool_exclusive';
+        END IF;
+    ELSE
+        -- Ensure sibling, full_year, and scholar cannot coexist
+        IF (NEW.type = 'sibling' AND EXISTS (
+            SELECT 1 FROM discounts d
+            WHERE d.enrollment_id = NEW.enrollment_id
+              AND d.type IN ('full_year', 'scholar')
+              AND d.deleted_at IS NULL
+        )) OR
+           (NEW.type = 'full_year' AND EXISTS (
+            SELECT 1 FROM discounts d
+            WHERE d.enrollment_id = NEW.enrollment_id
+              AND d.type IN ('sibling', 'scholar')
+              AND d.deleted_at IS NULL
+        )) OR
+           (NEW.type = 'scholar' AND EXISTS (
+            SELECT 1 FROM discounts d
+            WHERE d.enrollment_id = NEW.enrollment_id
+              AND d.type IN ('sibling', 'full_year')
+              AND d.deleted_at IS NULL
+        )) THEN
+            RAISE EXCEPTION 'Cannot combine sibling, full_year, and scholar tuition discounts.'
+                USING ERRCODE = 'check_violation', CONSTRAINT = 'discount_tuition_exclusive';
+        END IF;
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
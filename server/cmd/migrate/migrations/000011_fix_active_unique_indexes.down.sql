@@ -0,0 +1,21 @@
+DROP INDEX IF EXISTS idx_discounts_active_type_scope;
+
+ALTER TABLE discounts ADD UNIQUE (enrollment_id, type, scope, deleted_at);
+
+DROP INDEX IF EXISTS enrollments_student_id_school_year_key;
+
+ALTER TABLE enrollments ADD UNIQUE (student_id, school_year, deleted_at);
+
+DROP INDEX IF EXISTS idx_unique_student_name_birthday_gender;
+
+CREATE UNIQUE INDEX idx_unique_student_name_birthday_gender
+ON students (
+  UPPER(first_name),
+  UPPER(middle_name),
+  UPPER(last_name),
+  birthdate,
+  LOWER(gender),
+  COALESCE(suffix, ''),
+  deleted_at
+)
+WHERE deleted_at IS NULL;
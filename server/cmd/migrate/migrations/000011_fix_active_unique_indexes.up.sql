@@ -0,0 +1,28 @@
+-- The original unique keys included deleted_at, which is NULL on every active
+-- row, so Postgres never treated two active rows as duplicates. Partial
+-- indexes over the active rows enforce the intended uniqueness and give the
+-- discount upsert the conflict target it names.
+DROP INDEX IF EXISTS idx_unique_student_name_birthday_gender;
+
+CREATE UNIQUE INDEX idx_unique_student_name_birthday_gender
+ON students (
+  UPPER(first_name),
+  UPPER(middle_name),
+  UPPER(last_name),
+  birthdate,
+  LOWER(gender),
+  COALESCE(suffix, '')
+)
+WHERE deleted_at IS NULL;
+
+ALTER TABLE enrollments DROP CONSTRAINT IF EXISTS enrollments_student_id_school_year_deleted_at_key;
+
+CREATE UNIQUE INDEX enrollments_student_id_school_year_key
+ON enrollments (student_id, school_year)
+WHERE deleted_at IS NULL;
+
+ALTER TABLE discounts DROP CONSTRAINT IF EXISTS discounts_enrollment_id_type_scope_deleted_at_key;
+
+CREATE UNIQUE INDEX idx_discounts_active_type_scope
+ON discounts (enrollment_id, type, scope)
+WHERE deleted_at IS NULL;
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS sync_enrollment_balance ON tuition_payments;
+DROP TRIGGER IF EXISTS sync_enrollment_balance ON discounts;
+DROP TRIGGER IF EXISTS sync_enrollment_balance ON enrollments;
+DROP FUNCTION IF EXISTS sync_enrollment_balance();
+DROP FUNCTION IF EXISTS refresh_enrollment_balance(UUID);
+DROP TABLE IF EXISTS enrollment_balances;
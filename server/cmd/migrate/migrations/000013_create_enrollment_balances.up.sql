@@ -0,0 +1,78 @@
+-- Read model of enrollment_totals kept current by triggers, so list endpoints
+-- read one row per enrollment instead of aggregating discounts and payments.
+CREATE TABLE IF NOT EXISTS enrollment_balances (
+    enrollment_id UUID PRIMARY KEY REFERENCES enrollments(id) ON DELETE CASCADE,
+    discount_types TEXT[] NOT NULL DEFAULT '{}',
+    total_amount NUMERIC(12,2) NOT NULL,
+    total_paid NUMERIC(12,2) NOT NULL,
+    remaining_amount NUMERIC(12,2) NOT NULL,
+    payment_status VARCHAR(10) NOT NULL CHECK (payment_status IN ('unpaid', 'partial', 'paid')),
+    refreshed_at TIMESTAMPTZ(0) NOT NULL DEFAULT now()
+);
+
+CREATE OR REPLACE FUNCTION refresh_enrollment_balance(p_enrollment_id UUID)
+RETURNS void AS $$
+BEGIN
+    -- Serialize refreshes of the same enrollment. The lock is held until
+    -- commit, and the statements below then take a fresh snapshot, so a
+    -- refresh that waited sees the writes of the one it waited for.
+    PERFORM 1 FROM enrollments WHERE id = p_enrollment_id FOR NO KEY UPDATE;
+
+    INSERT INTO enrollment_balances
+        (enrollment_id, discount_types, total_amount, total_paid, remaining_amount, payment_status, refreshed_at)
+    SELECT enrollment_id, discount_types, total_amount, total_paid, remaining_amount, payment_status, now()
+    FROM enrollment_totals
+    WHERE enrollment_id = p_enrollment_id
+    ON CONFLICT (enrollment_id) DO UPDATE SET
+        discount_types = EXCLUDED.discount_types,
+        total_amount = EXCLUDED.total_amount,
+        total_paid = EXCLUDED.total_paid,
+        remaining_amount = EXCLUDED.remaining_amount,
+        payment_status = EXCLUDED.payment_status,
+        refreshed_at = EXCLUDED.refreshed_at;
+
+    -- Soft-deleted enrollments drop out of enrollment_totals.
+    IF NOT FOUND THEN
+        DELETE FROM enrollment_balances WHERE enrollment_id = p_enrollment_id;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION sync_enrollment_balance()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_TABLE_NAME = 'enrollments' THEN
+        -- Hard deletes cascade to the balance row.
+        IF TG_OP <> 'DELETE' THEN
+            PERFORM refresh_enrollment_balance(NEW.id);
+        END IF;
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP <> 'DELETE' THEN
+        PERFORM refresh_enrollment_balance(NEW.enrollment_id);
+    END IF;
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.enrollment_id <> NEW.enrollment_id) THEN
+        PERFORM refresh_enrollment_balance(OLD.enrollment_id);
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER sync_enrollment_balance
+AFTER INSERT OR UPDATE OR DELETE ON enrollments
+FOR EACH ROW EXECUTE FUNCTION sync_enrollment_balance();
+
+CREATE TRIGGER sync_enrollment_balance
+AFTER INSERT OR UPDATE OR DELETE ON discounts
+FOR EACH ROW EXECUTE FUNCTION sync_enrollment_balance();
+
+CREATE TRIGGER sync_enrollment_balance
+AFTER INSERT OR UPDATE OR DELETE ON tuition_payments
+FOR EACH ROW EXECUTE FUNCTION sync_enrollment_balance();
+
+INSERT INTO enrollment_balances
+    (enrollment_id, discount_types, total_amount, total_paid, remaining_amount, payment_status)
+SELECT enrollment_id, discount_types, total_amount, total_paid, remaining_amount, payment_status
+FROM enrollment_totals;
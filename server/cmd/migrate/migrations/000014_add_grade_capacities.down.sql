@@ -0,0 +1,65 @@
+DROP INDEX IF EXISTS idx_enrollments_waitlist;
+
+DROP TABLE IF EXISTS grade_capacities;
+
+CREATE OR REPLACE VIEW enrollment_totals AS
+WITH discount_totals AS (
+    SELECT
+        d.enrollment_id,
+        array_agg(DISTINCT d.type::text ORDER BY d.type::text) AS discount_types,
+        SUM(
+            CASE WHEN d.scope = 'tuition'
+                THEN ROUND(COALESCE(d.amount, 0) * COALESCE(e.months_attended, e.months) / e.months, 2)
+                ELSE COALESCE(d.amount, 0)
+            END
+        ) AS total
+    FROM discounts d
+    JOIN enrollments e ON e.id = d.enrollment_id
+    WHERE d.deleted_at IS NULL
+    GROUP BY d.enrollment_id
+),
+payment_totals AS (
+    SELECT
+        tp.enrollment_id,
+        SUM(COALESCE(tp.reservation_fee, 0) + COALESCE(tp.tuition_fee, 0) + COALESCE(tp.advance_payment, 0)) AS total
+    FROM tuition_payments tp
+    WHERE tp.deleted_at IS NULL
+    GROUP BY tp.enrollment_id
+),
+totals AS (
+    SELECT
+        e.id AS enrollment_id,
+        COALESCE(dt.discount_types, ARRAY[]::text[]) AS discount_types,
+        e.monthly_tuition * COALESCE(e.months_attended, e.months)
+            + e.enrollment_fee + e.misc_fee + e.pta_fee + e.lms_books_fee
+            - COALESCE(dt.total, 0) AS total_amount,
+        COALESCE(pt.total, 0) AS total_paid
+    FROM enrollments e
+    LEFT JOIN discount_totals dt ON dt.enrollment_id = e.id
+    LEFT JOIN payment_totals pt ON pt.enrollment_id = e.id
+    WHERE e.deleted_at IS NULL
+)
+SELECT
+    enrollment_id,
+    discount_types,
+    total_amount,
+    total_paid,
+    total_amount - total_paid AS remaining_amount,
+    CASE
+        WHEN total_paid = 0 THEN 'unpaid'
+        WHEN total_paid >= total_amount THEN 'paid'
+        ELSE 'partial'
+    END AS payment_status
+FROM totals;
+
+-- The waitlist has no equivalent before this migration, so waitlisted
+-- enrollments are dropped.
+UPDATE enrollments
+SET status = 'enrolled', deleted_at = COALESCE(deleted_at, now())
+WHERE status = 'waitlisted';
+
+ALTER TABLE enrollments
+    DROP CONSTRAINT IF EXISTS check_waitlisted_at,
+    DROP COLUMN IF EXISTS waitlisted_at,
+    DROP CONSTRAINT IF EXISTS check_enrollment_status,
+    ADD CONSTRAINT check_enrollment_status CHECK (status IN ('enrolled', 'withdrawn'));
@@ -0,0 +1,73 @@
+ALTER TABLE enrollments
+    ADD COLUMN waitlisted_at TIMESTAMPTZ DEFAULT NULL,
+    DROP CONSTRAINT IF EXISTS check_enrollment_status,
+    ADD CONSTRAINT check_enrollment_status CHECK (status IN ('enrolled', 'withdrawn', 'waitlisted')),
+    ADD CONSTRAINT check_waitlisted_at CHECK (status <> 'waitlisted' OR waitlisted_at IS NOT NULL);
+
+-- Seats per grade level and school year. Grades without a row are not capped.
+CREATE TABLE IF NOT EXISTS grade_capacities (
+    school_year VARCHAR(20) NOT NULL,
+    grade_level VARCHAR(20) NOT NULL,
+    capacity INTEGER NOT NULL CHECK (capacity >= 0),
+    created_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (school_year, grade_level)
+);
+
+CREATE INDEX IF NOT EXISTS idx_enrollments_waitlist
+ON enrollments (school_year, grade_level, waitlisted_at)
+WHERE status = 'waitlisted' AND deleted_at IS NULL;
+
+-- Waitlisted enrollments are not billed until they are promoted.
+CREATE OR REPLACE VIEW enrollment_totals AS
+WITH discount_totals AS (
+    SELECT
+        d.enrollment_id,
+        array_agg(DISTINCT d.type::text ORDER BY d.type::text) AS discount_types,
+        SUM(
+            CASE WHEN d.scope = 'tuition'
+                THEN ROUND(COALESCE(d.amount, 0) * COALESCE(e.months_attended, e.months) / e.months, 2)
+                ELSE COALESCE(d.amount, 0)
+            END
+        ) AS total
+    FROM discounts d
+    JOIN enrollments e ON e.id = d.enrollment_id
+    WHERE d.deleted_at IS NULL
+    GROUP BY d.enrollment_id
+),
+payment_totals AS (
+    SELECT
+        tp.enrollment_id,
+        SUM(COALESCE(tp.reservation_fee, 0) + COALESCE(tp.tuition_fee, 0) + COALESCE(tp.advance_payment, 0)) AS total
+    FROM tuition_payments tp
+    WHERE tp.deleted_at IS NULL
+    GROUP BY tp.enrollment_id
+),
+totals AS (
+    SELECT
+        e.id AS enrollment_id,
+        COALESCE(dt.discount_types, ARRAY[]::text[]) AS discount_types,
+        CASE WHEN e.status = 'waitlisted' THEN 0
+            ELSE e.monthly_tuition * COALESCE(e.months_attended, e.months)
+                + e.enrollment_fee + e.misc_fee + e.pta_fee + e.lms_books_fee
+                - COALESCE(dt.total, 0)
+        END AS total_amount,
+        COALESCE(pt.total, 0) AS total_paid
+    FROM enrollments e
+    LEFT JOIN discount_totals dt ON dt.enrollment_id = e.id
+    LEFT JOIN payment_totals pt ON pt.enrollment_id = e.id
+    WHERE e.deleted_at IS NULL
+)
+SELECT
+    enrollment_id,
+    discount_types,
+    total_amount,
+    total_paid,
+    total_amount - total_paid AS remaining_amount,
+    CASE
+        WHEN total_paid = 0 THEN 'unpaid'
+        WHEN total_paid >= total_amount THEN 'paid'
+        ELSE 'partial'
+    END AS payment_status
+FROM totals;
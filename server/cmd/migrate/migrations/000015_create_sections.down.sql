@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_enrollments_section_id;
+
+ALTER TABLE enrollments DROP COLUMN IF EXISTS section_id;
+
+DROP TABLE IF EXISTS sections;
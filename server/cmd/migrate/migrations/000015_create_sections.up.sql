@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS sections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    school_year VARCHAR(20) NOT NULL,
+    grade_level VARCHAR(20) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    adviser VARCHAR(100) NOT NULL DEFAULT '',
+    capacity INTEGER NOT NULL CHECK (capacity > 0),
+    created_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ(0) NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sections_name
+ON sections (school_year, grade_level, LOWER(name));
+
+ALTER TABLE enrollments
+    ADD COLUMN section_id UUID DEFAULT NULL REFERENCES sections(id);
+
+CREATE INDEX IF NOT EXISTS idx_enrollments_section_id
+ON enrollments (section_id)
+WHERE section_id IS NOT NULL AND deleted_at IS NULL;
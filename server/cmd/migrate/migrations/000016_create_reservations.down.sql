@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_tuition_payments_reservation_id;
+ALTER TABLE tuition_payments DROP COLUMN IF EXISTS reservation_id;
+
+DROP TABLE IF EXISTS reservations;
+
+-- The accounts stay if anything has been posted to them
+DELETE FROM accounts a
+WHERE a.code IN ('2010', '4050')
+    AND NOT EXISTS (SELECT 1 FROM journal_lines jl WHERE jl.account_id = a.id);
@@ -0,0 +1,47 @@
+INSERT INTO accounts (code, name, type) VALUES
+    ('2010', 'Reservation Deposits', 'liability'),
+    ('4050', 'Forfeited Reservations', 'revenue')
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id UUID NOT NULL REFERENCES students(id),
+    school_year VARCHAR(20) NOT NULL,
+    grade_level VARCHAR(20) NOT NULL,
+    invoice_number VARCHAR(100) NOT NULL,
+    fee NUMERIC(10,2) NOT NULL CHECK (fee > 0),
+    payment_method VARCHAR(10) NOT NULL CHECK (payment_method IN ('cash', 'gcash', 'bank')),
+    reserved_on DATE NOT NULL,
+    expires_on DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'reserved'
+        CHECK (status IN ('reserved', 'converted', 'expired', 'forfeited')),
+    enrollment_id UUID DEFAULT NULL REFERENCES enrollments(id),
+    notes TEXT NOT NULL DEFAULT '',
+    closed_at TIMESTAMPTZ(0) DEFAULT NULL,
+    created_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+
+    UNIQUE(invoice_number),
+    CONSTRAINT check_reservation_expiry CHECK (expires_on >= reserved_on),
+    CONSTRAINT check_reservation_closed CHECK ((status = 'reserved') = (closed_at IS NULL)),
+    -- The enrollment is linked after it is created, in the same transaction
+    -- that converts the reservation.
+    CONSTRAINT check_reservation_enrollment CHECK (enrollment_id IS NULL OR status = 'converted')
+);
+
+-- A student holds at most one open reservation per school year
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reservations_open_student
+ON reservations (student_id, school_year)
+WHERE status = 'reserved';
+
+CREATE INDEX IF NOT EXISTS idx_reservations_open_grade
+ON reservations (school_year, grade_level, expires_on)
+WHERE status = 'reserved';
+
+-- The payment that credits a converted reservation's fee to its enrollment
+ALTER TABLE tuition_payments
+    ADD COLUMN reservation_id UUID DEFAULT NULL REFERENCES reservations(id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tuition_payments_reservation_id
+ON tuition_payments (reservation_id)
+WHERE reservation_id IS NOT NULL;
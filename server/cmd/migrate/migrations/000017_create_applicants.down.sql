@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS applicant_documents;
+DROP TABLE IF EXISTS applicants;
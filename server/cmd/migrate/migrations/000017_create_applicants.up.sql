@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS applicants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    school_year VARCHAR(20) NOT NULL,
+    grade_level VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'inquiry'
+        CHECK (status IN ('inquiry', 'applied', 'assessed', 'accepted', 'rejected', 'converted')),
+    first_name VARCHAR(100) NOT NULL,
+    middle_name VARCHAR(100) NOT NULL,
+    last_name VARCHAR(100) NOT NULL,
+    suffix VARCHAR(10) DEFAULT NULL,
+    gender VARCHAR(10) NOT NULL CHECK (gender IN ('male', 'female')),
+    birthdate DATE NOT NULL,
+    address TEXT NOT NULL,
+    mother_name VARCHAR(255) DEFAULT NULL,
+    mother_job VARCHAR(100) DEFAULT NULL,
+    mother_education VARCHAR(100) DEFAULT NULL,
+    father_name VARCHAR(255) DEFAULT NULL,
+    father_job VARCHAR(100) DEFAULT NULL,
+    father_education VARCHAR(100) DEFAULT NULL,
+    living_with VARCHAR(100) DEFAULT NULL,
+    contact_numbers VARCHAR(15) [],
+    notes TEXT NOT NULL DEFAULT '',
+    enrollment_id UUID DEFAULT NULL REFERENCES enrollments(id),
+    status_changed_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+
+    CONSTRAINT check_applicant_enrollment CHECK ((status = 'converted') = (enrollment_id IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_applicants_school_year_status
+ON applicants (school_year, status);
+
+-- Documents received from an applicant, one row per checklist item
+CREATE TABLE IF NOT EXISTS applicant_documents (
+    applicant_id UUID NOT NULL REFERENCES applicants(id) ON DELETE CASCADE,
+    type VARCHAR(30) NOT NULL CHECK (type IN ('birth_certificate', 'report_card')),
+    received_on DATE NOT NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (applicant_id, type)
+);
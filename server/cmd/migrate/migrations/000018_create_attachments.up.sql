@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id UUID DEFAULT NULL REFERENCES students(id),
+    enrollment_id UUID DEFAULT NULL REFERENCES enrollments(id),
+    payment_id UUID DEFAULT NULL REFERENCES tuition_payments(id),
+    storage_key TEXT NOT NULL UNIQUE,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    checksum_sha256 CHAR(64) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    CONSTRAINT check_attachment_owner CHECK (num_nonnulls(student_id, enrollment_id, payment_id) = 1)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_student_id
+ON attachments (student_id)
+WHERE student_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_attachments_enrollment_id
+ON attachments (enrollment_id)
+WHERE enrollment_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_attachments_payment_id
+ON attachments (payment_id)
+WHERE payment_id IS NOT NULL;
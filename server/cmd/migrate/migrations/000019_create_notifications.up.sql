@@ -0,0 +1,59 @@
+CREATE TABLE IF NOT EXISTS guardians (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id UUID NOT NULL REFERENCES students(id),
+    name VARCHAR(100) NOT NULL,
+    relationship VARCHAR(20) NOT NULL CHECK (relationship IN ('mother', 'father', 'guardian')),
+    email VARCHAR(254) DEFAULT NULL,
+    phone VARCHAR(20) DEFAULT NULL,
+    email_opt_out BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ(0) NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_guardians_student_id
+ON guardians (student_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_guardians_student_email
+ON guardians (student_id, LOWER(email))
+WHERE email IS NOT NULL;
+
+-- Start every student off with the parents already on file, reachable on the
+-- student's first contact number, so only their emails need adding.
+INSERT INTO guardians (student_id, name, relationship, phone)
+SELECT id, mother_name, 'mother', contact_numbers[1]
+FROM students
+WHERE deleted_at IS NULL AND COALESCE(mother_name, '') <> '';
+
+INSERT INTO guardians (student_id, name, relationship, phone)
+SELECT id, father_name, 'father', contact_numbers[1]
+FROM students
+WHERE deleted_at IS NULL AND COALESCE(father_name, '') <> '';
+
+-- Emails are queued here in the transaction that causes them and sent by a
+-- background worker, so a send survives restarts and is never lost to a
+-- rolled back payment. dedupe_key keeps each notice from being queued twice.
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('receipt', 'upcoming_due', 'overdue')),
+    guardian_id UUID DEFAULT NULL REFERENCES guardians(id) ON DELETE SET NULL,
+    enrollment_id UUID NOT NULL REFERENCES enrollments(id),
+    payment_id UUID DEFAULT NULL REFERENCES tuition_payments(id),
+    recipient VARCHAR(254) NOT NULL,
+    data JSONB NOT NULL,
+    dedupe_key TEXT NOT NULL UNIQUE,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed', 'cancelled')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMPTZ(0) DEFAULT NULL,
+    created_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ(0) NOT NULL DEFAULT now(),
+    CONSTRAINT check_notification_sent CHECK ((status = 'sent') = (sent_at IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_pending
+ON notification_outbox (next_attempt_at)
+WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_enrollment_id
+ON notification_outbox (enrollment_id);
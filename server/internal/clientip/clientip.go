@@ -0,0 +1,117 @@
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Prefixes is a list of networks parsed from a comma separated list of CIDRs
+// or single addresses.
+type Prefixes []netip.Prefix
+
+func ParsePrefixes(list string) (Prefixes, error) {
+	var prefixes Prefixes
+
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q: %w", item, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address %q: %w", item, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+func (p Prefixes) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolver finds the address of the client that made a request. Forwarding
+// headers are only believed when the connection comes from a trusted proxy,
+// otherwise any client could pick its own rate limit key.
+type Resolver struct {
+	trusted Prefixes
+}
+
+func NewResolver(trusted Prefixes) *Resolver {
+	return &Resolver{trusted: trusted}
+}
+
+func (res *Resolver) Resolve(r *http.Request) netip.Addr {
+	remote, ok := parseAddr(r.RemoteAddr)
+	if !ok || !res.trusted.Contains(remote) {
+		return remote
+	}
+
+	// X-Forwarded-For is appended to by each proxy, so walk it from the
+	// right and stop at the first hop that is not one of ours.
+	hops := forwardedFor(r)
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(hops[i])
+		if !ok {
+			break
+		}
+		if !res.trusted.Contains(addr) {
+			return addr
+		}
+		remote = addr
+	}
+
+	if len(hops) == 0 {
+		if addr, ok := parseAddr(r.Header.Get("X-Real-IP")); ok {
+			return addr
+		}
+	}
+
+	return remote
+}
+
+func forwardedFor(r *http.Request) []string {
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	return hops
+}
+
+func parseAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
@@ -0,0 +1,234 @@
+package clientip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	trusted, err := ParsePrefixes("10.0.0.0/8, 192.0.2.10, fd00::/8")
+	if err != nil {
+		t.Fatalf("ParsePrefixes: %v", err)
+	}
+	resolver := NewResolver(trusted)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		xRealIP    string
+		want       string
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "203.0.113.5:51000",
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "untrusted remote cannot pick its address",
+			remoteAddr: "203.0.113.5:51000",
+			xff:        []string{"198.51.100.1"},
+			xRealIP:    "198.51.100.2",
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.1:51000",
+			xff:        []string{"198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "walks right to left through trusted hops",
+			remoteAddr: "10.0.0.1:51000",
+			xff:        []string{"198.51.100.9, 198.51.100.1, 192.0.2.10, 10.0.0.2"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "hops spread over several headers",
+			remoteAddr: "10.0.0.1:51000",
+			xff:        []string{"198.51.100.9, 198.51.100.1", "10.0.0.2"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "malformed hop stops the walk at the last trusted proxy",
+			remoteAddr: "10.0.0.1:51000",
+			xff:        []string{"198.51.100.1, not-an-ip, 10.0.0.2"},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "only trusted hops",
+			remoteAddr: "10.0.0.1:51000",
+			xff:        []string{"10.0.0.3, 10.0.0.2"},
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "hop with a port",
+			remoteAddr: "10.0.0.1:51000",
+			xff:        []string{"198.51.100.1:4711"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "X-Real-IP when there is no X-Forwarded-For",
+			remoteAddr: "10.0.0.1:51000",
+			xRealIP:    "198.51.100.2",
+			want:       "198.51.100.2",
+		},
+		{
+			name:       "X-Forwarded-For wins over X-Real-IP",
+			remoteAddr: "10.0.0.1:51000",
+			xff:        []string{"198.51.100.1"},
+			xRealIP:    "198.51.100.2",
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "malformed X-Real-IP",
+			remoteAddr: "10.0.0.1:51000",
+			xRealIP:    "unknown",
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "IPv4-mapped remote is trusted",
+			remoteAddr: "[::ffff:10.0.0.1]:51000",
+			xff:        []string{"198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "IPv4-mapped hop is unmapped",
+			remoteAddr: "10.0.0.1:51000",
+			xff:        []string{"::ffff:198.51.100.1, ::ffff:10.0.0.2"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "IPv6 proxy",
+			remoteAddr: "[fd00::1]:51000",
+			xff:        []string{"2001:db8::1"},
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "unparseable remote address",
+			remoteAddr: "pipe",
+			xff:        []string{"198.51.100.1"},
+			want:       "invalid IP",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, xff := range tt.xff {
+				r.Header.Add("X-Forwarded-For", xff)
+			}
+			if tt.xRealIP != "" {
+				r.Header.Set("X-Real-IP", tt.xRealIP)
+			}
+
+			if got := resolver.Resolve(r).String(); got != tt.want {
+				t.Errorf("Resolve = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveWithoutTrustedProxies(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:51000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	r.Header.Set("X-Real-IP", "198.51.100.2")
+
+	if got := NewResolver(nil).Resolve(r).String(); got != "10.0.0.1" {
+		t.Errorf("Resolve = %s, want the remote address", got)
+	}
+}
+
+func TestParsePrefixes(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []string
+		wantErr bool
+	}{
+		{list: "", want: nil},
+		{list: " , ", want: nil},
+		{list: "192.0.2.1", want: []string{"192.0.2.1/32"}},
+		{list: "2001:db8::1", want: []string{"2001:db8::1/128"}},
+		{list: "::ffff:192.0.2.1", want: []string{"192.0.2.1/32"}},
+		{list: "10.1.2.3/8, 2001:db8::/32", want: []string{"10.0.0.0/8", "2001:db8::/32"}},
+		{list: "10.0.0.0/33", wantErr: true},
+		{list: "192.0.2.1, example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			prefixes, err := ParsePrefixes(tt.list)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParsePrefixes(%q) = %v, want an error", tt.list, prefixes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePrefixes(%q): %v", tt.list, err)
+			}
+
+			var got []string
+			for _, prefix := range prefixes {
+				got = append(got, prefix.String())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParsePrefixes(%q) = %v, want %v", tt.list, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ParsePrefixes(%q) = %v, want %v", tt.list, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestPrefixesContains(t *testing.T) {
+	// An allow list of office networks and a deny list of single addresses.
+	allow, err := ParsePrefixes("10.0.0.0/8, 2001:db8:1::/48")
+	if err != nil {
+		t.Fatalf("ParsePrefixes: %v", err)
+	}
+	deny, err := ParsePrefixes("203.0.113.7, 2001:db8:2::7")
+	if err != nil {
+		t.Fatalf("ParsePrefixes: %v", err)
+	}
+
+	tests := []struct {
+		addr            string
+		allowed, denied bool
+	}{
+		{"10.20.30.40", true, false},
+		{"::ffff:10.20.30.40", true, false},
+		{"11.0.0.1", false, false},
+		{"2001:db8:1::42", true, false},
+		{"2001:db8:2::42", false, false},
+		{"203.0.113.7", false, true},
+		{"::ffff:203.0.113.7", false, true},
+		{"203.0.113.8", false, false},
+		{"2001:db8:2::7", false, true},
+	}
+
+	for _, tt := range tests {
+		addr := netip.MustParseAddr(tt.addr)
+		if got := allow.Contains(addr); got != tt.allowed {
+			t.Errorf("allow list contains %s = %t, want %t", tt.addr, got, tt.allowed)
+		}
+		if got := deny.Contains(addr); got != tt.denied {
+			t.Errorf("deny list contains %s = %t, want %t", tt.addr, got, tt.denied)
+		}
+	}
+
+	if (Prefixes{}).Contains(netip.MustParseAddr("10.0.0.1")) {
+		t.Error("empty list contains an address")
+	}
+	if allow.Contains(netip.Addr{}) {
+		t.Error("allow list contains the zero address")
+	}
+}
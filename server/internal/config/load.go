@@ -0,0 +1,182 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the configuration for the given command line arguments
+// (without the program name) and validates it. The config file comes from
+// -config or CONFIG_FILE; a missing .env file is not an error.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return cfg, fmt.Errorf("loading .env: %w", err)
+	}
+
+	fset := flag.NewFlagSet("bookkeeping", flag.ContinueOnError)
+	configFile := fset.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+
+	var fields []field
+	collectFields(reflect.ValueOf(&cfg).Elem(), &fields)
+
+	for _, f := range fields {
+		if f.flag == "" {
+			continue
+		}
+		// Bools are registered as such so that a bare -flag means true.
+		if f.value.Kind() == reflect.Bool {
+			fset.Bool(f.flag, false, f.usage)
+			continue
+		}
+		fset.String(f.flag, "", f.usage)
+	}
+
+	if err := fset.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(*configFile, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if value, ok := os.LookupEnv(f.env); ok && value != "" {
+			if err := f.set(value); err != nil {
+				return cfg, fmt.Errorf("env %s: %w", f.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	fset.Visit(func(fl *flag.Flag) {
+		for _, f := range fields {
+			if f.flag == fl.Name {
+				if err := f.set(fl.Value.String()); err != nil {
+					flagErr = errors.Join(flagErr, fmt.Errorf("flag -%s: %w", f.flag, err))
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return cfg, flagErr
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// field is a settable leaf of Config together with its tags.
+type field struct {
+	value  reflect.Value
+	env    string
+	flag   string
+	usage  string
+	secret string
+	name   string
+}
+
+func collectFields(v reflect.Value, fields *[]field) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct {
+			collectFields(fv, fields)
+			continue
+		}
+
+		*fields = append(*fields, field{
+			value:  fv,
+			env:    sf.Tag.Get("env"),
+			flag:   sf.Tag.Get("flag"),
+			usage:  sf.Tag.Get("usage"),
+			secret: sf.Tag.Get("secret"),
+			name:   sf.Name,
+		})
+	}
+}
+
+// set parses s into the field. Lists are comma separated.
+func (f field) set(s string) error {
+	switch f.value.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(int64(d))
+	case string:
+		f.value.SetString(s)
+	case int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(int64(n))
+	case bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.value.SetBool(b)
+	case float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		f.value.SetFloat(n)
+	case []string:
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		f.value.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported config type %s", f.value.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/edzhabs/bookkeeping/internal/clientip"
+	"github.com/edzhabs/bookkeeping/internal/filestore"
+	"github.com/edzhabs/bookkeeping/internal/notify"
+	"github.com/edzhabs/bookkeeping/internal/ratelimiter"
+	"github.com/edzhabs/bookkeeping/internal/tracing"
+)
+
+const redacted = "[REDACTED]"
+
+// Validate reports every invalid setting at once so a misconfigured
+// deployment can be fixed in one go.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Addr != "", "addr is required")
+	check(oneOf(c.Env, "development", "staging", "production"), "env must be development, staging or production, got %q", c.Env)
+	check(len(c.CORS.AllowedOrigins) > 0, "cors.allowed_origins needs at least one origin")
+
+	if _, err := clientip.ParsePrefixes(strings.Join(c.TrustedProxies, ",")); err != nil {
+		errs = append(errs, fmt.Errorf("trusted_proxies: %w", err))
+	}
+
+	check(c.DB.Addr != "", "db.addr is required")
+	if u, err := url.Parse(c.DB.Addr); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+		errs = append(errs, errors.New("db.addr must be a postgres:// URL"))
+	}
+	check(c.DB.MaxOpenConns > 0, "db.max_open_conns must be positive")
+	check(c.DB.MaxIdleConns >= 0 && c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "db.max_idle_conns must be between 0 and db.max_open_conns")
+	check(c.DB.MaxIdleTime > 0, "db.max_idle_time must be positive")
+
+	check(c.Auth.BasicUser != "", "auth.basic_user is required")
+	check(c.Env != "production" || c.Auth.BasicPass != "", "auth.basic_pass is required in production")
+
+	rl := c.RateLimiter
+	check(oneOf(rl.Algorithm, ratelimiter.FixedWindow, ratelimiter.SlidingWindow, ratelimiter.TokenBucket),
+		"rate_limiter.algorithm must be %s, %s or %s, got %q", ratelimiter.FixedWindow, ratelimiter.SlidingWindow, ratelimiter.TokenBucket, rl.Algorithm)
+	check(oneOf(rl.Backend, ratelimiter.BackendMemory, ratelimiter.BackendRedis),
+		"rate_limiter.backend must be %s or %s, got %q", ratelimiter.BackendMemory, ratelimiter.BackendRedis, rl.Backend)
+	check(rl.RequestCount > 0, "rate_limiter.request_count must be positive")
+	check(rl.TimeFrame > 0, "rate_limiter.time_frame must be positive")
+	check(rl.AuthRequestCount > 0, "rate_limiter.auth_request_count must be positive")
+	check(rl.AuthTimeFrame > 0, "rate_limiter.auth_time_frame must be positive")
+	check(rl.Backend != ratelimiter.BackendRedis || rl.Redis.Addr != "", "rate_limiter.redis.addr is required for the redis backend")
+	check(rl.Redis.DB >= 0, "rate_limiter.redis.db must not be negative")
+
+	if _, err := clientip.ParsePrefixes(strings.Join(rl.AllowList, ",")); err != nil {
+		errs = append(errs, fmt.Errorf("rate_limiter.allow_list: %w", err))
+	}
+	if _, err := clientip.ParsePrefixes(strings.Join(rl.DenyList, ",")); err != nil {
+		errs = append(errs, fmt.Errorf("rate_limiter.deny_list: %w", err))
+	}
+
+	check(c.Idempotency.TTL > 0, "idempotency.ttl must be positive")
+
+	check(oneOf(c.Tracing.Exporter, tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP),
+		"tracing.exporter must be %s, %s or %s, got %q", tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP, c.Tracing.Exporter)
+	check(c.Tracing.Exporter != tracing.ExporterOTLP || c.Tracing.Endpoint != "", "tracing.endpoint is required for the otlp exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+
+	at := c.Attachments
+	check(oneOf(at.Backend, filestore.BackendLocal, filestore.BackendS3),
+		"attachments.backend must be %s or %s, got %q", filestore.BackendLocal, filestore.BackendS3, at.Backend)
+	check(at.MaxSize > 0, "attachments.max_size must be positive")
+	check(at.Backend != filestore.BackendLocal || at.Dir != "", "attachments.dir is required for the local backend")
+	if at.Backend == filestore.BackendS3 {
+		if u, err := url.Parse(at.S3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, errors.New("attachments.s3.endpoint must be an http:// or https:// URL"))
+		}
+		check(at.S3.Region != "", "attachments.s3.region is required for the s3 backend")
+		check(at.S3.Bucket != "", "attachments.s3.bucket is required for the s3 backend")
+		check(at.S3.AccessKey != "" && at.S3.SecretKey != "", "attachments.s3.access_key and secret_key are required for the s3 backend")
+	}
+
+	n := c.Notifications
+	check(oneOf(n.Sender, notify.SenderLog, notify.SenderSMTP),
+		"notifications.sender must be %s or %s, got %q", notify.SenderLog, notify.SenderSMTP, n.Sender)
+	if _, err := mail.ParseAddress(n.From); err != nil {
+		errs = append(errs, fmt.Errorf("notifications.from: %w", err))
+	}
+	check(n.DueDay >= 1 && n.DueDay <= 28, "notifications.due_day must be between 1 and 28")
+	check(n.ReminderDays >= 0, "notifications.reminder_days must not be negative")
+	check(n.PollInterval > 0, "notifications.poll_interval must be positive")
+	check(n.MaxAttempts > 0, "notifications.max_attempts must be positive")
+	if n.Sender == notify.SenderSMTP {
+		check(n.SMTP.Host != "", "notifications.smtp.host is required for the smtp sender")
+		check(n.SMTP.Port > 0 && n.SMTP.Port <= 65535, "notifications.smtp.port must be between 1 and 65535")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted returns the effective configuration keyed by the file names of
+// its settings, with secrets masked, for logging at startup.
+func (c Config) Redacted() map[string]any {
+	return redactStruct(reflect.ValueOf(c))
+}
+
+func redactStruct(v reflect.Value) map[string]any {
+	out := make(map[string]any)
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+
+		switch {
+		case fv.Kind() == reflect.Struct:
+			out[name] = redactStruct(fv)
+		case sf.Tag.Get("secret") == "url":
+			out[name] = redactURL(fv.String())
+		case sf.Tag.Get("secret") != "":
+			if fv.String() != "" {
+				out[name] = redacted
+			} else {
+				out[name] = ""
+			}
+		case fv.Type().String() == "time.Duration":
+			out[name] = fmt.Sprint(fv.Interface())
+		default:
+			out[name] = fv.Interface()
+		}
+	}
+
+	return out
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+	return u.Redacted()
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
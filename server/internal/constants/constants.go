@@ -1,5 +1,7 @@
 package constants
 
+import "time"
+
 const (
 	// Enrollment and Discount
 	Rank_1   = "rank_1"
@@ -9,4 +11,80 @@ const (
 	Carpool  = "carpool"
 	LmsBooks = "lms_books"
 	Tuition  = "tuition"
+
+	// Enrollment status
+	Enrolled   = "enrolled"
+	Withdrawn  = "withdrawn"
+	Waitlisted = "waitlisted"
+
+	// Reservation status
+	Reserved  = "reserved"
+	Converted = "converted"
+	Expired   = "expired"
+	Forfeited = "forfeited"
+
+	// Applicant status
+	Inquiry  = "inquiry"
+	Applied  = "applied"
+	Assessed = "assessed"
+	Accepted = "accepted"
+	Rejected = "rejected"
+
+	// Applicant documents
+	BirthCertificate = "birth_certificate"
+	ReportCard       = "report_card"
+
+	// Notification kinds
+	Receipt     = "receipt"
+	UpcomingDue = "upcoming_due"
+	Overdue     = "overdue"
+
+	// Notification status
+	Pending   = "pending"
+	Sent      = "sent"
+	Failed    = "failed"
+	Cancelled = "cancelled"
+
+	// School year
+	SchoolYearStartMonth = time.June
+
+	// Payment methods
+	Cash  = "cash"
+	GCash = "gcash"
+	Bank  = "bank"
+
+	// Chart of accounts
+	AccountCash              = "1000"
+	AccountGCash             = "1010"
+	AccountBank              = "1020"
+	AccountTuitionReceivable = "1100"
+	AccountRefundsPayable    = "2000"
+	AccountReservations      = "2010"
+	AccountTuitionRevenue    = "4000"
+	AccountEnrollmentRevenue = "4010"
+	AccountMiscRevenue       = "4020"
+	AccountPtaRevenue        = "4030"
+	AccountLmsBooksRevenue   = "4040"
+	AccountForfeitedRevenue  = "4050"
+	AccountTuitionDiscounts  = "4900"
+	AccountLmsBooksDiscounts = "4910"
+	AccountCarpoolDiscounts  = "4920"
+	AccountOperatingExpenses = "5000"
+
+	// Ledger sources
+	SourceEnrollment  = "enrollment"
+	SourceDiscount    = "discount"
+	SourcePayment     = "payment"
+	SourceRefund      = "refund"
+	SourceReservation = "reservation"
+	SourceExpense     = "expense"
+	SourceAdjustment  = "adjustment"
+
+	// Closed periods
+	PeriodMonth      = "month"
+	PeriodSchoolYear = "school_year"
+
+	// Idempotency keys
+	IdempotencyInProgress = "in_progress"
+	IdempotencyCompleted  = "completed"
 )
@@ -4,12 +4,23 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/XSAM/otelsql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 var dbDriver = "postgres"
 
 func New(addr string, maxOpenConns, maxIdleConns int, maxIdleTime string) (*sql.DB, error) {
-	db, err := sql.Open(dbDriver, addr)
+	// Every query and transaction made through the pool gets a span, child
+	// of whatever span is in the context passed to it.
+	db, err := otelsql.Open(dbDriver, addr,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+		}),
+	)
 	if err != nil {
 		return nil, err
 	}
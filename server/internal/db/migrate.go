@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+type MigrationStatus struct {
+	Version uint `json:"version"`
+	Latest  uint `json:"latest"`
+	Dirty   bool `json:"dirty"`
+}
+
+func (s MigrationStatus) Pending() bool {
+	return s.Version < s.Latest
+}
+
+// Migrator applies the migrations in fsys over a dedicated connection, so
+// closing it leaves the rest of the pool open.
+type Migrator struct {
+	migrate *migrate.Migrate
+	source  source.Driver
+}
+
+func NewMigrator(ctx context.Context, db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	src, err := iofs.New(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "postgres", driver)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Migrator{migrate: m, source: src}, nil
+}
+
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.migrate.Up())
+}
+
+// Down rolls back the given number of migrations, or all of them when steps
+// is not positive.
+func (m *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return ignoreNoChange(m.migrate.Down())
+	}
+	return ignoreNoChange(m.migrate.Steps(-steps))
+}
+
+func (m *Migrator) Force(version int) error {
+	return m.migrate.Force(version)
+}
+
+func (m *Migrator) Status() (MigrationStatus, error) {
+	var status MigrationStatus
+
+	latest, err := m.latest()
+	if err != nil {
+		return status, err
+	}
+	status.Latest = latest
+
+	version, dirty, err := m.migrate.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return status, err
+	}
+	status.Version = version
+	status.Dirty = dirty
+
+	return status, nil
+}
+
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.migrate.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+func (m *Migrator) latest() (uint, error) {
+	version, err := m.source.First()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	for {
+		next, err := m.source.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
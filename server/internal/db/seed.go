@@ -69,7 +69,7 @@ func randomPhoneNumber() string {
 func randomDigits(n int) string {
 	digits := ""
 	for i := 0; i < n; i++ {
-		digits += string('0' + rand.Intn(10))
+		digits += string(rune('0' + rand.Intn(10)))
 	}
 	return digits
 }
@@ -0,0 +1,55 @@
+// Package filestore keeps uploaded files outside the database, on local disk
+// or in an S3-compatible bucket. Callers choose the keys; the store only
+// saves, returns and removes the bytes.
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// ErrNotExist is returned by Get when no file is stored under the key.
+var ErrNotExist = errors.New("file does not exist")
+
+type Store interface {
+	// Put stores size bytes read from body under key, replacing any file
+	// already there.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the file under key. Deleting a missing file is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+}
+
+type Config struct {
+	Backend string
+	Dir     string
+	S3      S3Config
+}
+
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// New builds the store for the configured backend.
+func New(cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case BackendLocal:
+		return NewLocal(cfg.Dir)
+	case BackendS3:
+		return NewS3(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown file store backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,92 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+)
+
+// TestLocal runs the store contract against a temporary directory.
+func TestLocal(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+
+	testStore(t, store)
+
+	if err := store.Put(context.Background(), "../escape", bytes.NewReader([]byte("x")), 1, "text/plain"); err == nil {
+		t.Error("Put outside the root succeeded, want an error")
+	}
+}
+
+// TestS3 runs the store contract against an S3-compatible service, such as a
+// local MinIO:
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	S3_ENDPOINT=http://localhost:9000 S3_BUCKET=test S3_ACCESS_KEY=minioadmin \
+//		S3_SECRET_KEY=minioadmin go test ./internal/filestore
+//
+// The bucket must already exist.
+func TestS3(t *testing.T) {
+	endpoint := os.Getenv("S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_ENDPOINT is not set")
+	}
+
+	region := os.Getenv("S3_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	store, err := NewS3(S3Config{
+		Endpoint:  endpoint,
+		Region:    region,
+		Bucket:    os.Getenv("S3_BUCKET"),
+		AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_SECRET_KEY"),
+	})
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+
+	testStore(t, store)
+}
+
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+
+	ctx := context.Background()
+	key := "attachments/ab/report card.pdf"
+	content := []byte("%PDF-1.4 report card")
+
+	if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	body, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		t.Fatalf("reading file: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("file = %q, want %q", got, content)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete missing file: %v", err)
+	}
+
+	if _, err := store.Get(ctx, key); err != ErrNotExist {
+		t.Errorf("Get after Delete error = %v, want ErrNotExist", err)
+	}
+}
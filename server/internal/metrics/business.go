@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const businessScrapeTimeout = 5 * time.Second
+
+type businessCollector struct {
+	source                 BusinessSource
+	enrollments            *prometheus.Desc
+	outstandingReceivables *prometheus.Desc
+}
+
+func newBusinessCollector(source BusinessSource) *businessCollector {
+	return &businessCollector{
+		source: source,
+		enrollments: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "enrollments"),
+			"Enrollments by school year and status.",
+			[]string{"school_year", "status"}, nil,
+		),
+		outstandingReceivables: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "outstanding_receivables"),
+			"Balance of the tuition receivable account.",
+			nil, nil,
+		),
+	}
+}
+
+func (c *businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.enrollments
+	ch <- c.outstandingReceivables
+}
+
+func (c *businessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), businessScrapeTimeout)
+	defer cancel()
+
+	business, err := c.source.GetBusinessMetrics(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.enrollments, err)
+		ch <- prometheus.NewInvalidMetric(c.outstandingReceivables, err)
+		return
+	}
+
+	for _, count := range business.Enrollments {
+		ch <- prometheus.MustNewConstMetric(c.enrollments, prometheus.GaugeValue,
+			float64(count.Count), count.SchoolYear, count.Status)
+	}
+
+	receivables, _ := business.OutstandingReceivables.Float64()
+	ch <- prometheus.MustNewConstMetric(c.outstandingReceivables, prometheus.GaugeValue, receivables)
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "bookkeeping"
+
+// BusinessSource supplies the figures behind the business gauges. It is
+// queried on every scrape.
+type BusinessSource interface {
+	GetBusinessMetrics(ctx context.Context) (models.BusinessMetrics, error)
+}
+
+type Metrics struct {
+	registry            *prometheus.Registry
+	requests            *prometheus.CounterVec
+	requestDuration     *prometheus.HistogramVec
+	rateLimitRejections *prometheus.CounterVec
+}
+
+func New(db *sql.DB, business BusinessSource) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		rateLimitRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_rejections_total",
+			Help:      "Requests rejected by the rate limiter by policy and reason.",
+		}, []string{"policy", "reason"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, namespace),
+		newBusinessCollector(business),
+		m.requests,
+		m.requestDuration,
+		m.rateLimitRejections,
+	)
+
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a finished request. Route is the chi route pattern
+// rather than the raw path so IDs do not explode the label cardinality.
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.requestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func (m *Metrics) RateLimitRejected(policy, reason string) {
+	m.rateLimitRejections.WithLabelValues(policy, reason).Inc()
+}
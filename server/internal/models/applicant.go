@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Applicant is a prospective new student moving through admissions. Their
+// details are kept in Student until they are enrolled, when a student record
+// is created from them.
+type Applicant struct {
+	ID               uuid.UUID           `json:"id"`
+	SchoolYear       string              `json:"school_year"`
+	GradeLevel       string              `json:"grade_level"`
+	Status           string              `json:"status"`
+	Student          *Student            `json:"student"`
+	Notes            string              `json:"notes"`
+	EnrollmentID     *uuid.UUID          `json:"enrollment_id"`
+	Documents        []ApplicantDocument `json:"documents,omitempty"`
+	MissingDocuments []string            `json:"missing_documents"`
+	StatusChangedAt  time.Time           `json:"status_changed_at"`
+	CreatedAt        time.Time           `json:"created_at"`
+	UpdatedAt        time.Time           `json:"updated_at"`
+}
+
+// ApplicantDocument is an item of the admissions checklist. ReceivedOn is nil
+// until the document has been handed in.
+type ApplicantDocument struct {
+	ApplicantID uuid.UUID  `json:"applicant_id"`
+	Type        string     `json:"type"`
+	ReceivedOn  *time.Time `json:"received_on"`
+	Notes       string     `json:"notes"`
+}
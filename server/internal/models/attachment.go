@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attachment is an uploaded file, such as a scanned birth certificate or a
+// GCash screenshot, belonging to exactly one student, enrollment or payment.
+// The bytes live in the file store under StorageKey.
+type Attachment struct {
+	ID           uuid.UUID  `json:"id"`
+	StudentID    *uuid.UUID `json:"student_id"`
+	EnrollmentID *uuid.UUID `json:"enrollment_id"`
+	PaymentID    *uuid.UUID `json:"payment_id"`
+	StorageKey   string     `json:"-"`
+	FileName     string     `json:"file_name"`
+	ContentType  string     `json:"content_type"`
+	Size         int64      `json:"size"`
+	Checksum     string     `json:"checksum_sha256"`
+	Description  string     `json:"description"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// AttachmentOwner selects the attachments of one student, enrollment or
+// payment. Exactly one of its IDs is set.
+type AttachmentOwner struct {
+	StudentID    *uuid.UUID
+	EnrollmentID *uuid.UUID
+	PaymentID    *uuid.UUID
+}
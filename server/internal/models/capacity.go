@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type GradeCapacity struct {
+	SchoolYear string    `json:"school_year"`
+	GradeLevel string    `json:"grade_level"`
+	Capacity   int       `json:"capacity"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// GradeUtilization reports the seats taken in a grade level. Capacity,
+// Available and Utilization are nil when the grade is not capped.
+type GradeUtilization struct {
+	GradeLevel  string           `json:"grade_level"`
+	Capacity    *int             `json:"capacity"`
+	Enrolled    int              `json:"enrolled"`
+	Waitlisted  int              `json:"waitlisted"`
+	Reserved    int              `json:"reserved"`
+	Available   *int             `json:"available"`
+	Utilization *decimal.Decimal `json:"utilization"`
+}
+
+type WaitlistEntry struct {
+	EnrollmentID uuid.UUID `json:"enrollment_id"`
+	StudentID    uuid.UUID `json:"student_id"`
+	FullName     string    `json:"full_name"`
+	SchoolYear   string    `json:"school_year"`
+	GradeLevel   string    `json:"grade_level"`
+	Position     int       `json:"position"`
+	WaitlistedAt time.Time `json:"waitlisted_at"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type Dashboard struct {
+	SchoolYear  string               `json:"school_year"`
+	Enrollments DashboardEnrollments `json:"enrollments"`
+	Collections DashboardCollections `json:"collections"`
+	TopDebtors  []Debtor             `json:"top_debtors"`
+	Discounts   []DiscountTotal      `json:"discounts"`
+}
+
+// DashboardEnrollments counts the students currently enrolled; withdrawn and
+// waitlisted enrollments are only counted in Withdrawn and Waitlisted.
+type DashboardEnrollments struct {
+	Total        int          `json:"total"`
+	Withdrawn    int          `json:"withdrawn"`
+	Waitlisted   int          `json:"waitlisted"`
+	ByGradeLevel []GroupCount `json:"by_grade_level"`
+	ByType       []GroupCount `json:"by_type"`
+	ByGender     []GroupCount `json:"by_gender"`
+}
+
+type GroupCount struct {
+	Group string `json:"group"`
+	Count int    `json:"count"`
+}
+
+type DashboardCollections struct {
+	TotalBilled      decimal.Decimal `json:"total_billed"`
+	TotalCollected   decimal.Decimal `json:"total_collected"`
+	TotalOutstanding decimal.Decimal `json:"total_outstanding"`
+	// CollectionRate is the percentage of the amount billed that has been
+	// collected.
+	CollectionRate decimal.Decimal `json:"collection_rate"`
+	Today          []MethodTotal   `json:"today"`
+	ThisMonth      []MethodTotal   `json:"this_month"`
+}
+
+type MethodTotal struct {
+	PaymentMethod string          `json:"payment_method"`
+	Amount        decimal.Decimal `json:"amount"`
+}
+
+type Debtor struct {
+	EnrollmentID    uuid.UUID       `json:"enrollment_id"`
+	FullName        string          `json:"full_name"`
+	GradeLevel      string          `json:"grade_level"`
+	RemainingAmount decimal.Decimal `json:"remaining_amount"`
+}
+
+type DiscountTotal struct {
+	Type   string          `json:"type"`
+	Count  int             `json:"count"`
+	Amount decimal.Decimal `json:"amount"`
+}
@@ -13,15 +13,21 @@ type Enrollment struct {
 	SchoolYear     string          `json:"school_year"`
 	GradeLevel     string          `json:"grade_level"`
 	Type           string          `json:"type"`
+	Status         string          `json:"status"`
 	MonthlyTuition decimal.Decimal `json:"monthly_tuition"`
 	EnrollmentFee  decimal.Decimal `json:"enrollment_fee"`
 	MiscFee        decimal.Decimal `json:"misc_fee"`
 	PtaFee         decimal.Decimal `json:"pta_fee"`
 	LmsFee         decimal.Decimal `json:"lms_books_fee"`
 	Discounts      []*Discount     `json:"discounts"`
+	Version        int             `json:"version"`
 	CreatedAt      time.Time       `json:"created_at"`
 	UpdatedAt      time.Time       `json:"updated_at"`
 	DeletedAt      time.Time       `json:"deleted_at"`
+
+	// AllowWaitlist puts the enrollment on the waitlist instead of rejecting
+	// it when its grade level is full.
+	AllowWaitlist bool `json:"-"`
 }
 
 type Discount struct {
@@ -42,6 +48,7 @@ type EnrollmentsTableData struct {
 	GradeLevel      string          `json:"grade_level"`
 	Gender          string          `json:"gender"`
 	SchoolYear      string          `json:"school_year"`
+	Status          string          `json:"status"`
 	DiscountTypes   []string        `json:"discount_types"`
 	TotalAmount     decimal.Decimal `json:"total_amount"`
 	TotalPaid       decimal.Decimal `json:"total_paid"`
@@ -54,6 +61,11 @@ type EnrollmentStudentDetails struct {
 	Type            string          `json:"type"`
 	GradeLevel      string          `json:"grade_level"`
 	SchoolYear      string          `json:"school_year"`
+	Status          string          `json:"status"`
+	WithdrawalDate  *time.Time      `json:"withdrawal_date"`
+	SectionID       *uuid.UUID      `json:"section_id"`
+	SectionName     *string         `json:"section_name"`
+	Version         int             `json:"version"`
 	DiscountTypes   []string        `json:"discount_types"`
 	TotalAmount     decimal.Decimal `json:"total_amount"`
 	TotalPaid       decimal.Decimal `json:"total_paid"`
@@ -73,8 +85,41 @@ type EditEnrollmentDetails struct {
 	MiscFee              decimal.Decimal `json:"misc_fee"`
 	PtaFee               decimal.Decimal `json:"pta_fee"`
 	LmsFee               decimal.Decimal `json:"lms_books_fee"`
+	Version              int             `json:"version"`
 	IsRankOne            bool            `json:"isRankOne"`
 	HasSiblingDiscount   bool            `json:"hasSiblingDiscount"`
 	HasWholeYearDiscount bool            `json:"hasWholeYearDiscount"`
 	HasScholarDiscount   bool            `json:"hasScholarDiscount"`
 }
+
+type Withdrawal struct {
+	EnrollmentID    uuid.UUID       `json:"enrollment_id"`
+	SchoolYear      string          `json:"school_year"`
+	WithdrawalDate  time.Time       `json:"withdrawal_date"`
+	Reason          string          `json:"reason"`
+	Months          int             `json:"months"`
+	MonthsAttended  int             `json:"months_attended"`
+	ProratedTuition decimal.Decimal `json:"prorated_tuition"`
+	TotalAmount     decimal.Decimal `json:"total_amount"`
+	TotalPaid       decimal.Decimal `json:"total_paid"`
+	RefundAmount    decimal.Decimal `json:"refund_amount"`
+	BalanceDue      decimal.Decimal `json:"balance_due"`
+}
+
+type EnrollmentBalance struct {
+	EnrollmentID    uuid.UUID       `json:"enrollment_id"`
+	DiscountTypes   []string        `json:"discount_types"`
+	TotalAmount     decimal.Decimal `json:"total_amount"`
+	TotalPaid       decimal.Decimal `json:"total_paid"`
+	RemainingAmount decimal.Decimal `json:"remaining_amount"`
+	PaymentStatus   string          `json:"payment_status"`
+}
+
+// BalanceDrift is an enrollment whose stored balance no longer matches the
+// one computed from its fees, discounts and payments. Stored is nil when the
+// balance row is missing and Expected is nil when the enrollment is gone.
+type BalanceDrift struct {
+	EnrollmentID uuid.UUID          `json:"enrollment_id"`
+	Stored       *EnrollmentBalance `json:"stored"`
+	Expected     *EnrollmentBalance `json:"expected"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type IdempotencyRecord struct {
+	Key             string            `json:"key"`
+	Method          string            `json:"method"`
+	Path            string            `json:"path"`
+	RequestHash     string            `json:"request_hash"`
+	Status          string            `json:"status"`
+	ResponseStatus  int               `json:"response_status"`
+	ResponseHeaders map[string]string `json:"response_headers"`
+	ResponseBody    []byte            `json:"response_body"`
+	CreatedAt       time.Time         `json:"created_at"`
+	LockedUntil     time.Time         `json:"locked_until"`
+	ExpiresAt       time.Time         `json:"expires_at"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type Account struct {
+	ID        uuid.UUID `json:"id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TrialBalanceRow struct {
+	Code   string          `json:"code"`
+	Name   string          `json:"name"`
+	Type   string          `json:"type"`
+	Debit  decimal.Decimal `json:"debit"`
+	Credit decimal.Decimal `json:"credit"`
+}
+
+type TrialBalance struct {
+	AsOf        time.Time         `json:"as_of"`
+	Accounts    []TrialBalanceRow `json:"accounts"`
+	TotalDebit  decimal.Decimal   `json:"total_debit"`
+	TotalCredit decimal.Decimal   `json:"total_credit"`
+	Balanced    bool              `json:"balanced"`
+}
+
+type JournalEntry struct {
+	ID           uuid.UUID     `json:"id"`
+	EntryDate    time.Time     `json:"entry_date"`
+	Description  string        `json:"description"`
+	SourceType   string        `json:"source_type"`
+	SourceID     uuid.UUID     `json:"source_id"`
+	IsAdjustment bool          `json:"is_adjustment"`
+	CreatedBy    string        `json:"created_by"`
+	Lines        []JournalLine `json:"lines"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+type JournalLine struct {
+	AccountCode string          `json:"account_code"`
+	Debit       decimal.Decimal `json:"debit"`
+	Credit      decimal.Decimal `json:"credit"`
+}
+
+type ClosedPeriod struct {
+	ID          uuid.UUID  `json:"id"`
+	PeriodType  string     `json:"period_type"`
+	SchoolYear  string     `json:"school_year,omitempty"`
+	PeriodMonth *time.Time `json:"period_month,omitempty"`
+	ClosedBy    string     `json:"closed_by"`
+	ClosedAt    time.Time  `json:"closed_at"`
+}
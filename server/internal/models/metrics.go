@@ -0,0 +1,14 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+type BusinessMetrics struct {
+	Enrollments            []EnrollmentCount
+	OutstandingReceivables decimal.Decimal
+}
+
+type EnrollmentCount struct {
+	SchoolYear string
+	Status     string
+	Count      int
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Guardian is a parent or guardian of a student who can be emailed about
+// their payments. EmailOptOut stops every email to them.
+type Guardian struct {
+	ID           uuid.UUID `json:"id"`
+	StudentID    uuid.UUID `json:"student_id"`
+	Name         string    `json:"name"`
+	Relationship string    `json:"relationship"`
+	Email        *string   `json:"email"`
+	Phone        *string   `json:"phone"`
+	EmailOptOut  bool      `json:"email_opt_out"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Notification is an email in the outbox. Its content is captured in Data
+// when it is queued and rendered when it is sent.
+type Notification struct {
+	ID            uuid.UUID        `json:"id"`
+	Kind          string           `json:"kind"`
+	GuardianID    *uuid.UUID       `json:"guardian_id"`
+	EnrollmentID  uuid.UUID        `json:"enrollment_id"`
+	PaymentID     *uuid.UUID       `json:"payment_id"`
+	Recipient     string           `json:"recipient"`
+	Data          NotificationData `json:"data"`
+	Status        string           `json:"status"`
+	Attempts      int              `json:"attempts"`
+	NextAttemptAt time.Time        `json:"next_attempt_at"`
+	LastError     string           `json:"last_error"`
+	SentAt        *time.Time       `json:"sent_at"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+// NotificationData is what the email templates are rendered from. Receipts
+// fill in the payment fields and reminders the installment fields.
+type NotificationData struct {
+	GuardianName    string          `json:"guardian_name"`
+	StudentName     string          `json:"student_name"`
+	SchoolYear      string          `json:"school_year"`
+	GradeLevel      string          `json:"grade_level"`
+	TotalPaid       decimal.Decimal `json:"total_paid"`
+	RemainingAmount decimal.Decimal `json:"remaining_amount"`
+
+	InvoiceNumber string          `json:"invoice_number,omitempty"`
+	PaymentDate   string          `json:"payment_date,omitempty"`
+	PaymentMethod string          `json:"payment_method,omitempty"`
+	Amount        decimal.Decimal `json:"amount,omitzero"`
+
+	Installment int             `json:"installment,omitempty"`
+	DueDate     string          `json:"due_date,omitempty"`
+	AmountDue   decimal.Decimal `json:"amount_due,omitzero"`
+}
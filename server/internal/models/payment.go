@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type Payment struct {
+	ID             uuid.UUID       `json:"id"`
+	EnrollmentID   uuid.UUID       `json:"enrollment_id"`
+	InvoiceNumber  string          `json:"invoice_number"`
+	PaymentDate    time.Time       `json:"payment_date"`
+	PaymentMethod  string          `json:"payment_method"`
+	ReservationFee decimal.Decimal `json:"reservation_fee"`
+	TuitionFee     decimal.Decimal `json:"tuition_fee"`
+	AdvancePayment decimal.Decimal `json:"advance_payment"`
+	Notes          string          `json:"notes"`
+	// ReservationID is set on the payment that credits a converted
+	// reservation's fee.
+	ReservationID *uuid.UUID `json:"reservation_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (p *Payment) Amount() decimal.Decimal {
+	return p.ReservationFee.Add(p.TuitionFee).Add(p.AdvancePayment)
+}
+
+type Expense struct {
+	ID            uuid.UUID       `json:"id"`
+	ExpenseDate   time.Time       `json:"expense_date"`
+	Category      string          `json:"category"`
+	Description   string          `json:"description"`
+	Amount        decimal.Decimal `json:"amount"`
+	PaymentMethod string          `json:"payment_method"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
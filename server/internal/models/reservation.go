@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Reservation holds a seat for a student in a school year against a fee paid
+// up front. The fee is credited to the enrollment the reservation is
+// converted into, or kept by the school if it expires or is forfeited.
+type Reservation struct {
+	ID            uuid.UUID       `json:"id"`
+	StudentID     uuid.UUID       `json:"student_id"`
+	FullName      string          `json:"full_name"`
+	SchoolYear    string          `json:"school_year"`
+	GradeLevel    string          `json:"grade_level"`
+	InvoiceNumber string          `json:"invoice_number"`
+	Fee           decimal.Decimal `json:"fee"`
+	PaymentMethod string          `json:"payment_method"`
+	ReservedOn    time.Time       `json:"reserved_on"`
+	ExpiresOn     time.Time       `json:"expires_on"`
+	Status        string          `json:"status"`
+	EnrollmentID  *uuid.UUID      `json:"enrollment_id"`
+	Notes         string          `json:"notes"`
+	ClosedAt      *time.Time      `json:"closed_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// ReservationReport summarizes the open reservations of a school year.
+// Overdue counts reservations past their expiry that have not been swept yet.
+type ReservationReport struct {
+	SchoolYear   string          `json:"school_year"`
+	Count        int             `json:"count"`
+	TotalFees    decimal.Decimal `json:"total_fees"`
+	ExpiringSoon int             `json:"expiring_soon"`
+	Overdue      int             `json:"overdue"`
+	ByGradeLevel []GroupCount    `json:"by_grade_level"`
+	Reservations []Reservation   `json:"reservations"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Section is a class within a grade level. The counts cover the enrolled
+// students assigned to it and are filled in when a section is read.
+type Section struct {
+	ID         uuid.UUID `json:"id"`
+	SchoolYear string    `json:"school_year"`
+	GradeLevel string    `json:"grade_level"`
+	Name       string    `json:"name"`
+	Adviser    string    `json:"adviser"`
+	Capacity   int       `json:"capacity"`
+	Enrolled   int       `json:"enrolled"`
+	Male       int       `json:"male"`
+	Female     int       `json:"female"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type ClassList struct {
+	Section  Section            `json:"section"`
+	Students []ClassListStudent `json:"students"`
+}
+
+type ClassListStudent struct {
+	EnrollmentID   uuid.UUID `json:"enrollment_id"`
+	StudentID      uuid.UUID `json:"student_id"`
+	LastName       string    `json:"last_name"`
+	FirstName      string    `json:"first_name"`
+	MiddleName     string    `json:"middle_name"`
+	Suffix         string    `json:"suffix"`
+	Gender         string    `json:"gender"`
+	Birthdate      time.Time `json:"birthdate"`
+	ContactNumbers []string  `json:"contact_numbers"`
+}
+
+// SectionBalance is the outcome of spreading the unassigned students of a
+// grade level across its sections.
+type SectionBalance struct {
+	Assigned   int       `json:"assigned"`
+	Unassigned int       `json:"unassigned"`
+	Sections   []Section `json:"sections"`
+}
@@ -0,0 +1,60 @@
+// Package notify renders notification emails and delivers them, over SMTP
+// or, in development, to the log.
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+const (
+	SenderLog  = "log"
+	SenderSMTP = "smtp"
+)
+
+// Message is a plain text email to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	Sender string
+	From   string
+	SMTP   SMTPConfig
+}
+
+// New builds the sender for the configured backend. The logger is only used
+// by the log sender.
+func New(cfg Config, logger *zap.SugaredLogger) (Sender, error) {
+	switch cfg.Sender {
+	case SenderLog:
+		return NewLogSender(logger), nil
+	case SenderSMTP:
+		return NewSMTPSender(cfg.SMTP, cfg.From)
+	default:
+		return nil, fmt.Errorf("unknown notification sender %q", cfg.Sender)
+	}
+}
+
+// LogSender writes emails to the log instead of sending them, so the outbox
+// drains in development without a mail server.
+type LogSender struct {
+	logger *zap.SugaredLogger
+}
+
+func NewLogSender(logger *zap.SugaredLogger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	s.logger.Infow("email", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
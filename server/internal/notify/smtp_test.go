@@ -0,0 +1,174 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// fakeSMTP is a minimal SMTP server that accepts every message and records
+// the envelope and data of the last one.
+type fakeSMTP struct {
+	listener net.Listener
+	done     chan struct{}
+
+	from string
+	rcpt []string
+	data string
+}
+
+func startFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+
+	server := &fakeSMTP{listener: listener, done: make(chan struct{})}
+	t.Cleanup(func() { listener.Close() })
+
+	go server.serve()
+
+	return server
+}
+
+func (s *fakeSMTP) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost fake SMTP")
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			text.PrintfLine("250 localhost")
+		case "MAIL":
+			s.from = arg
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			s.rcpt = append(s.rcpt, arg)
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			s.data = string(data)
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 Bye")
+			return
+		default:
+			text.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTPSender(t *testing.T) {
+	server := startFakeSMTP(t)
+
+	sender, err := NewSMTPSender(SMTPConfig{Host: "127.0.0.1", Port: server.port()}, "Bookkeeping <billing@school.test>")
+	if err != nil {
+		t.Fatalf("NewSMTPSender: %v", err)
+	}
+
+	subject, body, err := Render(constants.Receipt, models.NotificationData{
+		GuardianName:    "Ana Santos",
+		StudentName:     "María Santos",
+		SchoolYear:      "2025-2026",
+		GradeLevel:      "grade-1",
+		TotalPaid:       decimal.NewFromInt(1000),
+		RemainingAmount: decimal.NewFromInt(11500),
+		InvoiceNumber:   "INV-001",
+		PaymentDate:     "2025-06-16",
+		PaymentMethod:   "cash",
+		Amount:          decimal.NewFromInt(1000),
+	}, "St. Jude School")
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	err = sender.Send(context.Background(), Message{To: "Ana Santos <ana@example.com>", Subject: subject, Body: body})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-server.done
+
+	if server.from != "FROM:<billing@school.test>" {
+		t.Errorf("MAIL %s, want the sender's address", server.from)
+	}
+	if len(server.rcpt) != 1 || server.rcpt[0] != "TO:<ana@example.com>" {
+		t.Errorf("RCPT %v, want only the recipient's address", server.rcpt)
+	}
+
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(server.data)))
+	if err != nil {
+		t.Fatalf("parsing message: %v", err)
+	}
+
+	gotSubject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("decoding subject: %v", err)
+	}
+	if gotSubject != subject {
+		t.Errorf("subject = %q, want %q", gotSubject, subject)
+	}
+
+	decoded, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	for _, want := range []string{"Dear Ana Santos,", "María Santos", "PHP 11,500.00", "St. Jude School"} {
+		if !strings.Contains(string(decoded), want) {
+			t.Errorf("body is missing %q:\n%s", want, decoded)
+		}
+	}
+}
+
+func TestSMTPSenderUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	sender, err := NewSMTPSender(SMTPConfig{Host: "127.0.0.1", Port: port}, "billing@school.test")
+	if err != nil {
+		t.Fatalf("NewSMTPSender: %v", err)
+	}
+
+	if err := sender.Send(context.Background(), Message{To: "ana@example.com", Subject: "x", Body: "x"}); err == nil {
+		t.Error("Send to a closed port succeeded, want an error")
+	}
+}
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templates = mustLoadTemplates()
+
+// mustLoadTemplates parses each template file into its own set, named after
+// the notification kind, defining a "subject" and a "body". The templates
+// are embedded, so a parse error is a bug and panics at startup.
+func mustLoadTemplates() map[string]*template.Template {
+	entries, err := templateFS.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+
+	sets := make(map[string]*template.Template, len(entries))
+	for _, entry := range entries {
+		kind := strings.TrimSuffix(entry.Name(), ".tmpl")
+
+		sets[kind] = template.Must(template.New(kind).Funcs(templateFuncs).ParseFS(templateFS, "templates/"+entry.Name()))
+	}
+
+	return sets
+}
+
+var templateFuncs = template.FuncMap{
+	"money":  formatMoney,
+	"grade":  formatGradeLevel,
+	"method": formatPaymentMethod,
+}
+
+// Render builds the email for a notification of the given kind. school signs
+// off the message.
+func Render(kind string, data models.NotificationData, school string) (subject, body string, err error) {
+	set, ok := templates[kind]
+	if !ok {
+		return "", "", fmt.Errorf("no email template for %q", kind)
+	}
+
+	view := struct {
+		models.NotificationData
+		School string
+	}{data, school}
+
+	var buf bytes.Buffer
+
+	if err := set.ExecuteTemplate(&buf, "subject", view); err != nil {
+		return "", "", err
+	}
+	subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := set.ExecuteTemplate(&buf, "body", view); err != nil {
+		return "", "", err
+	}
+	body = strings.TrimSpace(buf.String()) + "\n"
+
+	return subject, body, nil
+}
+
+// formatMoney writes an amount in pesos with thousands separators, e.g.
+// "PHP 12,500.00".
+func formatMoney(amount decimal.Decimal) string {
+	sign := ""
+	if amount.IsNegative() {
+		sign = "-"
+		amount = amount.Neg()
+	}
+
+	whole, fraction, _ := strings.Cut(amount.StringFixed(2), ".")
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	return "PHP " + sign + grouped.String() + "." + fraction
+}
+
+// formatGradeLevel turns a grade level such as "grade-1" into "Grade 1".
+func formatGradeLevel(gradeLevel string) string {
+	words := strings.FieldsFunc(gradeLevel, func(r rune) bool { return r == '-' || r == '_' })
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+
+	return strings.Join(words, " ")
+}
+
+func formatPaymentMethod(method string) string {
+	switch method {
+	case "gcash":
+		return "GCash"
+	case "bank":
+		return "Bank transfer"
+	case "cash":
+		return "Cash"
+	default:
+		return method
+	}
+}
@@ -1,49 +1,78 @@
 package ratelimiter
 
 import (
+	"context"
 	"sync"
 	"time"
 )
 
 type FixedWindowRateLimiter struct {
-	sync.RWMutex
-	clients map[string]int
-	limit   int
-	window  time.Duration
+	sync.Mutex
+	clients   map[string]*fixedWindow
+	policy    Policy
+	lastSweep time.Time
+	now       func() time.Time
 }
 
-func NewFixedWindowLimiter(limit int, window time.Duration) *FixedWindowRateLimiter {
+type fixedWindow struct {
+	start time.Time
+	count int
+}
+
+func NewFixedWindowLimiter(policy Policy) *FixedWindowRateLimiter {
 	return &FixedWindowRateLimiter{
-		clients: make(map[string]int),
-		limit:   limit,
-		window:  window,
+		clients: make(map[string]*fixedWindow),
+		policy:  policy,
+		now:     time.Now,
 	}
 }
 
-func (rl *FixedWindowRateLimiter) Allow(ip string) (bool, time.Duration) {
+func (rl *FixedWindowRateLimiter) Policy() Policy {
+	return rl.policy
+}
+
+func (rl *FixedWindowRateLimiter) Allow(_ context.Context, key string) (Result, error) {
 	rl.Lock()
-	count, exists := rl.clients[ip]
-	rl.Unlock()
+	defer rl.Unlock()
 
-	if !exists || count < rl.limit {
-		rl.Lock()
-		if !exists {
-			go rl.resetCount(ip)
-		}
+	now := rl.now()
+	rl.sweep(now)
 
-		rl.clients[ip]++
-		rl.Unlock()
+	client, exists := rl.clients[key]
+	if !exists || now.Sub(client.start) >= rl.policy.Window {
+		client = &fixedWindow{start: now}
+		rl.clients[key] = client
+	}
 
-		return true, 0
+	reset := resetAfter(client.start.Add(rl.policy.Window).Sub(now))
+	result := Result{
+		Limit:      rl.policy.Limit,
+		ResetAfter: reset,
 	}
 
-	return false, rl.window
+	if client.count >= rl.policy.Limit {
+		result.RetryAfter = reset
+		return result, nil
+	}
 
+	client.count++
+	result.Allowed = true
+	result.Remaining = rl.policy.Limit - client.count
+
+	return result, nil
 }
 
-func (rl *FixedWindowRateLimiter) resetCount(ip string) {
-	time.Sleep(rl.window)
-	rl.Lock()
-	delete(rl.clients, ip)
-	rl.Unlock()
+// sweep drops windows that have expired, at most once per window, so idle
+// clients do not accumulate.
+func (rl *FixedWindowRateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rl.policy.Window {
+		return
+	}
+	rl.lastSweep = now
+
+	for key, client := range rl.clients {
+		if now.Sub(client.start) >= rl.policy.Window {
+			delete(rl.clients, key)
+		}
+	}
 }
@@ -1,13 +1,89 @@
 package ratelimiter
 
-import "time"
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	FixedWindow   = "fixed_window"
+	SlidingWindow = "sliding_window"
+	TokenBucket   = "token_bucket"
+)
+
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
 
 type Limiter interface {
-	Allow(ip string) (bool, time.Duration)
+	Allow(ctx context.Context, key string) (Result, error)
+	Policy() Policy
+}
+
+// Result describes the state of a client's quota after a request was counted.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration
+	RetryAfter time.Duration
+}
+
+// Policy is a named limit of requests per window. The name keeps counters of
+// different policies apart when they share a backend.
+type Policy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
 }
 
 type Config struct {
-	RequestPerTimeFrame int
-	TimeFrame           time.Duration
-	Enabled             bool
+	Algorithm string
+	Backend   string
+}
+
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
+// New builds a limiter for the configured backend. The client is only used,
+// and must be non-nil, for the Redis backend.
+func New(cfg Config, client redis.Scripter, policy Policy) (Limiter, error) {
+	switch cfg.Backend {
+	case BackendMemory:
+		return NewMemoryLimiter(cfg.Algorithm, policy)
+	case BackendRedis:
+		return NewRedisLimiter(client, cfg.Algorithm, policy)
+	default:
+		return nil, fmt.Errorf("unknown rate limiter backend %q", cfg.Backend)
+	}
+}
+
+// NewMemoryLimiter returns an in-process limiter for the given algorithm.
+func NewMemoryLimiter(algorithm string, policy Policy) (Limiter, error) {
+	switch algorithm {
+	case FixedWindow:
+		return NewFixedWindowLimiter(policy), nil
+	case SlidingWindow:
+		return NewSlidingWindowLimiter(policy), nil
+	case TokenBucket:
+		return NewTokenBucketLimiter(policy), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limiter algorithm %q", algorithm)
+	}
+}
+
+// resetAfter rounds a remaining duration up to whole seconds, the unit used
+// by the RateLimit-Reset and Retry-After headers.
+func resetAfter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return ((d + time.Second - 1) / time.Second) * time.Second
 }
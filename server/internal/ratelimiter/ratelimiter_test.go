@@ -0,0 +1,218 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testClock is a settable clock for the limiters' now field.
+type testClock struct {
+	t time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{t: time.Date(2025, time.June, 2, 8, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) now() time.Time {
+	return c.t
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+// step is one request in a limiter scenario, made after advancing the clock.
+type step struct {
+	advance    time.Duration
+	key        string
+	allowed    bool
+	remaining  int
+	resetAfter time.Duration
+	retryAfter time.Duration
+}
+
+func runSteps(t *testing.T, limiter Limiter, clock *testClock, steps []step) {
+	t.Helper()
+
+	for i, s := range steps {
+		clock.advance(s.advance)
+
+		key := s.key
+		if key == "" {
+			key = "ip:192.0.2.1"
+		}
+
+		result, err := limiter.Allow(context.Background(), key)
+		if err != nil {
+			t.Fatalf("step %d: Allow: %v", i, err)
+		}
+
+		want := Result{
+			Allowed:    s.allowed,
+			Limit:      limiter.Policy().Limit,
+			Remaining:  s.remaining,
+			ResetAfter: s.resetAfter,
+			RetryAfter: s.retryAfter,
+		}
+		if result != want {
+			t.Errorf("step %d: result = %+v, want %+v", i, result, want)
+		}
+	}
+}
+
+func TestFixedWindowLimiter(t *testing.T) {
+	clock := newTestClock()
+	limiter := NewFixedWindowLimiter(Policy{Name: "test", Limit: 3, Window: 10 * time.Second})
+	limiter.now = clock.now
+
+	runSteps(t, limiter, clock, []step{
+		{allowed: true, remaining: 2, resetAfter: 10 * time.Second},
+		{allowed: true, remaining: 1, resetAfter: 10 * time.Second},
+		{advance: 4 * time.Second, allowed: true, remaining: 0, resetAfter: 6 * time.Second},
+		{allowed: false, resetAfter: 6 * time.Second, retryAfter: 6 * time.Second},
+		// Other clients have their own window.
+		{key: "ip:192.0.2.2", allowed: true, remaining: 2, resetAfter: 10 * time.Second},
+		// Part seconds round up.
+		{advance: 5500 * time.Millisecond, allowed: false, resetAfter: time.Second, retryAfter: time.Second},
+		// The window ends exactly Window after its first request.
+		{advance: 500 * time.Millisecond, allowed: true, remaining: 2, resetAfter: 10 * time.Second},
+	})
+}
+
+func TestSlidingWindowLimiter(t *testing.T) {
+	clock := newTestClock()
+	limiter := NewSlidingWindowLimiter(Policy{Name: "test", Limit: 4, Window: 10 * time.Second})
+	limiter.now = clock.now
+
+	runSteps(t, limiter, clock, []step{
+		{allowed: true, remaining: 3, resetAfter: 10 * time.Second},
+		{allowed: true, remaining: 2, resetAfter: 10 * time.Second},
+		{allowed: true, remaining: 1, resetAfter: 10 * time.Second},
+		{advance: 9 * time.Second, allowed: true, remaining: 0, resetAfter: time.Second},
+		// The current window alone is full: wait for the next one.
+		{allowed: false, resetAfter: time.Second, retryAfter: time.Second},
+		// At the boundary the previous window still counts in full, unlike a
+		// fixed window that would allow a second burst.
+		{advance: time.Second, allowed: false, resetAfter: 10 * time.Second, retryAfter: time.Second},
+		// A quarter of the way in, 4 * 0.75 = 3 requests still count.
+		{advance: 2500 * time.Millisecond, allowed: true, remaining: 0, resetAfter: 8 * time.Second},
+		{allowed: false, resetAfter: 8 * time.Second, retryAfter: time.Second},
+		// Halfway in, 4 * 0.5 + 1 = 3.
+		{advance: 2500 * time.Millisecond, allowed: true, remaining: 0, resetAfter: 5 * time.Second},
+		// More than a whole window of silence forgets both windows.
+		{advance: 25 * time.Second, allowed: true, remaining: 3, resetAfter: 10 * time.Second},
+	})
+}
+
+func TestSlidingRetryAfter(t *testing.T) {
+	tests := []struct {
+		name              string
+		previous, current int
+		limit             int
+		elapsed           time.Duration
+		want              time.Duration
+	}{
+		{"current window full", 0, 5, 5, 3 * time.Second, 7 * time.Second},
+		{"no previous window", 0, 2, 5, 3 * time.Second, 7 * time.Second},
+		{"previous window decays", 10, 0, 5, 2 * time.Second, 3 * time.Second},
+		{"current requests delay decay", 10, 2, 5, time.Second, 6 * time.Second},
+		{"part seconds round up", 3, 0, 2, 0, 4 * time.Second},
+		{"already decayed", 10, 0, 5, 6 * time.Second, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slidingRetryAfter(tt.previous, tt.current, tt.limit, tt.elapsed, 10*time.Second)
+			if got != tt.want {
+				t.Errorf("slidingRetryAfter = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenBucketLimiter(t *testing.T) {
+	clock := newTestClock()
+	// Four tokens, refilled at one every two seconds.
+	limiter := NewTokenBucketLimiter(Policy{Name: "test", Limit: 4, Window: 8 * time.Second})
+	limiter.now = clock.now
+
+	runSteps(t, limiter, clock, []step{
+		{allowed: true, remaining: 3, resetAfter: 2 * time.Second},
+		{allowed: true, remaining: 2, resetAfter: 4 * time.Second},
+		{allowed: true, remaining: 1, resetAfter: 6 * time.Second},
+		{allowed: true, remaining: 0, resetAfter: 8 * time.Second},
+		{allowed: false, resetAfter: 8 * time.Second, retryAfter: 2 * time.Second},
+		// Half a token has refilled.
+		{advance: time.Second, allowed: false, resetAfter: 7 * time.Second, retryAfter: time.Second},
+		{advance: time.Second, allowed: true, remaining: 0, resetAfter: 8 * time.Second},
+		// The bucket never holds more than the limit.
+		{advance: time.Minute, allowed: true, remaining: 3, resetAfter: 2 * time.Second},
+	})
+}
+
+func TestSweepDropsIdleClients(t *testing.T) {
+	clock := newTestClock()
+	policy := Policy{Name: "test", Limit: 1, Window: 10 * time.Second}
+
+	fixed := NewFixedWindowLimiter(policy)
+	fixed.now = clock.now
+	sliding := NewSlidingWindowLimiter(policy)
+	sliding.now = clock.now
+	bucket := NewTokenBucketLimiter(policy)
+	bucket.now = clock.now
+
+	limiters := []Limiter{fixed, sliding, bucket}
+	for _, limiter := range limiters {
+		limiter.Allow(context.Background(), "ip:192.0.2.1")
+	}
+
+	clock.advance(time.Minute)
+
+	for _, limiter := range limiters {
+		limiter.Allow(context.Background(), "ip:192.0.2.2")
+	}
+
+	for name, clients := range map[string]int{
+		"fixed window":   len(fixed.clients),
+		"sliding window": len(sliding.clients),
+		"token bucket":   len(bucket.clients),
+	} {
+		if clients != 1 {
+			t.Errorf("%s keeps %d clients, want only the active one", name, clients)
+		}
+	}
+}
+
+func TestResetAfter(t *testing.T) {
+	tests := []struct {
+		in, want time.Duration
+	}{
+		{-time.Second, 0},
+		{0, 0},
+		{time.Millisecond, time.Second},
+		{time.Second, time.Second},
+		{1001 * time.Millisecond, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := resetAfter(tt.in); got != tt.want {
+			t.Errorf("resetAfter(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	policy := Policy{Name: "test", Limit: 1, Window: time.Second}
+
+	if _, err := New(Config{Algorithm: TokenBucket, Backend: BackendMemory}, nil, policy); err != nil {
+		t.Errorf("memory token bucket: %v", err)
+	}
+	if _, err := New(Config{Algorithm: "leaky_bucket", Backend: BackendMemory}, nil, policy); err == nil {
+		t.Error("unknown algorithm accepted")
+	}
+	if _, err := New(Config{Algorithm: FixedWindow, Backend: "memcached"}, nil, policy); err == nil {
+		t.Error("unknown backend accepted")
+	}
+}
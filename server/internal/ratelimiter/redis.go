@@ -0,0 +1,206 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// The scripts read the clock with TIME so every API instance sharing a Redis
+// server agrees on window boundaries regardless of local clock skew. Each
+// script runs atomically, which removes the check-then-increment race.
+var (
+	fixedWindowScript = redis.NewScript(`
+		local limit = tonumber(ARGV[1])
+		local window = tonumber(ARGV[2])
+
+		local count = tonumber(redis.call('GET', KEYS[1]) or '0')
+		local ttl = redis.call('PTTL', KEYS[1])
+		if ttl < 0 then
+			ttl = window
+		end
+
+		if count >= limit then
+			return {0, 0, ttl}
+		end
+
+		count = redis.call('INCR', KEYS[1])
+		if count == 1 then
+			redis.call('PEXPIRE', KEYS[1], window)
+		end
+
+		return {1, limit - count, ttl}
+	`)
+
+	slidingWindowScript = redis.NewScript(`
+		local limit = tonumber(ARGV[1])
+		local window = tonumber(ARGV[2])
+
+		local time = redis.call('TIME')
+		local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+		local index = math.floor(now / window)
+		local elapsed = now - index * window
+
+		local currentKey = KEYS[1] .. ':' .. index
+		local current = tonumber(redis.call('GET', currentKey) or '0')
+		local previous = tonumber(redis.call('GET', KEYS[1] .. ':' .. (index - 1)) or '0')
+
+		local estimated = math.floor(previous * (1 - elapsed / window)) + current
+		if estimated >= limit then
+			return {0, 0, elapsed, previous, current}
+		end
+
+		redis.call('INCR', currentKey)
+		redis.call('PEXPIRE', currentKey, window * 2)
+
+		return {1, limit - estimated - 1, elapsed, previous, current}
+	`)
+
+	tokenBucketScript = redis.NewScript(`
+		local capacity = tonumber(ARGV[1])
+		local window = tonumber(ARGV[2])
+		local rate = capacity / window
+
+		local time = redis.call('TIME')
+		local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+		local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+		local tokens = tonumber(state[1]) or capacity
+		local last = tonumber(state[2]) or now
+
+		tokens = math.min(capacity, tokens + math.max(0, now - last) * rate)
+
+		local allowed = 0
+		if tokens >= 1 then
+			tokens = tokens - 1
+			allowed = 1
+		end
+
+		redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(now))
+		redis.call('PEXPIRE', KEYS[1], window)
+
+		return {allowed, tostring(tokens)}
+	`)
+)
+
+// RedisRateLimiter keeps counters in Redis so that every API instance shares
+// the same quota per client.
+type RedisRateLimiter struct {
+	client    redis.Scripter
+	algorithm string
+	policy    Policy
+}
+
+func NewRedisClient(cfg RedisConfig) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+}
+
+func NewRedisLimiter(client redis.Scripter, algorithm string, policy Policy) (*RedisRateLimiter, error) {
+	switch algorithm {
+	case FixedWindow, SlidingWindow, TokenBucket:
+	default:
+		return nil, fmt.Errorf("unknown rate limiter algorithm %q", algorithm)
+	}
+
+	return &RedisRateLimiter{
+		client:    client,
+		algorithm: algorithm,
+		policy:    policy,
+	}, nil
+}
+
+func (rl *RedisRateLimiter) Policy() Policy {
+	return rl.policy
+}
+
+func (rl *RedisRateLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	// The hash tag keeps a sliding window's two keys in the same cluster slot.
+	keys := []string{fmt.Sprintf("ratelimit:%s:%s:{%s}", rl.algorithm, rl.policy.Name, key)}
+	args := []any{rl.policy.Limit, rl.policy.Window.Milliseconds()}
+
+	switch rl.algorithm {
+	case FixedWindow:
+		return rl.fixedWindow(ctx, keys, args)
+	case SlidingWindow:
+		return rl.slidingWindow(ctx, keys, args)
+	default:
+		return rl.tokenBucket(ctx, keys, args)
+	}
+}
+
+func (rl *RedisRateLimiter) fixedWindow(ctx context.Context, keys []string, args []any) (Result, error) {
+	values, err := fixedWindowScript.Run(ctx, rl.client, keys, args...).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{
+		Allowed:    values[0] == 1,
+		Limit:      rl.policy.Limit,
+		Remaining:  int(values[1]),
+		ResetAfter: resetAfter(time.Duration(values[2]) * time.Millisecond),
+	}
+	if !result.Allowed {
+		result.RetryAfter = result.ResetAfter
+	}
+
+	return result, nil
+}
+
+func (rl *RedisRateLimiter) slidingWindow(ctx context.Context, keys []string, args []any) (Result, error) {
+	values, err := slidingWindowScript.Run(ctx, rl.client, keys, args...).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	elapsed := time.Duration(values[2]) * time.Millisecond
+	window := rl.policy.Window
+
+	result := Result{
+		Allowed:    values[0] == 1,
+		Limit:      rl.policy.Limit,
+		Remaining:  int(values[1]),
+		ResetAfter: resetAfter(window - elapsed),
+	}
+	if !result.Allowed {
+		result.RetryAfter = slidingRetryAfter(int(values[3]), int(values[4]), rl.policy.Limit, elapsed, window)
+	}
+
+	return result, nil
+}
+
+func (rl *RedisRateLimiter) tokenBucket(ctx context.Context, keys []string, args []any) (Result, error) {
+	values, err := tokenBucketScript.Run(ctx, rl.client, keys, args...).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, _ := values[0].(int64)
+	raw, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return Result{}, err
+	}
+
+	capacity := float64(rl.policy.Limit)
+	rate := capacity / float64(rl.policy.Window)
+
+	result := Result{
+		Allowed:    allowed == 1,
+		Limit:      rl.policy.Limit,
+		Remaining:  int(tokens),
+		ResetAfter: resetAfter(time.Duration((capacity - tokens) / rate)),
+	}
+	if !result.Allowed {
+		result.RetryAfter = resetAfter(time.Duration((1 - tokens) / rate))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,156 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedisLimiter runs the limiter's scripts against an in-process
+// miniredis, whose clock the scripts read through TIME.
+func newTestRedisLimiter(t *testing.T, algorithm string, policy Policy) (*RedisRateLimiter, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	server.SetTime(time.Date(2025, time.June, 2, 8, 0, 0, 0, time.UTC))
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	limiter, err := NewRedisLimiter(client, algorithm, policy)
+	if err != nil {
+		t.Fatalf("NewRedisLimiter: %v", err)
+	}
+
+	return limiter, server
+}
+
+type redisStep struct {
+	// at is the time since the start of the scenario; expire additionally
+	// lets keys' TTLs run out.
+	at         time.Duration
+	expire     time.Duration
+	allowed    bool
+	remaining  int
+	resetAfter time.Duration
+	retryAfter time.Duration
+}
+
+func runRedisSteps(t *testing.T, limiter *RedisRateLimiter, server *miniredis.Miniredis, steps []redisStep) {
+	t.Helper()
+
+	start := time.Date(2025, time.June, 2, 8, 0, 0, 0, time.UTC)
+
+	for i, s := range steps {
+		server.SetTime(start.Add(s.at))
+		if s.expire > 0 {
+			server.FastForward(s.expire)
+		}
+
+		result, err := limiter.Allow(context.Background(), "ip:192.0.2.1")
+		if err != nil {
+			t.Fatalf("step %d: Allow: %v", i, err)
+		}
+
+		want := Result{
+			Allowed:    s.allowed,
+			Limit:      limiter.Policy().Limit,
+			Remaining:  s.remaining,
+			ResetAfter: s.resetAfter,
+			RetryAfter: s.retryAfter,
+		}
+		if result != want {
+			t.Errorf("step %d: result = %+v, want %+v", i, result, want)
+		}
+	}
+}
+
+func TestRedisFixedWindow(t *testing.T) {
+	policy := Policy{Name: "test", Limit: 2, Window: 10 * time.Second}
+	limiter, server := newTestRedisLimiter(t, FixedWindow, policy)
+
+	runRedisSteps(t, limiter, server, []redisStep{
+		{allowed: true, remaining: 1, resetAfter: 10 * time.Second},
+		{allowed: true, remaining: 0, resetAfter: 10 * time.Second},
+		{allowed: false, resetAfter: 10 * time.Second, retryAfter: 10 * time.Second},
+		{expire: 4 * time.Second, allowed: false, resetAfter: 6 * time.Second, retryAfter: 6 * time.Second},
+		// The counter expires with its window.
+		{expire: 6 * time.Second, allowed: true, remaining: 1, resetAfter: 10 * time.Second},
+	})
+
+	if !server.Exists("ratelimit:fixed_window:test:{ip:192.0.2.1}") {
+		t.Errorf("keys = %v, want the counter under the policy and client", server.Keys())
+	}
+}
+
+func TestRedisSlidingWindow(t *testing.T) {
+	policy := Policy{Name: "test", Limit: 2, Window: 10 * time.Second}
+	limiter, server := newTestRedisLimiter(t, SlidingWindow, policy)
+
+	runRedisSteps(t, limiter, server, []redisStep{
+		{allowed: true, remaining: 1, resetAfter: 10 * time.Second},
+		{allowed: true, remaining: 0, resetAfter: 10 * time.Second},
+		{allowed: false, resetAfter: 10 * time.Second, retryAfter: 10 * time.Second},
+		// The previous window still counts in full at the boundary.
+		{at: 10 * time.Second, allowed: false, resetAfter: 10 * time.Second, retryAfter: time.Second},
+		// Halfway in, only one of its two requests counts.
+		{at: 15 * time.Second, allowed: true, remaining: 0, resetAfter: 5 * time.Second},
+		// That request and the decayed one fill the quota until the previous
+		// window has faded a little further.
+		{at: 15 * time.Second, allowed: false, resetAfter: 5 * time.Second, retryAfter: time.Second},
+		{at: 16 * time.Second, allowed: true, remaining: 0, resetAfter: 4 * time.Second},
+	})
+}
+
+func TestRedisTokenBucket(t *testing.T) {
+	// Two tokens, refilled at one every five seconds.
+	policy := Policy{Name: "test", Limit: 2, Window: 10 * time.Second}
+	limiter, server := newTestRedisLimiter(t, TokenBucket, policy)
+
+	runRedisSteps(t, limiter, server, []redisStep{
+		{allowed: true, remaining: 1, resetAfter: 5 * time.Second},
+		{allowed: true, remaining: 0, resetAfter: 10 * time.Second},
+		{allowed: false, resetAfter: 10 * time.Second, retryAfter: 5 * time.Second},
+		{at: 6 * time.Second, allowed: true, remaining: 0, resetAfter: 9 * time.Second},
+		// The bucket never holds more than the limit.
+		{at: time.Minute, allowed: true, remaining: 1, resetAfter: 5 * time.Second},
+	})
+}
+
+func TestRedisPoliciesAreSeparate(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	strict, err := NewRedisLimiter(client, FixedWindow, Policy{Name: "auth", Limit: 1, Window: time.Minute})
+	if err != nil {
+		t.Fatalf("NewRedisLimiter: %v", err)
+	}
+	loose, err := NewRedisLimiter(client, FixedWindow, Policy{Name: "default", Limit: 5, Window: time.Minute})
+	if err != nil {
+		t.Fatalf("NewRedisLimiter: %v", err)
+	}
+
+	ctx := context.Background()
+	strict.Allow(ctx, "ip:192.0.2.1")
+
+	if result, _ := strict.Allow(ctx, "ip:192.0.2.1"); result.Allowed {
+		t.Error("auth policy allowed a second request")
+	}
+	if result, _ := loose.Allow(ctx, "ip:192.0.2.1"); !result.Allowed || result.Remaining != 4 {
+		t.Errorf("default policy result = %+v, want its own quota", result)
+	}
+}
+
+func TestRedisUnavailable(t *testing.T) {
+	limiter, server := newTestRedisLimiter(t, SlidingWindow, Policy{Name: "test", Limit: 1, Window: time.Second})
+	server.Close()
+
+	if _, err := limiter.Allow(context.Background(), "ip:192.0.2.1"); err == nil {
+		t.Error("Allow succeeded without a Redis server, want an error")
+	}
+}
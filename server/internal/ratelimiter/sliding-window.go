@@ -0,0 +1,109 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SlidingWindowRateLimiter approximates a rolling window by weighting the
+// previous fixed window's count by how much of it still overlaps the rolling
+// window. It avoids the burst of up to twice the limit that a fixed window
+// allows around its boundary.
+type SlidingWindowRateLimiter struct {
+	sync.Mutex
+	clients   map[string]*slidingWindow
+	policy    Policy
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type slidingWindow struct {
+	start    time.Time
+	previous int
+	current  int
+}
+
+func NewSlidingWindowLimiter(policy Policy) *SlidingWindowRateLimiter {
+	return &SlidingWindowRateLimiter{
+		clients: make(map[string]*slidingWindow),
+		policy:  policy,
+		now:     time.Now,
+	}
+}
+
+func (rl *SlidingWindowRateLimiter) Policy() Policy {
+	return rl.policy
+}
+
+func (rl *SlidingWindowRateLimiter) Allow(_ context.Context, key string) (Result, error) {
+	rl.Lock()
+	defer rl.Unlock()
+
+	now := rl.now()
+	window := rl.policy.Window
+	rl.sweep(now)
+
+	start := now.Truncate(window)
+
+	client, exists := rl.clients[key]
+	switch {
+	case !exists:
+		client = &slidingWindow{start: start}
+		rl.clients[key] = client
+	case start.Sub(client.start) == window:
+		client.previous, client.current, client.start = client.current, 0, start
+	case start.Sub(client.start) > window:
+		client.previous, client.current, client.start = 0, 0, start
+	}
+
+	weight := 1 - float64(now.Sub(start))/float64(window)
+	estimated := int(float64(client.previous)*weight) + client.current
+
+	result := Result{
+		Limit:      rl.policy.Limit,
+		ResetAfter: resetAfter(start.Add(window).Sub(now)),
+	}
+
+	if estimated >= rl.policy.Limit {
+		result.RetryAfter = slidingRetryAfter(client.previous, client.current, rl.policy.Limit, now.Sub(start), window)
+		return result, nil
+	}
+
+	client.current++
+	result.Allowed = true
+	result.Remaining = rl.policy.Limit - estimated - 1
+
+	return result, nil
+}
+
+func (rl *SlidingWindowRateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rl.policy.Window {
+		return
+	}
+	rl.lastSweep = now
+
+	for key, client := range rl.clients {
+		if now.Sub(client.start) >= 2*rl.policy.Window {
+			delete(rl.clients, key)
+		}
+	}
+}
+
+// slidingRetryAfter returns how long until the weighted previous window has
+// decayed enough for one more request, or until the next window when the
+// current one alone is full.
+func slidingRetryAfter(previous, current, limit int, elapsed, window time.Duration) time.Duration {
+	if current >= limit || previous == 0 {
+		return resetAfter(window - elapsed)
+	}
+
+	// previous * (1 - t/window) + current < limit
+	needed := float64(window) * (1 - float64(limit-current)/float64(previous))
+	wait := time.Duration(needed) - elapsed
+	if wait <= 0 {
+		wait = time.Second
+	}
+
+	return resetAfter(wait)
+}
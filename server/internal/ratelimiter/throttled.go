@@ -0,0 +1,92 @@
+package ratelimiter
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ThrottledClient is a client that was recently rejected by a policy.
+type ThrottledClient struct {
+	Key           string    `json:"key"`
+	Policy        string    `json:"policy"`
+	Rejections    int       `json:"rejections"`
+	FirstRejected time.Time `json:"first_rejected"`
+	LastRejected  time.Time `json:"last_rejected"`
+	BlockedUntil  time.Time `json:"blocked_until"`
+}
+
+// ThrottleTracker remembers rejected clients until their retry time passes.
+// It is kept per process, so with a shared backend each instance reports the
+// clients it turned away.
+type ThrottleTracker struct {
+	sync.Mutex
+	clients   map[string]*ThrottledClient
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+const throttleSweepInterval = time.Minute
+
+func NewThrottleTracker() *ThrottleTracker {
+	return &ThrottleTracker{
+		clients: make(map[string]*ThrottledClient),
+		now:     time.Now,
+	}
+}
+
+func (t *ThrottleTracker) Record(policy, key string, retryAfter time.Duration) {
+	t.Lock()
+	defer t.Unlock()
+
+	now := t.now()
+	id := policy + "\x00" + key
+
+	if now.Sub(t.lastSweep) >= throttleSweepInterval {
+		t.lastSweep = now
+		t.prune(now)
+	}
+
+	client, exists := t.clients[id]
+	if !exists || now.After(client.BlockedUntil) {
+		client = &ThrottledClient{
+			Key:           key,
+			Policy:        policy,
+			FirstRejected: now,
+		}
+		t.clients[id] = client
+	}
+
+	client.Rejections++
+	client.LastRejected = now
+	client.BlockedUntil = now.Add(retryAfter)
+}
+
+// Throttled returns the clients that are still blocked, most recently
+// rejected first, and forgets the rest.
+func (t *ThrottleTracker) Throttled() []ThrottledClient {
+	t.Lock()
+	defer t.Unlock()
+
+	now := t.now()
+	clients := make([]ThrottledClient, 0, len(t.clients))
+
+	t.prune(now)
+	for _, client := range t.clients {
+		clients = append(clients, *client)
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].LastRejected.After(clients[j].LastRejected)
+	})
+
+	return clients
+}
+
+func (t *ThrottleTracker) prune(now time.Time) {
+	for id, client := range t.clients {
+		if now.After(client.BlockedUntil) {
+			delete(t.clients, id)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package ratelimiter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucketRateLimiter lets clients burst up to the policy limit and then
+// refills at limit tokens per window.
+type TokenBucketRateLimiter struct {
+	sync.Mutex
+	clients   map[string]*tokenBucket
+	policy    Policy
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucketLimiter(policy Policy) *TokenBucketRateLimiter {
+	return &TokenBucketRateLimiter{
+		clients: make(map[string]*tokenBucket),
+		policy:  policy,
+		now:     time.Now,
+	}
+}
+
+func (rl *TokenBucketRateLimiter) Policy() Policy {
+	return rl.policy
+}
+
+func (rl *TokenBucketRateLimiter) Allow(_ context.Context, key string) (Result, error) {
+	rl.Lock()
+	defer rl.Unlock()
+
+	now := rl.now()
+	rl.sweep(now)
+
+	capacity := float64(rl.policy.Limit)
+	rate := capacity / float64(rl.policy.Window)
+
+	bucket, exists := rl.clients[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: capacity, last: now}
+		rl.clients[key] = bucket
+	}
+
+	bucket.tokens = math.Min(capacity, bucket.tokens+float64(now.Sub(bucket.last))*rate)
+	bucket.last = now
+
+	result := Result{Limit: rl.policy.Limit}
+
+	if bucket.tokens < 1 {
+		result.RetryAfter = resetAfter(time.Duration((1 - bucket.tokens) / rate))
+		result.ResetAfter = resetAfter(time.Duration((capacity - bucket.tokens) / rate))
+		return result, nil
+	}
+
+	bucket.tokens--
+	result.Allowed = true
+	result.Remaining = int(bucket.tokens)
+	result.ResetAfter = resetAfter(time.Duration((capacity - bucket.tokens) / rate))
+
+	return result, nil
+}
+
+// sweep drops buckets that would have refilled completely, since a new
+// bucket starts full anyway.
+func (rl *TokenBucketRateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rl.policy.Window {
+		return
+	}
+	rl.lastSweep = now
+
+	for key, bucket := range rl.clients {
+		if now.Sub(bucket.last) >= rl.policy.Window {
+			delete(rl.clients, key)
+		}
+	}
+}
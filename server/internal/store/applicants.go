@@ -0,0 +1,534 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// requiredDocuments is the admissions checklist every applicant must complete
+// before they can be accepted.
+var requiredDocuments = []string{constants.BirthCertificate, constants.ReportCard}
+
+// applicantStatuses lists the admissions stages in pipeline order.
+var applicantStatuses = []string{
+	constants.Inquiry,
+	constants.Applied,
+	constants.Assessed,
+	constants.Accepted,
+	constants.Rejected,
+	constants.Converted,
+}
+
+// applicantTransitions lists the statuses an applicant can be moved to from
+// each stage. Accepted applicants leave the pipeline by being converted into
+// an enrollment; rejected and converted applicants are closed.
+var applicantTransitions = map[string][]string{
+	constants.Inquiry:  {constants.Applied, constants.Rejected},
+	constants.Applied:  {constants.Assessed, constants.Rejected},
+	constants.Assessed: {constants.Accepted, constants.Rejected},
+	constants.Accepted: {constants.Rejected},
+}
+
+const applicantSelect = `
+	SELECT
+		a.id,
+		a.school_year,
+		a.grade_level,
+		a.status,
+		e.student_id,
+		a.first_name,
+		a.middle_name,
+		a.last_name,
+		COALESCE(a.suffix, ''),
+		TRIM(CONCAT_WS(' ',
+			a.first_name,
+			CASE
+				WHEN a.middle_name IS NOT NULL AND a.middle_name <> ''
+				THEN LEFT(a.middle_name, 1) || '.'
+				ELSE NULL
+			END,
+			a.last_name,
+			a.suffix
+		)) AS full_name,
+		a.gender,
+		a.birthdate,
+		a.address,
+		COALESCE(a.mother_name, ''),
+		COALESCE(a.mother_job, ''),
+		COALESCE(a.mother_education, ''),
+		COALESCE(a.father_name, ''),
+		COALESCE(a.father_job, ''),
+		COALESCE(a.father_education, ''),
+		COALESCE(a.living_with, ''),
+		a.contact_numbers,
+		a.notes,
+		a.enrollment_id,
+		ARRAY(
+			SELECT t.type
+			FROM unnest($1::text[]) WITH ORDINALITY AS t(type, position)
+			WHERE NOT EXISTS (
+				SELECT 1 FROM applicant_documents d
+				WHERE d.applicant_id = a.id AND d.type = t.type
+			)
+			ORDER BY t.position
+		),
+		a.status_changed_at,
+		a.created_at,
+		a.updated_at
+	FROM applicants a
+	LEFT JOIN enrollments e ON e.id = a.enrollment_id
+`
+
+type ApplicantStore struct {
+	db *sql.DB
+}
+
+func (s *ApplicantStore) Create(ctx context.Context, applicant *models.Applicant) error {
+	query := `
+		INSERT INTO applicants
+			(school_year, grade_level, status, first_name, middle_name, last_name, suffix, gender,
+			birthdate, address, mother_name, mother_job, mother_education,
+			father_name, father_job, father_education, contact_numbers, living_with, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		RETURNING id, status_changed_at, created_at, updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	if applicant.Status == "" {
+		applicant.Status = constants.Inquiry
+	}
+
+	student := applicant.Student
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		applicant.SchoolYear,
+		applicant.GradeLevel,
+		applicant.Status,
+		student.FirstName,
+		student.MiddleName,
+		student.LastName,
+		student.Suffix,
+		student.Gender,
+		student.Birthdate,
+		student.Address,
+		student.MotherName,
+		student.MotherJob,
+		student.MotherEducation,
+		student.FatherName,
+		student.FatherJob,
+		student.FatherEducation,
+		pq.Array(student.ContactNumbers),
+		student.LivingWith,
+		applicant.Notes,
+	).Scan(
+		&applicant.ID,
+		&applicant.StatusChangedAt,
+		&applicant.CreatedAt,
+		&applicant.UpdatedAt,
+	)
+	if err != nil {
+		return parsePgError(err)
+	}
+
+	applicant.MissingDocuments = slices.Clone(requiredDocuments)
+
+	return nil
+}
+
+// GetByID returns an applicant with their full document checklist.
+func (s *ApplicantStore) GetByID(ctx context.Context, id uuid.UUID) (models.Applicant, error) {
+	query := applicantSelect + `WHERE a.id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var applicant models.Applicant
+	err := scanApplicant(s.db.QueryRowContext(ctx, query, pq.Array(requiredDocuments), id), &applicant)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return applicant, ErrNotFound
+		}
+		return applicant, err
+	}
+
+	query = `
+		SELECT t.type, d.received_on, COALESCE(d.notes, '')
+		FROM unnest($2::text[]) WITH ORDINALITY AS t(type, position)
+		LEFT JOIN applicant_documents d ON d.applicant_id = $1 AND d.type = t.type
+		ORDER BY t.position
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, id, pq.Array(requiredDocuments))
+	if err != nil {
+		return applicant, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		document := models.ApplicantDocument{ApplicantID: id}
+		if err := rows.Scan(&document.Type, &document.ReceivedOn, &document.Notes); err != nil {
+			return applicant, err
+		}
+
+		applicant.Documents = append(applicant.Documents, document)
+	}
+
+	return applicant, rows.Err()
+}
+
+// GetAll lists the applicants of a school year, optionally only those at one
+// status, oldest first.
+func (s *ApplicantStore) GetAll(ctx context.Context, schoolYear, status string) ([]models.Applicant, error) {
+	query := applicantSelect + `
+		WHERE a.school_year = $2 AND ($3 = '' OR a.status = $3)
+		ORDER BY a.created_at, a.id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(requiredDocuments), schoolYear, status)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var applicants []models.Applicant
+
+	for rows.Next() {
+		var applicant models.Applicant
+		if err := scanApplicant(rows, &applicant); err != nil {
+			return nil, err
+		}
+
+		applicants = append(applicants, applicant)
+	}
+
+	return applicants, rows.Err()
+}
+
+// GetPipeline counts the applicants of a school year at every status, in
+// pipeline order.
+func (s *ApplicantStore) GetPipeline(ctx context.Context, schoolYear string) ([]models.GroupCount, error) {
+	query := `
+		SELECT t.status, COUNT(a.id)
+		FROM unnest($2::text[]) WITH ORDINALITY AS t(status, position)
+		LEFT JOIN applicants a ON a.status = t.status AND a.school_year = $1
+		GROUP BY t.status, t.position
+		ORDER BY t.position
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, schoolYear, pq.Array(applicantStatuses))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var pipeline []models.GroupCount
+
+	for rows.Next() {
+		var count models.GroupCount
+		if err := rows.Scan(&count.Group, &count.Count); err != nil {
+			return nil, err
+		}
+
+		pipeline = append(pipeline, count)
+	}
+
+	return pipeline, rows.Err()
+}
+
+// Update changes the details of an applicant who is still in the pipeline.
+func (s *ApplicantStore) Update(ctx context.Context, applicant *models.Applicant) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		current, err := s.lockApplicant(ctx, tx, applicant.ID)
+		if err != nil {
+			return err
+		}
+
+		if isApplicantClosed(current.Status) {
+			return ErrApplicantClosed
+		}
+
+		query := `
+			UPDATE applicants
+			SET
+				school_year = $1,
+				grade_level = $2,
+				first_name = $3,
+				middle_name = $4,
+				last_name = $5,
+				suffix = $6,
+				gender = $7,
+				birthdate = $8,
+				address = $9,
+				mother_name = $10,
+				mother_job = $11,
+				mother_education = $12,
+				father_name = $13,
+				father_job = $14,
+				father_education = $15,
+				contact_numbers = $16,
+				living_with = $17,
+				notes = $18,
+				updated_at = now()
+			WHERE id = $19
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		student := applicant.Student
+
+		_, err = tx.ExecContext(
+			ctx,
+			query,
+			applicant.SchoolYear,
+			applicant.GradeLevel,
+			student.FirstName,
+			student.MiddleName,
+			student.LastName,
+			student.Suffix,
+			student.Gender,
+			student.Birthdate,
+			student.Address,
+			student.MotherName,
+			student.MotherJob,
+			student.MotherEducation,
+			student.FatherName,
+			student.FatherJob,
+			student.FatherEducation,
+			pq.Array(student.ContactNumbers),
+			student.LivingWith,
+			applicant.Notes,
+			applicant.ID,
+		)
+
+		return parsePgError(err)
+	})
+}
+
+// SetStatus moves an applicant along the pipeline. An applicant can only be
+// accepted once every required document has been received.
+func (s *ApplicantStore) SetStatus(ctx context.Context, id uuid.UUID, status string) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		applicant, err := s.lockApplicant(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if isApplicantClosed(applicant.Status) {
+			return ErrApplicantClosed
+		}
+
+		if !slices.Contains(applicantTransitions[applicant.Status], status) {
+			return ErrInvalidTransition
+		}
+
+		if status == constants.Accepted && len(applicant.MissingDocuments) > 0 {
+			return ErrDocumentsMissing
+		}
+
+		return s.updateStatus(ctx, tx, id, status, nil)
+	})
+}
+
+// SetDocument records a checklist document as received, or updates when and
+// how it was received.
+func (s *ApplicantStore) SetDocument(ctx context.Context, document *models.ApplicantDocument) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		applicant, err := s.lockApplicant(ctx, tx, document.ApplicantID)
+		if err != nil {
+			return err
+		}
+
+		if isApplicantClosed(applicant.Status) {
+			return ErrApplicantClosed
+		}
+
+		query := `
+			INSERT INTO applicant_documents (applicant_id, type, received_on, notes)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (applicant_id, type) DO UPDATE SET
+				received_on = EXCLUDED.received_on,
+				notes = EXCLUDED.notes,
+				updated_at = now()
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		_, err = tx.ExecContext(ctx, query, document.ApplicantID, document.Type, document.ReceivedOn, document.Notes)
+
+		return parsePgError(err)
+	})
+}
+
+// RemoveDocument marks a checklist document as not received.
+func (s *ApplicantStore) RemoveDocument(ctx context.Context, applicantID uuid.UUID, documentType string) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		applicant, err := s.lockApplicant(ctx, tx, applicantID)
+		if err != nil {
+			return err
+		}
+
+		if isApplicantClosed(applicant.Status) {
+			return ErrApplicantClosed
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		_, err = tx.ExecContext(
+			ctx,
+			`DELETE FROM applicant_documents WHERE applicant_id = $1 AND type = $2`,
+			applicantID,
+			documentType,
+		)
+
+		return err
+	})
+}
+
+// Convert enrolls an accepted applicant as a new student in the school year
+// and grade level they applied for. The student and enrollment are created
+// exactly as EnrollmentStore.Create does, in the same transaction that closes
+// the applicant.
+func (s *ApplicantStore) Convert(ctx context.Context, applicantID uuid.UUID, enrollment *models.Enrollment) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		applicant, err := s.lockApplicant(ctx, tx, applicantID)
+		if err != nil {
+			return err
+		}
+
+		if isApplicantClosed(applicant.Status) {
+			return ErrApplicantClosed
+		}
+
+		if applicant.Status != constants.Accepted {
+			return ErrNotAccepted
+		}
+
+		if len(applicant.MissingDocuments) > 0 {
+			return ErrDocumentsMissing
+		}
+
+		student := *applicant.Student
+		student.ID = uuid.Nil
+
+		enrollment.Student = &student
+		enrollment.SchoolYear = applicant.SchoolYear
+		enrollment.GradeLevel = applicant.GradeLevel
+		enrollment.Type = "new"
+
+		enrollments := &EnrollmentStore{s.db}
+		if err := enrollments.create(ctx, tx, enrollment); err != nil {
+			return err
+		}
+
+		return s.updateStatus(ctx, tx, applicantID, constants.Converted, &enrollment.ID)
+	})
+}
+
+// lockApplicant reads an applicant and locks it for the rest of the
+// transaction.
+func (s *ApplicantStore) lockApplicant(ctx context.Context, tx *sql.Tx, id uuid.UUID) (models.Applicant, error) {
+	query := applicantSelect + `WHERE a.id = $2 FOR UPDATE OF a`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var applicant models.Applicant
+	err := scanApplicant(tx.QueryRowContext(ctx, query, pq.Array(requiredDocuments), id), &applicant)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return applicant, ErrNotFound
+		}
+		return applicant, err
+	}
+
+	return applicant, nil
+}
+
+func (s *ApplicantStore) updateStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID, status string, enrollmentID *uuid.UUID) error {
+	query := `
+		UPDATE applicants
+		SET
+			status = $1,
+			enrollment_id = $2,
+			status_changed_at = now(),
+			updated_at = now()
+		WHERE id = $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, status, enrollmentID, id)
+
+	return parsePgError(err)
+}
+
+func isApplicantClosed(status string) bool {
+	return status == constants.Rejected || status == constants.Converted
+}
+
+func scanApplicant(row rowScanner, applicant *models.Applicant) error {
+	var studentID uuid.NullUUID
+	student := &models.Student{}
+
+	err := row.Scan(
+		&applicant.ID,
+		&applicant.SchoolYear,
+		&applicant.GradeLevel,
+		&applicant.Status,
+		&studentID,
+		&student.FirstName,
+		&student.MiddleName,
+		&student.LastName,
+		&student.Suffix,
+		&student.FullName,
+		&student.Gender,
+		&student.Birthdate,
+		&student.Address,
+		&student.MotherName,
+		&student.MotherJob,
+		&student.MotherEducation,
+		&student.FatherName,
+		&student.FatherJob,
+		&student.FatherEducation,
+		&student.LivingWith,
+		pq.Array(&student.ContactNumbers),
+		&applicant.Notes,
+		&applicant.EnrollmentID,
+		pq.Array(&applicant.MissingDocuments),
+		&applicant.StatusChangedAt,
+		&applicant.CreatedAt,
+		&applicant.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Once converted, the applicant points at the student record made from it.
+	student.ID = studentID.UUID
+	applicant.Student = student
+
+	return nil
+}
@@ -0,0 +1,135 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+)
+
+func createTestApplicant(t *testing.T, s Storage, firstName string) *models.Applicant {
+	t.Helper()
+
+	applicant := &models.Applicant{
+		SchoolYear: testSchoolYear,
+		GradeLevel: "grade-1",
+		Student:    newTestStudent(firstName),
+	}
+	if err := s.Applicants.Create(context.Background(), applicant); err != nil {
+		t.Fatalf("creating applicant: %v", err)
+	}
+
+	return applicant
+}
+
+func TestApplicantStorePipeline(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	maria := createTestApplicant(t, s, "Maria")
+	jose := createTestApplicant(t, s, "Jose")
+
+	if maria.Status != constants.Inquiry || len(maria.MissingDocuments) != 2 {
+		t.Fatalf("applicant = %+v, want an inquiry missing both documents", maria)
+	}
+
+	assertErr(t, s.Applicants.SetStatus(ctx, maria.ID, constants.Accepted), ErrInvalidTransition)
+
+	for _, status := range []string{constants.Applied, constants.Assessed} {
+		if err := s.Applicants.SetStatus(ctx, maria.ID, status); err != nil {
+			t.Fatalf("SetStatus %s: %v", status, err)
+		}
+	}
+	assertErr(t, s.Applicants.SetStatus(ctx, maria.ID, constants.Accepted), ErrDocumentsMissing)
+
+	receivedOn := time.Date(2025, time.May, 5, 0, 0, 0, 0, time.UTC)
+	for _, documentType := range []string{constants.BirthCertificate, constants.ReportCard} {
+		document := &models.ApplicantDocument{ApplicantID: maria.ID, Type: documentType, ReceivedOn: &receivedOn}
+		if err := s.Applicants.SetDocument(ctx, document); err != nil {
+			t.Fatalf("SetDocument %s: %v", documentType, err)
+		}
+	}
+
+	applicant, err := s.Applicants.GetByID(ctx, maria.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if len(applicant.MissingDocuments) != 0 || len(applicant.Documents) != 2 || applicant.Documents[0].ReceivedOn == nil {
+		t.Errorf("applicant = %+v, want a complete checklist", applicant)
+	}
+
+	if err := s.Applicants.SetStatus(ctx, maria.ID, constants.Accepted); err != nil {
+		t.Fatalf("SetStatus accepted: %v", err)
+	}
+
+	if err := s.Applicants.SetStatus(ctx, jose.ID, constants.Rejected); err != nil {
+		t.Fatalf("SetStatus rejected: %v", err)
+	}
+	assertErr(t, s.Applicants.SetStatus(ctx, jose.ID, constants.Applied), ErrApplicantClosed)
+	assertErr(t, s.Applicants.Convert(ctx, jose.ID, newTestEnrollment(nil)), ErrApplicantClosed)
+
+	pipeline, err := s.Applicants.GetPipeline(ctx, testSchoolYear)
+	if err != nil {
+		t.Fatalf("GetPipeline: %v", err)
+	}
+	counts := map[string]int{}
+	for _, group := range pipeline {
+		counts[group.Group] = group.Count
+	}
+	if len(pipeline) != 6 || counts[constants.Accepted] != 1 || counts[constants.Rejected] != 1 {
+		t.Errorf("pipeline = %+v, want one accepted and one rejected", pipeline)
+	}
+}
+
+func TestApplicantStoreConvert(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	maria := createTestApplicant(t, s, "Maria")
+	assertErr(t, s.Applicants.Convert(ctx, maria.ID, newTestEnrollment(nil)), ErrNotAccepted)
+
+	// Accept Maria directly; the transitions are covered above.
+	if _, err := testDB.Exec(`
+		INSERT INTO applicant_documents (applicant_id, type, received_on)
+		VALUES ($1, 'birth_certificate', CURRENT_DATE), ($1, 'report_card', CURRENT_DATE)
+	`, maria.ID); err != nil {
+		t.Fatalf("inserting documents: %v", err)
+	}
+	if _, err := testDB.Exec(`UPDATE applicants SET status = 'accepted' WHERE id = $1`, maria.ID); err != nil {
+		t.Fatalf("accepting applicant: %v", err)
+	}
+
+	enrollment := newTestEnrollment(nil)
+	if err := s.Applicants.Convert(ctx, maria.ID, enrollment); err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+
+	details, err := s.Enrollments.GetEnrollmentByID(ctx, enrollment.ID)
+	if err != nil {
+		t.Fatalf("GetEnrollmentByID: %v", err)
+	}
+	if details.Student.FirstName != "Maria" || details.Status != constants.Enrolled {
+		t.Errorf("enrollment = %+v, want Maria enrolled", details)
+	}
+	assertDecimal(t, "tuition receivable", accountBalance(t, s, constants.AccountTuitionReceivable), 12500)
+
+	applicant, err := s.Applicants.GetByID(ctx, maria.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if applicant.Status != constants.Converted || applicant.EnrollmentID == nil || *applicant.EnrollmentID != enrollment.ID {
+		t.Errorf("applicant = %+v, want converted into %s", applicant, enrollment.ID)
+	}
+	if applicant.Student.ID != details.Student.ID {
+		t.Errorf("student id = %s, want %s", applicant.Student.ID, details.Student.ID)
+	}
+
+	assertErr(t, s.Applicants.Convert(ctx, maria.ID, newTestEnrollment(nil)), ErrApplicantClosed)
+	if got := countRows(t, `SELECT COUNT(*) FROM students`); got != 1 {
+		t.Errorf("students = %d, want 1", got)
+	}
+}
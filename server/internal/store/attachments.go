@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+)
+
+const attachmentColumns = `
+	id, student_id, enrollment_id, payment_id, storage_key, file_name,
+	content_type, size_bytes, checksum_sha256, description, created_at
+`
+
+type AttachmentStore struct {
+	db *sql.DB
+}
+
+// Create records a file already saved to the file store. The owning student,
+// enrollment or payment must exist and not be deleted.
+func (s *AttachmentStore) Create(ctx context.Context, attachment *models.Attachment) error {
+	query := `
+		INSERT INTO attachments
+			(id, student_id, enrollment_id, payment_id, storage_key, file_name,
+			content_type, size_bytes, checksum_sha256, description)
+		SELECT $1::uuid, $2::uuid, $3::uuid, $4::uuid, $5::text, $6::text, $7::text, $8::bigint, $9::text, $10::text
+		WHERE ($2::uuid IS NULL OR EXISTS (SELECT 1 FROM students WHERE id = $2::uuid AND deleted_at IS NULL))
+			AND ($3::uuid IS NULL OR EXISTS (SELECT 1 FROM enrollments WHERE id = $3::uuid AND deleted_at IS NULL))
+			AND ($4::uuid IS NULL OR EXISTS (SELECT 1 FROM tuition_payments WHERE id = $4::uuid AND deleted_at IS NULL))
+		RETURNING created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	if attachment.ID == uuid.Nil {
+		attachment.ID = uuid.New()
+	}
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		attachment.ID,
+		attachment.StudentID,
+		attachment.EnrollmentID,
+		attachment.PaymentID,
+		attachment.StorageKey,
+		attachment.FileName,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.Checksum,
+		attachment.Description,
+	).Scan(&attachment.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return parsePgError(err)
+	}
+
+	return nil
+}
+
+func (s *AttachmentStore) GetByID(ctx context.Context, id uuid.UUID) (models.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var attachment models.Attachment
+	err := scanAttachment(s.db.QueryRowContext(ctx, query, id), &attachment)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return attachment, ErrNotFound
+		}
+		return attachment, err
+	}
+
+	return attachment, nil
+}
+
+// GetAll lists the attachments of a student, enrollment or payment, oldest
+// first.
+func (s *AttachmentStore) GetAll(ctx context.Context, owner models.AttachmentOwner) ([]models.Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE ($1::uuid IS NULL OR student_id = $1)
+			AND ($2::uuid IS NULL OR enrollment_id = $2)
+			AND ($3::uuid IS NULL OR payment_id = $3)
+		ORDER BY created_at, id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, owner.StudentID, owner.EnrollmentID, owner.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var attachments []models.Attachment
+
+	for rows.Next() {
+		var attachment models.Attachment
+		if err := scanAttachment(rows, &attachment); err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+// Delete removes an attachment's record and returns it, so the caller can
+// remove the file from the file store.
+func (s *AttachmentStore) Delete(ctx context.Context, id uuid.UUID) (models.Attachment, error) {
+	query := `DELETE FROM attachments WHERE id = $1 RETURNING ` + attachmentColumns
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var attachment models.Attachment
+	err := scanAttachment(s.db.QueryRowContext(ctx, query, id), &attachment)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return attachment, ErrNotFound
+		}
+		return attachment, err
+	}
+
+	return attachment, nil
+}
+
+func scanAttachment(row rowScanner, attachment *models.Attachment) error {
+	return row.Scan(
+		&attachment.ID,
+		&attachment.StudentID,
+		&attachment.EnrollmentID,
+		&attachment.PaymentID,
+		&attachment.StorageKey,
+		&attachment.FileName,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.Checksum,
+		&attachment.Description,
+		&attachment.CreatedAt,
+	)
+}
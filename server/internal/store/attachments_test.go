@@ -0,0 +1,77 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+)
+
+func newTestAttachment(owner models.AttachmentOwner, fileName string) *models.Attachment {
+	id := uuid.New()
+
+	return &models.Attachment{
+		ID:           id,
+		StudentID:    owner.StudentID,
+		EnrollmentID: owner.EnrollmentID,
+		PaymentID:    owner.PaymentID,
+		StorageKey:   "attachments/" + id.String(),
+		FileName:     fileName,
+		ContentType:  "application/pdf",
+		Size:         1024,
+		Checksum:     strings.Repeat("ab", 32),
+	}
+}
+
+func TestAttachmentStore(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	enrollment := createTestEnrollment(t, s, "Maria")
+	payment := createTestPayment(t, s, enrollment.ID, "INV-001", 1000)
+
+	byStudent := models.AttachmentOwner{StudentID: &enrollment.Student.ID}
+	byPayment := models.AttachmentOwner{PaymentID: &payment.ID}
+
+	birthCertificate := newTestAttachment(byStudent, "birth-certificate.pdf")
+	if err := s.Attachments.Create(ctx, birthCertificate); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	receipt := newTestAttachment(byPayment, "gcash.png")
+	if err := s.Attachments.Create(ctx, receipt); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	missing := uuid.New()
+	assertErr(t, s.Attachments.Create(ctx, newTestAttachment(models.AttachmentOwner{EnrollmentID: &missing}, "x.pdf")), ErrNotFound)
+
+	attachments, err := s.Attachments.GetAll(ctx, byStudent)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(attachments) != 1 || attachments[0].ID != birthCertificate.ID || attachments[0].Checksum != birthCertificate.Checksum {
+		t.Errorf("attachments = %+v, want the birth certificate", attachments)
+	}
+
+	deleted, err := s.Attachments.Delete(ctx, receipt.ID)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if deleted.StorageKey != receipt.StorageKey {
+		t.Errorf("deleted key = %q, want %q", deleted.StorageKey, receipt.StorageKey)
+	}
+
+	_, err = s.Attachments.GetByID(ctx, receipt.ID)
+	assertErr(t, err, ErrNotFound)
+
+	// Files cannot be attached to deleted records.
+	if err := s.Enrollments.Delete(ctx, enrollment.ID); err != nil {
+		t.Fatalf("deleting enrollment: %v", err)
+	}
+	assertErr(t, s.Attachments.Create(ctx, newTestAttachment(models.AttachmentOwner{EnrollmentID: &enrollment.ID}, "x.pdf")), ErrNotFound)
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+// BalanceStore maintains enrollment_balances, the read model of the
+// enrollment_totals view that triggers keep current on every write.
+type BalanceStore struct {
+	db *sql.DB
+}
+
+// Rebuild replaces every stored balance with one recomputed from the view and
+// returns the number of balances written. Writes that would refresh a balance
+// wait until the rebuild commits.
+func (s *BalanceStore) Rebuild(ctx context.Context) (int64, error) {
+	var rebuilt int64
+
+	err := withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE enrollment_balances IN EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM enrollment_balances`); err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO enrollment_balances
+				(enrollment_id, discount_types, total_amount, total_paid, remaining_amount, payment_status)
+			SELECT enrollment_id, discount_types, total_amount, total_paid, remaining_amount, payment_status
+			FROM enrollment_totals
+		`)
+		if err != nil {
+			return err
+		}
+
+		rebuilt, err = res.RowsAffected()
+		return err
+	})
+
+	return rebuilt, err
+}
+
+// CheckDrift compares the stored balances against the view and returns every
+// enrollment where they disagree, including missing and orphaned rows.
+func (s *BalanceStore) CheckDrift(ctx context.Context) ([]models.BalanceDrift, error) {
+	query := `
+		SELECT
+			COALESCE(b.enrollment_id, t.enrollment_id),
+			b.discount_types, b.total_amount, b.total_paid, b.remaining_amount, b.payment_status,
+			t.discount_types, t.total_amount, t.total_paid, t.remaining_amount, t.payment_status
+		FROM enrollment_balances b
+		FULL JOIN enrollment_totals t ON t.enrollment_id = b.enrollment_id
+		WHERE (b.discount_types, b.total_amount, b.total_paid, b.remaining_amount, b.payment_status)
+			IS DISTINCT FROM (t.discount_types, t.total_amount, t.total_paid, t.remaining_amount, t.payment_status)
+		ORDER BY 1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var drifts []models.BalanceDrift
+
+	for rows.Next() {
+		var (
+			id               uuid.UUID
+			stored, expected nullBalance
+		)
+
+		err := rows.Scan(
+			&id,
+			&stored.discountTypes, &stored.totalAmount, &stored.totalPaid, &stored.remainingAmount, &stored.paymentStatus,
+			&expected.discountTypes, &expected.totalAmount, &expected.totalPaid, &expected.remainingAmount, &expected.paymentStatus,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		drifts = append(drifts, models.BalanceDrift{
+			EnrollmentID: id,
+			Stored:       stored.balance(id),
+			Expected:     expected.balance(id),
+		})
+	}
+
+	return drifts, rows.Err()
+}
+
+// nullBalance scans one side of the drift check's full join, which is all
+// NULL when the balance has no counterpart on that side.
+type nullBalance struct {
+	discountTypes   pq.StringArray
+	totalAmount     decimal.NullDecimal
+	totalPaid       decimal.NullDecimal
+	remainingAmount decimal.NullDecimal
+	paymentStatus   sql.NullString
+}
+
+func (n nullBalance) balance(id uuid.UUID) *models.EnrollmentBalance {
+	if !n.paymentStatus.Valid {
+		return nil
+	}
+
+	return &models.EnrollmentBalance{
+		EnrollmentID:    id,
+		DiscountTypes:   n.discountTypes,
+		TotalAmount:     n.totalAmount.Decimal,
+		TotalPaid:       n.totalPaid.Decimal,
+		RemainingAmount: n.remainingAmount.Decimal,
+		PaymentStatus:   n.paymentStatus.String,
+	}
+}
@@ -0,0 +1,123 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/shopspring/decimal"
+)
+
+func TestEnrollmentBalancesFollowWrites(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	enrollment := createTestEnrollment(t, s, "Maria", constants.Sibling)
+	payment := createTestPayment(t, s, enrollment.ID, "OR-0001", 2000)
+
+	var total, paid decimal.Decimal
+	var status string
+	readBalance := func(t *testing.T) {
+		t.Helper()
+
+		err := testDB.QueryRow(`
+			SELECT total_amount, total_paid, payment_status
+			FROM enrollment_balances WHERE enrollment_id = $1
+		`, enrollment.ID).Scan(&total, &paid, &status)
+		if err != nil {
+			t.Fatalf("reading balance: %v", err)
+		}
+	}
+
+	readBalance(t)
+	assertDecimal(t, "total amount", total, 11500)
+	assertDecimal(t, "total paid", paid, 2000)
+	if status != "partial" {
+		t.Errorf("payment status = %q, want partial", status)
+	}
+
+	payment.TuitionFee = decimal.NewFromInt(11500)
+	if err := s.Payments.Update(ctx, payment); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	readBalance(t)
+	assertDecimal(t, "total paid", paid, 11500)
+	if status != "paid" {
+		t.Errorf("payment status = %q, want paid", status)
+	}
+
+	if err := s.Enrollments.Delete(ctx, enrollment.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if n := countRows(t, `SELECT COUNT(*) FROM enrollment_balances`); n != 0 {
+		t.Errorf("%d balances left after deleting the enrollment, want 0", n)
+	}
+}
+
+func TestBalanceStoreCheckDriftAndRebuild(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	maria := createTestEnrollment(t, s, "Maria")
+	jose := createTestEnrollment(t, s, "Jose")
+	createTestEnrollment(t, s, "Ana")
+
+	drifts, err := s.Balances.CheckDrift(ctx)
+	if err != nil {
+		t.Fatalf("CheckDrift: %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Fatalf("CheckDrift = %+v, want no drift", drifts)
+	}
+
+	if _, err := testDB.Exec(`UPDATE enrollment_balances SET total_paid = 999 WHERE enrollment_id = $1`, maria.ID); err != nil {
+		t.Fatalf("corrupting balance: %v", err)
+	}
+	if _, err := testDB.Exec(`DELETE FROM enrollment_balances WHERE enrollment_id = $1`, jose.ID); err != nil {
+		t.Fatalf("deleting balance: %v", err)
+	}
+
+	drifts, err = s.Balances.CheckDrift(ctx)
+	if err != nil {
+		t.Fatalf("CheckDrift: %v", err)
+	}
+	if len(drifts) != 2 {
+		t.Fatalf("CheckDrift = %+v, want 2 drifted enrollments", drifts)
+	}
+	for _, drift := range drifts {
+		switch drift.EnrollmentID {
+		case maria.ID:
+			if drift.Stored == nil || drift.Expected == nil {
+				t.Fatalf("drift for Maria = %+v, want both sides", drift)
+			}
+			assertDecimal(t, "stored total paid", drift.Stored.TotalPaid, 999)
+			assertDecimal(t, "expected total paid", drift.Expected.TotalPaid, 0)
+		case jose.ID:
+			if drift.Stored != nil || drift.Expected == nil {
+				t.Errorf("drift for Jose = %+v, want a missing balance", drift)
+			}
+		default:
+			t.Errorf("unexpected drift %+v", drift)
+		}
+	}
+
+	rebuilt, err := s.Balances.Rebuild(ctx)
+	if err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	if rebuilt != 3 {
+		t.Errorf("Rebuild wrote %d balances, want 3", rebuilt)
+	}
+
+	drifts, err = s.Balances.CheckDrift(ctx)
+	if err != nil {
+		t.Fatalf("CheckDrift: %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("CheckDrift after Rebuild = %+v, want no drift", drifts)
+	}
+}
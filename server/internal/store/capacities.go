@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// unlimitedSeats is returned by gradeSeatsLeft for grades without a capacity.
+const unlimitedSeats = -1
+
+type CapacityStore struct {
+	db *sql.DB
+}
+
+func (s *CapacityStore) Set(ctx context.Context, capacity *models.GradeCapacity) error {
+	query := `
+		INSERT INTO grade_capacities (school_year, grade_level, capacity)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (school_year, grade_level) DO UPDATE SET
+			capacity = EXCLUDED.capacity,
+			updated_at = now()
+		RETURNING updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		capacity.SchoolYear,
+		capacity.GradeLevel,
+		capacity.Capacity,
+	).Scan(
+		&capacity.UpdatedAt,
+	)
+	if err != nil {
+		return parsePgError(err)
+	}
+
+	return nil
+}
+
+// GetUtilization reports every grade level of a school year that has a
+// capacity, any enrollments or any open reservations.
+func (s *CapacityStore) GetUtilization(ctx context.Context, schoolYear string) ([]models.GradeUtilization, error) {
+	query := `
+		WITH counts AS (
+			SELECT
+				grade_level,
+				COUNT(*) FILTER (WHERE status = $2) AS enrolled,
+				COUNT(*) FILTER (WHERE status = $3) AS waitlisted,
+				0 AS reserved
+			FROM enrollments
+			WHERE deleted_at IS NULL AND school_year = $1
+			GROUP BY grade_level
+			UNION ALL
+			SELECT grade_level, 0, 0, COUNT(*)
+			FROM reservations
+			WHERE school_year = $1 AND status = $4 AND expires_on >= CURRENT_DATE
+			GROUP BY grade_level
+		), totals AS (
+			SELECT grade_level, SUM(enrolled) AS enrolled, SUM(waitlisted) AS waitlisted, SUM(reserved) AS reserved
+			FROM counts
+			GROUP BY grade_level
+		)
+		SELECT
+			COALESCE(c.grade_level, gc.grade_level),
+			gc.capacity,
+			COALESCE(c.enrolled, 0),
+			COALESCE(c.waitlisted, 0),
+			COALESCE(c.reserved, 0)
+		FROM totals c
+		FULL JOIN (
+			SELECT grade_level, capacity FROM grade_capacities WHERE school_year = $1
+		) gc ON gc.grade_level = c.grade_level
+		ORDER BY 1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, schoolYear, constants.Enrolled, constants.Waitlisted, constants.Reserved)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var report []models.GradeUtilization
+
+	for rows.Next() {
+		var (
+			grade    models.GradeUtilization
+			capacity sql.NullInt64
+		)
+
+		if err := rows.Scan(&grade.GradeLevel, &capacity, &grade.Enrolled, &grade.Waitlisted, &grade.Reserved); err != nil {
+			return nil, err
+		}
+
+		if capacity.Valid {
+			seats := int(capacity.Int64)
+			available := max(seats-grade.Enrolled-grade.Reserved, 0)
+			utilization := decimal.Zero
+			if seats > 0 {
+				utilization = decimal.NewFromInt(int64(grade.Enrolled * 100)).Div(decimal.NewFromInt(int64(seats))).Round(2)
+			}
+
+			grade.Capacity = &seats
+			grade.Available = &available
+			grade.Utilization = &utilization
+		}
+
+		report = append(report, grade)
+	}
+
+	return report, rows.Err()
+}
+
+// gradeSeatsLeft returns how many students can still be enrolled in or
+// reserve a seat in a grade level, or unlimitedSeats if it has no capacity.
+// The capacity row stays locked until the transaction ends, so concurrent
+// enrollments into the same grade are counted one after another.
+func gradeSeatsLeft(ctx context.Context, tx *sql.Tx, schoolYear, gradeLevel string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var capacity int
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT capacity FROM grade_capacities WHERE school_year = $1 AND grade_level = $2 FOR UPDATE`,
+		schoolYear,
+		gradeLevel,
+	).Scan(&capacity)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return unlimitedSeats, nil
+		}
+		return 0, err
+	}
+
+	// Open reservations hold their seats until they lapse.
+	var taken int
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT
+			(SELECT COUNT(*) FROM enrollments
+			WHERE school_year = $1 AND grade_level = $2 AND status = $3 AND deleted_at IS NULL) +
+			(SELECT COUNT(*) FROM reservations
+			WHERE school_year = $1 AND grade_level = $2 AND status = $4 AND expires_on >= CURRENT_DATE)`,
+		schoolYear,
+		gradeLevel,
+		constants.Enrolled,
+		constants.Reserved,
+	).Scan(&taken)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(capacity-taken, 0), nil
+}
@@ -0,0 +1,204 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+)
+
+func setTestCapacity(t *testing.T, s Storage, gradeLevel string, capacity int) {
+	t.Helper()
+
+	err := s.Capacities.Set(context.Background(), &models.GradeCapacity{
+		SchoolYear: testSchoolYear,
+		GradeLevel: gradeLevel,
+		Capacity:   capacity,
+	})
+	if err != nil {
+		t.Fatalf("setting capacity: %v", err)
+	}
+}
+
+func createTestWaitlisted(t *testing.T, s Storage, firstName string) *models.Enrollment {
+	t.Helper()
+
+	enrollment := newTestEnrollment(newTestStudent(firstName))
+	enrollment.AllowWaitlist = true
+	if err := s.Enrollments.Create(context.Background(), enrollment); err != nil {
+		t.Fatalf("creating enrollment: %v", err)
+	}
+	if enrollment.Status != constants.Waitlisted {
+		t.Fatalf("status = %q, want waitlisted", enrollment.Status)
+	}
+
+	return enrollment
+}
+
+func TestEnrollmentStoreCapacity(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	setTestCapacity(t, s, "grade-1", 1)
+
+	maria := createTestEnrollment(t, s, "Maria")
+	if maria.Status != constants.Enrolled {
+		t.Errorf("status = %q, want enrolled", maria.Status)
+	}
+
+	assertErr(t, s.Enrollments.Create(ctx, newTestEnrollment(newTestStudent("Jose"))), ErrGradeFull)
+	if n := countRows(t, `SELECT COUNT(*) FROM students`); n != 1 {
+		t.Errorf("%d students after a rejected enrollment, want 1", n)
+	}
+
+	// Waitlisted students are not billed.
+	jose := createTestWaitlisted(t, s, "Jose")
+	assertDecimal(t, "tuition receivable", accountBalance(t, s, constants.AccountTuitionReceivable), 12500)
+
+	details, err := s.Enrollments.GetEnrollmentByID(ctx, jose.ID)
+	if err != nil {
+		t.Fatalf("GetEnrollmentByID: %v", err)
+	}
+	if details.Status != constants.Waitlisted {
+		t.Errorf("status = %q, want waitlisted", details.Status)
+	}
+	assertDecimal(t, "total amount", details.TotalAmount, 0)
+
+	withdrawal := &models.Withdrawal{EnrollmentID: jose.ID, WithdrawalDate: time.Date(2025, time.August, 15, 0, 0, 0, 0, time.UTC)}
+	assertErr(t, s.Enrollments.Withdraw(ctx, withdrawal), ErrWaitlisted)
+
+	t.Run("moving into a full grade", func(t *testing.T) {
+		pedro := newTestEnrollment(newTestStudent("Pedro"))
+		pedro.GradeLevel = "grade-2"
+		if err := s.Enrollments.Create(ctx, pedro); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		update := newTestEnrollment(pedro.Student)
+		update.Version = 1
+		assertErr(t, s.Enrollments.Update(ctx, update, pedro.ID), ErrGradeFull)
+	})
+
+	t.Run("utilization", func(t *testing.T) {
+		report, err := s.Capacities.GetUtilization(ctx, testSchoolYear)
+		if err != nil {
+			t.Fatalf("GetUtilization: %v", err)
+		}
+		if len(report) != 2 {
+			t.Fatalf("GetUtilization = %+v, want grade-1 and grade-2", report)
+		}
+
+		grade1, grade2 := report[0], report[1]
+		if grade1.GradeLevel != "grade-1" || *grade1.Capacity != 1 || grade1.Enrolled != 1 ||
+			grade1.Waitlisted != 1 || *grade1.Available != 0 {
+			t.Errorf("grade-1 = %+v", grade1)
+		}
+		assertDecimal(t, "grade-1 utilization", *grade1.Utilization, 100)
+
+		if grade2.GradeLevel != "grade-2" || grade2.Capacity != nil || grade2.Enrolled != 1 {
+			t.Errorf("grade-2 = %+v, want one enrolled without a capacity", grade2)
+		}
+	})
+}
+
+func TestEnrollmentStoreWaitlistPromotion(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	setTestCapacity(t, s, "grade-1", 1)
+
+	maria := createTestEnrollment(t, s, "Maria")
+	jose := createTestWaitlisted(t, s, "Jose")
+	ana := createTestWaitlisted(t, s, "Ana")
+
+	waitlist, err := s.Enrollments.GetWaitlist(ctx, testSchoolYear, "")
+	if err != nil {
+		t.Fatalf("GetWaitlist: %v", err)
+	}
+	if len(waitlist) != 2 || waitlist[0].EnrollmentID != jose.ID || waitlist[0].Position != 1 ||
+		waitlist[1].EnrollmentID != ana.ID || waitlist[1].Position != 2 {
+		t.Fatalf("GetWaitlist = %+v, want Jose then Ana", waitlist)
+	}
+
+	assertErr(t, s.Enrollments.Promote(ctx, jose.ID), ErrGradeFull)
+	assertErr(t, s.Enrollments.Promote(ctx, maria.ID), ErrNotWaitlisted)
+	assertErr(t, s.Enrollments.Promote(ctx, uuid.New()), ErrNotFound)
+
+	promoted, err := s.Enrollments.PromoteWaitlist(ctx, testSchoolYear, "grade-1")
+	if err != nil || len(promoted) != 0 {
+		t.Fatalf("PromoteWaitlist into a full grade = %v, %v, want none promoted", promoted, err)
+	}
+
+	setTestCapacity(t, s, "grade-1", 2)
+
+	promoted, err = s.Enrollments.PromoteWaitlist(ctx, testSchoolYear, "grade-1")
+	if err != nil {
+		t.Fatalf("PromoteWaitlist: %v", err)
+	}
+	if !slices.Equal(promoted, []uuid.UUID{jose.ID}) {
+		t.Fatalf("PromoteWaitlist = %v, want only Jose", promoted)
+	}
+
+	// Promotion bills the enrollment.
+	assertDecimal(t, "tuition receivable", accountBalance(t, s, constants.AccountTuitionReceivable), 25000)
+
+	details, err := s.Enrollments.GetEnrollmentByID(ctx, jose.ID)
+	if err != nil {
+		t.Fatalf("GetEnrollmentByID: %v", err)
+	}
+	if details.Status != constants.Enrolled || details.Version != 2 {
+		t.Errorf("status = %q, version = %d, want enrolled at version 2", details.Status, details.Version)
+	}
+	assertDecimal(t, "total amount", details.TotalAmount, 12500)
+
+	// Removing a capacity lets the rest of the waitlist in.
+	if _, err := testDB.Exec(`DELETE FROM grade_capacities`); err != nil {
+		t.Fatalf("removing capacity: %v", err)
+	}
+	if err := s.Enrollments.Promote(ctx, ana.ID); err != nil {
+		t.Fatalf("Promote: %v", err)
+	}
+
+	waitlist, err = s.Enrollments.GetWaitlist(ctx, testSchoolYear, "grade-1")
+	if err != nil {
+		t.Fatalf("GetWaitlist: %v", err)
+	}
+	if len(waitlist) != 0 {
+		t.Errorf("GetWaitlist = %+v, want it empty", waitlist)
+	}
+}
+
+func TestPaymentStoreWaitlisted(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	setTestCapacity(t, s, "grade-1", 1)
+	createTestEnrollment(t, s, "Maria")
+	jose := createTestWaitlisted(t, s, "Jose")
+
+	// A waitlisted enrollment is billed nothing, so a payment would leave it
+	// overpaid.
+	assertErr(t, s.Payments.Create(ctx, newTestPayment(jose.ID, "OR-0001", 1000)), ErrWaitlisted)
+
+	details, err := s.Enrollments.GetEnrollmentByID(ctx, jose.ID)
+	if err != nil {
+		t.Fatalf("GetEnrollmentByID: %v", err)
+	}
+	if details.PaymentStatus != "unpaid" {
+		t.Errorf("payment status = %q, want unpaid", details.PaymentStatus)
+	}
+	assertDecimal(t, "remaining amount", details.RemainingAmount, 0)
+
+	setTestCapacity(t, s, "grade-1", 2)
+	if err := s.Enrollments.Promote(ctx, jose.ID); err != nil {
+		t.Fatalf("Promote: %v", err)
+	}
+
+	createTestPayment(t, s, jose.ID, "OR-0001", 1000)
+}
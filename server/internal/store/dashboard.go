@@ -0,0 +1,298 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+// dashboardTopDebtors is how many of the largest balances the dashboard lists.
+const dashboardTopDebtors = 5
+
+// Bits of GROUPING(e.grade_level, e.type, s.gender) for each grouping set in
+// getEnrollmentCounts; a bit is set for every column not grouped on.
+const (
+	groupedByGradeLevel = 0b011
+	groupedByType       = 0b101
+	groupedByGender     = 0b110
+	groupedByNone       = 0b111
+)
+
+var paymentMethods = []string{constants.Cash, constants.GCash, constants.Bank}
+
+type DashboardStore struct {
+	db *sql.DB
+}
+
+// Get summarizes a school year. Collections are reported for the day and the
+// month of today. The sections are read from one repeatable read snapshot so
+// they agree with each other while payments are being posted.
+func (s *DashboardStore) Get(ctx context.Context, schoolYear string, today time.Time) (models.Dashboard, error) {
+	dashboard := models.Dashboard{SchoolYear: schoolYear}
+
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+
+	err := withTxOptions(ctx, s.db, opts, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		if err := s.getEnrollmentCounts(ctx, tx, schoolYear, &dashboard.Enrollments); err != nil {
+			return err
+		}
+
+		if err := s.getCollections(ctx, tx, schoolYear, today, &dashboard.Collections); err != nil {
+			return err
+		}
+
+		debtors, err := s.getTopDebtors(ctx, tx, schoolYear)
+		if err != nil {
+			return err
+		}
+		dashboard.TopDebtors = debtors
+
+		discounts, err := s.getDiscountTotals(ctx, tx, schoolYear)
+		if err != nil {
+			return err
+		}
+		dashboard.Discounts = discounts
+
+		return nil
+	})
+
+	return dashboard, err
+}
+
+func (s *DashboardStore) getEnrollmentCounts(ctx context.Context, tx *sql.Tx, schoolYear string, counts *models.DashboardEnrollments) error {
+	query := `
+		SELECT
+			GROUPING(e.grade_level, e.type, s.gender),
+			COALESCE(e.grade_level, ''),
+			COALESCE(e.type, ''),
+			COALESCE(s.gender, ''),
+			COUNT(*) FILTER (WHERE e.status = $2),
+			COUNT(*) FILTER (WHERE e.status = $3),
+			COUNT(*) FILTER (WHERE e.status = $4)
+		FROM enrollments e
+		JOIN students s ON s.id = e.student_id
+		WHERE e.deleted_at IS NULL AND e.school_year = $1
+		GROUP BY GROUPING SETS ((e.grade_level), (e.type), (s.gender), ())
+		ORDER BY 1, 2, 3, 4
+	`
+
+	rows, err := tx.QueryContext(ctx, query, schoolYear, constants.Enrolled, constants.Withdrawn, constants.Waitlisted)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			grouping                           int
+			gradeLevel, enrollmentType, gender string
+			enrolled, withdrawn, waitlisted    int
+		)
+
+		if err := rows.Scan(&grouping, &gradeLevel, &enrollmentType, &gender, &enrolled, &withdrawn, &waitlisted); err != nil {
+			return err
+		}
+
+		if grouping == groupedByNone {
+			counts.Total = enrolled
+			counts.Withdrawn = withdrawn
+			counts.Waitlisted = waitlisted
+			continue
+		}
+
+		if enrolled == 0 {
+			continue
+		}
+
+		switch grouping {
+		case groupedByGradeLevel:
+			counts.ByGradeLevel = append(counts.ByGradeLevel, models.GroupCount{Group: gradeLevel, Count: enrolled})
+		case groupedByType:
+			counts.ByType = append(counts.ByType, models.GroupCount{Group: enrollmentType, Count: enrolled})
+		case groupedByGender:
+			counts.ByGender = append(counts.ByGender, models.GroupCount{Group: gender, Count: enrolled})
+		}
+	}
+
+	return rows.Err()
+}
+
+func (s *DashboardStore) getCollections(ctx context.Context, tx *sql.Tx, schoolYear string, today time.Time, collections *models.DashboardCollections) error {
+	query := `
+		SELECT
+			COALESCE(SUM(b.total_amount), 0),
+			COALESCE(SUM(b.total_paid), 0),
+			COALESCE(SUM(GREATEST(b.remaining_amount, 0)), 0)
+		FROM enrollment_balances b
+		JOIN enrollments e ON e.id = b.enrollment_id
+		WHERE e.deleted_at IS NULL AND e.school_year = $1
+	`
+
+	err := tx.QueryRowContext(ctx, query, schoolYear).Scan(
+		&collections.TotalBilled,
+		&collections.TotalCollected,
+		&collections.TotalOutstanding,
+	)
+	if err != nil {
+		return err
+	}
+
+	collections.CollectionRate = decimal.Zero
+	if collections.TotalBilled.IsPositive() {
+		collections.CollectionRate = collections.TotalCollected.
+			Div(collections.TotalBilled).
+			Mul(decimal.NewFromInt(100)).
+			Round(2)
+	}
+
+	// Every payment method is listed, with zero when nothing was collected.
+	// Reservation fees are counted when they are paid, not again when they
+	// are credited to an enrollment.
+	query = `
+		SELECT
+			m.method,
+			COALESCE(SUM(tp.amount) FILTER (WHERE tp.payment_date = $2::date), 0),
+			COALESCE(SUM(tp.amount) FILTER (
+				WHERE date_trunc('month', tp.payment_date) = date_trunc('month', $2::date)
+			), 0)
+		FROM unnest($3::text[]) WITH ORDINALITY AS m(method, position)
+		LEFT JOIN (
+			SELECT
+				tp.payment_method,
+				tp.payment_date,
+				COALESCE(tp.reservation_fee, 0) + COALESCE(tp.tuition_fee, 0) + COALESCE(tp.advance_payment, 0) AS amount
+			FROM tuition_payments tp
+			JOIN enrollments e ON e.id = tp.enrollment_id
+			WHERE tp.deleted_at IS NULL AND tp.reservation_id IS NULL
+				AND e.deleted_at IS NULL AND e.school_year = $1
+			UNION ALL
+			SELECT r.payment_method, r.reserved_on, r.fee
+			FROM reservations r
+			WHERE r.school_year = $1
+		) tp ON tp.payment_method = m.method
+		GROUP BY m.method, m.position
+		ORDER BY m.position
+	`
+
+	rows, err := tx.QueryContext(ctx, query, schoolYear, today, pq.Array(paymentMethods))
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var method string
+		var day, month decimal.Decimal
+
+		if err := rows.Scan(&method, &day, &month); err != nil {
+			return err
+		}
+
+		collections.Today = append(collections.Today, models.MethodTotal{PaymentMethod: method, Amount: day})
+		collections.ThisMonth = append(collections.ThisMonth, models.MethodTotal{PaymentMethod: method, Amount: month})
+	}
+
+	return rows.Err()
+}
+
+func (s *DashboardStore) getTopDebtors(ctx context.Context, tx *sql.Tx, schoolYear string) ([]models.Debtor, error) {
+	query := `
+		SELECT
+			e.id,
+			TRIM(CONCAT_WS(' ',
+				s.first_name,
+				CASE
+					WHEN s.middle_name IS NOT NULL AND s.middle_name <> ''
+					THEN LEFT(s.middle_name, 1) || '.'
+					ELSE NULL
+				END,
+				s.last_name,
+				s.suffix
+			)) AS full_name,
+			e.grade_level,
+			b.remaining_amount
+		FROM enrollment_balances b
+		JOIN enrollments e ON e.id = b.enrollment_id
+		JOIN students s ON s.id = e.student_id
+		WHERE e.deleted_at IS NULL AND e.school_year = $1 AND b.remaining_amount > 0
+		ORDER BY b.remaining_amount DESC, e.created_at
+		LIMIT $2
+	`
+
+	rows, err := tx.QueryContext(ctx, query, schoolYear, dashboardTopDebtors)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var debtors []models.Debtor
+
+	for rows.Next() {
+		var debtor models.Debtor
+		err := rows.Scan(
+			&debtor.EnrollmentID,
+			&debtor.FullName,
+			&debtor.GradeLevel,
+			&debtor.RemainingAmount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		debtors = append(debtors, debtor)
+	}
+
+	return debtors, rows.Err()
+}
+
+func (s *DashboardStore) getDiscountTotals(ctx context.Context, tx *sql.Tx, schoolYear string) ([]models.DiscountTotal, error) {
+	// Tuition discounts of withdrawn enrollments are prorated as they are
+	// billed.
+	query := `
+		SELECT
+			d.type,
+			COUNT(*),
+			SUM(
+				CASE WHEN d.scope = 'tuition'
+					THEN ROUND(COALESCE(d.amount, 0) * COALESCE(e.months_attended, e.months) / e.months, 2)
+					ELSE COALESCE(d.amount, 0)
+				END
+			)
+		FROM discounts d
+		JOIN enrollments e ON e.id = d.enrollment_id
+		WHERE d.deleted_at IS NULL AND e.deleted_at IS NULL AND e.school_year = $1
+		GROUP BY d.type
+		ORDER BY d.type
+	`
+
+	rows, err := tx.QueryContext(ctx, query, schoolYear)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var totals []models.DiscountTotal
+
+	for rows.Next() {
+		var total models.DiscountTotal
+		if err := rows.Scan(&total.Type, &total.Count, &total.Amount); err != nil {
+			return nil, err
+		}
+
+		totals = append(totals, total)
+	}
+
+	return totals, rows.Err()
+}
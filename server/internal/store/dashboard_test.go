@@ -0,0 +1,152 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestDashboardStore(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	maria := createTestEnrollment(t, s, "Maria", constants.Rank_1, constants.Sibling)
+	createTestPayment(t, s, maria.ID, "OR-0001", 2000)
+	gcash := newTestPayment(maria.ID, "OR-0002", 1000)
+	gcash.PaymentMethod = constants.GCash
+	gcash.PaymentDate = time.Date(2025, time.June, 2, 0, 0, 0, 0, time.UTC)
+	if err := s.Payments.Create(ctx, gcash); err != nil {
+		t.Fatalf("creating payment: %v", err)
+	}
+
+	joseStudent := newTestStudent("Jose")
+	joseStudent.Gender = "male"
+	jose := newTestEnrollment(joseStudent)
+	jose.GradeLevel = "grade-2"
+	jose.Type = "old"
+	if err := s.Enrollments.Create(ctx, jose); err != nil {
+		t.Fatalf("creating enrollment: %v", err)
+	}
+
+	ana := createTestEnrollment(t, s, "Ana", constants.Sibling)
+	withdrawal := &models.Withdrawal{
+		EnrollmentID:   ana.ID,
+		WithdrawalDate: time.Date(2025, time.August, 15, 0, 0, 0, 0, time.UTC),
+	}
+	if err := s.Enrollments.Withdraw(ctx, withdrawal); err != nil {
+		t.Fatalf("Withdraw: %v", err)
+	}
+
+	// Another school year is left out of every section.
+	previous := newTestEnrollment(newTestStudent("Pedro"), constants.Scholar)
+	previous.SchoolYear = "2024-2025"
+	if err := s.Enrollments.Create(ctx, previous); err != nil {
+		t.Fatalf("creating enrollment: %v", err)
+	}
+	createTestPayment(t, s, previous.ID, "OR-0003", 5000)
+
+	dashboard, err := s.Dashboard.Get(ctx, testSchoolYear, time.Date(2025, time.June, 16, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	t.Run("enrollments", func(t *testing.T) {
+		enrollments := dashboard.Enrollments
+		if enrollments.Total != 2 || enrollments.Withdrawn != 1 {
+			t.Errorf("total = %d, withdrawn = %d, want 2 and 1", enrollments.Total, enrollments.Withdrawn)
+		}
+
+		groups := []struct {
+			name string
+			got  []models.GroupCount
+			want []models.GroupCount
+		}{
+			{"grade level", enrollments.ByGradeLevel, []models.GroupCount{{Group: "grade-1", Count: 1}, {Group: "grade-2", Count: 1}}},
+			{"type", enrollments.ByType, []models.GroupCount{{Group: "new", Count: 1}, {Group: "old", Count: 1}}},
+			{"gender", enrollments.ByGender, []models.GroupCount{{Group: "female", Count: 1}, {Group: "male", Count: 1}}},
+		}
+		for _, group := range groups {
+			if !slices.Equal(group.got, group.want) {
+				t.Errorf("by %s = %+v, want %+v", group.name, group.got, group.want)
+			}
+		}
+	})
+
+	t.Run("collections", func(t *testing.T) {
+		collections := dashboard.Collections
+
+		// 10,500 for Maria, 12,500 for Jose and 5,200 for Ana after
+		// withdrawing.
+		assertDecimal(t, "total billed", collections.TotalBilled, 28200)
+		assertDecimal(t, "total collected", collections.TotalCollected, 3000)
+		assertDecimal(t, "total outstanding", collections.TotalOutstanding, 25200)
+		if !collections.CollectionRate.Equal(decimal.RequireFromString("10.64")) {
+			t.Errorf("collection rate = %s, want 10.64", collections.CollectionRate)
+		}
+
+		methodTotals := func(totals []models.MethodTotal) map[string]int64 {
+			amounts := make(map[string]int64)
+			for _, total := range totals {
+				amounts[total.PaymentMethod] = total.Amount.IntPart()
+			}
+			return amounts
+		}
+
+		wantToday := map[string]int64{constants.Cash: 2000, constants.GCash: 0, constants.Bank: 0}
+		if got := methodTotals(collections.Today); len(collections.Today) != 3 || !maps.Equal(got, wantToday) {
+			t.Errorf("today = %+v, want %v", collections.Today, wantToday)
+		}
+
+		wantMonth := map[string]int64{constants.Cash: 2000, constants.GCash: 1000, constants.Bank: 0}
+		if got := methodTotals(collections.ThisMonth); len(collections.ThisMonth) != 3 || !maps.Equal(got, wantMonth) {
+			t.Errorf("this month = %+v, want %v", collections.ThisMonth, wantMonth)
+		}
+	})
+
+	t.Run("top debtors", func(t *testing.T) {
+		want := []struct {
+			id        uuid.UUID
+			remaining int64
+		}{
+			{jose.ID, 12500},
+			{maria.ID, 7500},
+			{ana.ID, 5200},
+		}
+
+		if len(dashboard.TopDebtors) != len(want) {
+			t.Fatalf("top debtors = %+v, want %d", dashboard.TopDebtors, len(want))
+		}
+		for i, debtor := range dashboard.TopDebtors {
+			if debtor.EnrollmentID != want[i].id {
+				t.Errorf("debtor %d = %s, want %s", i, debtor.EnrollmentID, want[i].id)
+			}
+			assertDecimal(t, "remaining amount", debtor.RemainingAmount, want[i].remaining)
+		}
+	})
+
+	t.Run("discounts", func(t *testing.T) {
+		// Ana's sibling discount is prorated to the three months attended.
+		if len(dashboard.Discounts) != 2 {
+			t.Fatalf("discounts = %+v, want rank_1 and sibling", dashboard.Discounts)
+		}
+
+		rank, sibling := dashboard.Discounts[0], dashboard.Discounts[1]
+		if rank.Type != constants.Rank_1 || rank.Count != 1 {
+			t.Errorf("discounts[0] = %+v, want one rank_1", rank)
+		}
+		assertDecimal(t, "rank 1 discounts", rank.Amount, 1000)
+		if sibling.Type != constants.Sibling || sibling.Count != 2 {
+			t.Errorf("discounts[1] = %+v, want two sibling", sibling)
+		}
+		assertDecimal(t, "sibling discounts", sibling.Amount, 1300)
+	})
+}
@@ -3,11 +3,15 @@ package store
 import (
 	"context"
 	"database/sql"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/edzhabs/bookkeeping/internal/constants"
 	"github.com/edzhabs/bookkeeping/internal/models"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
 )
 
 var total_school_months = 10
@@ -49,32 +53,21 @@ func (s *EnrollmentStore) GetEnrollmentByID(ctx context.Context, id uuid.UUID) (
 	  e.type,
 	  e.grade_level,
 	  e.school_year,
-	  COALESCE(array_agg(DISTINCT d.type) FILTER (WHERE d.type IS NOT NULL), ARRAY[]::text[]) AS discount_types,
-      (e.monthly_tuition * e.months + e.enrollment_fee + e.misc_fee + e.pta_fee + e.lms_books_fee
-         - COALESCE(SUM(d.amount), 0)) AS total_amount,
-	  COALESCE(SUM(tp.reservation_fee + tp.tuition_fee + tp.advance_payment), 0) AS total_paid,
-	  (
-	 	(e.monthly_tuition * e.months + e.enrollment_fee + e.misc_fee + e.pta_fee + e.lms_books_fee)
-		- COALESCE(SUM(d.amount), 0)
-		- COALESCE(SUM(tp.reservation_fee + tp.tuition_fee + tp.advance_payment), 0) 
-	  ) AS remaining_amount,
-	  CASE
-	  	WHEN COALESCE(SUM(tp.reservation_fee + tp.tuition_fee + tp.advance_payment), 0) = 0
-			THEN 'unpaid'
-		WHEN COALESCE(SUM(tp.reservation_fee + tp.tuition_fee + tp.advance_payment), 0) >=
-			   (e.monthly_tuition * e.months + e.enrollment_fee + e.misc_fee + e.pta_fee + e.lms_books_fee - COALESCE(SUM(d.amount), 0))
-			THEN 'paid'
-		ELSE 'partial'
-	  END AS payment_status
+	  e.status,
+	  e.withdrawal_date,
+	  e.section_id,
+	  sec.name,
+	  e.version,
+	  b.discount_types,
+	  b.total_amount,
+	  b.total_paid,
+	  b.remaining_amount,
+	  b.payment_status
     FROM enrollments e
-    LEFT JOIN discounts d ON d.enrollment_id = e.id AND d.deleted_at IS NULL
-	LEFT JOIN tuition_payments tp ON tp.enrollment_id = e.id AND tp.deleted_at IS NULL
+    JOIN enrollment_balances b ON b.enrollment_id = e.id
     LEFT JOIN students s ON s.id = e.student_id AND s.deleted_at IS NULL
+    LEFT JOIN sections sec ON sec.id = e.section_id
     WHERE e.deleted_at IS NULL AND e.id = $1
-	GROUP BY e.id, s.id, s.first_name, s.middle_name, s.last_name, s.suffix, e.type, e.school_year, e.grade_level,
-	  s.gender, s.birthdate, s.address, s.mother_name, s.mother_job, s.mother_education, s.father_name, s.father_job,
-	  s.father_education, s.living_with, s.contact_numbers, e.monthly_tuition, e.months, e.enrollment_fee, e.misc_fee,
-	  e.pta_fee, e.lms_books_fee
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
@@ -109,6 +102,11 @@ func (s *EnrollmentStore) GetEnrollmentByID(ctx context.Context, id uuid.UUID) (
 		&enrollment.Type,
 		&enrollment.GradeLevel,
 		&enrollment.SchoolYear,
+		&enrollment.Status,
+		&enrollment.WithdrawalDate,
+		&enrollment.SectionID,
+		&enrollment.SectionName,
+		&enrollment.Version,
 		pq.Array(&enrollment.DiscountTypes),
 		&enrollment.TotalAmount,
 		&enrollment.TotalPaid,
@@ -165,6 +163,7 @@ func (s *EnrollmentStore) GetEditEnrollmentDetails(ctx context.Context, id uuid.
 		e.misc_fee,
 		e.pta_fee,
 		e.lms_books_fee,
+		e.version,
 		(
 			SELECT EXISTS (
 			SELECT 1 FROM discounts d
@@ -190,16 +189,8 @@ func (s *EnrollmentStore) GetEditEnrollmentDetails(ctx context.Context, id uuid.
 			)
 		) AS "hasScholarDiscount"
 	FROM enrollments e
-	LEFT JOIN tuition_payments tp ON tp.enrollment_id = e.id AND tp.deleted_at IS NULL
 	LEFT JOIN students s ON s.id = e.student_id AND s.deleted_at IS NULL
 	WHERE e.deleted_at IS NULL AND e.id = $1
-	GROUP BY
-		e.id, s.id, s.first_name, s.middle_name, s.last_name, s.suffix,
-		e.type, e.school_year, e.grade_level,
-		s.gender, s.birthdate, s.address, s.mother_name, s.mother_job,
-		s.mother_education, s.father_name, s.father_job, s.father_education,
-		s.living_with, s.contact_numbers, e.monthly_tuition, e.months,
-		e.enrollment_fee, e.misc_fee, e.pta_fee, e.lms_books_fee
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
@@ -239,6 +230,7 @@ func (s *EnrollmentStore) GetEditEnrollmentDetails(ctx context.Context, id uuid.
 		&enrollment.MiscFee,
 		&enrollment.PtaFee,
 		&enrollment.LmsFee,
+		&enrollment.Version,
 		&enrollment.IsRankOne,
 		&enrollment.HasSiblingDiscount,
 		&enrollment.HasWholeYearDiscount,
@@ -274,29 +266,16 @@ func (s *EnrollmentStore) GetAll(ctx context.Context) ([]models.EnrollmentsTable
       e.school_year,
 	  e.grade_level,
 	  s.gender,
-	  COALESCE(array_agg(DISTINCT d.type) FILTER (WHERE d.type IS NOT NULL), ARRAY[]::text[]) AS discount_types,
-      (e.monthly_tuition * e.months + e.enrollment_fee + e.misc_fee + e.pta_fee + e.lms_books_fee
-         - COALESCE(SUM(d.amount), 0)) AS total_amount,
-	  COALESCE(SUM(tp.reservation_fee + tp.tuition_fee + tp.advance_payment), 0) AS total_paid,
-	  (
-	 	(e.monthly_tuition * e.months + e.enrollment_fee + e.misc_fee + e.pta_fee + e.lms_books_fee)
-		- COALESCE(SUM(d.amount), 0)
-		- COALESCE(SUM(tp.reservation_fee + tp.tuition_fee + tp.advance_payment), 0) 
-	  ) AS remaining_amount,
-	  CASE
-	  	WHEN COALESCE(SUM(tp.reservation_fee + tp.tuition_fee + tp.advance_payment), 0) = 0
-			THEN 'unpaid'
-		WHEN COALESCE(SUM(tp.reservation_fee + tp.tuition_fee + tp.advance_payment), 0) >=
-			   (e.monthly_tuition * e.months + e.enrollment_fee + e.misc_fee + e.pta_fee + e.lms_books_fee - COALESCE(SUM(d.amount), 0))
-			THEN 'paid'
-		ELSE 'partial'
-	  END AS payment_status
+	  e.status,
+	  b.discount_types,
+	  b.total_amount,
+	  b.total_paid,
+	  b.remaining_amount,
+	  b.payment_status
     FROM enrollments e
-    LEFT JOIN discounts d ON d.enrollment_id = e.id AND d.deleted_at IS NULL
-	LEFT JOIN tuition_payments tp ON tp.enrollment_id = e.id AND tp.deleted_at IS NULL
+    JOIN enrollment_balances b ON b.enrollment_id = e.id
     LEFT JOIN students s ON s.id = e.student_id AND s.deleted_at IS NULL
     WHERE e.deleted_at IS NULL
-    GROUP BY e.id, s.first_name, s.middle_name, s.last_name, s.suffix, s.gender, s.birthdate
 	ORDER BY e.created_at DESC
     `
 
@@ -324,6 +303,7 @@ func (s *EnrollmentStore) GetAll(ctx context.Context) ([]models.EnrollmentsTable
 			&enrollment.SchoolYear,
 			&enrollment.GradeLevel,
 			&enrollment.Gender,
+			&enrollment.Status,
 			pq.Array(&enrollment.DiscountTypes),
 			&enrollment.TotalAmount,
 			&enrollment.TotalPaid,
@@ -359,8 +339,8 @@ func (s *EnrollmentStore) GetAll(ctx context.Context) ([]models.EnrollmentsTable
 // 	  e.grade_level,
 // 	  s.gender,
 // 	  COALESCE(array_agg(DISTINCT d.type) FILTER (WHERE d.type IS NOT NULL), ARRAY[]::text[]) AS discount_types,
-//       (e.monthly_tuition * e.months + e.enrollment_fee + e.misc_fee + e.pta_fee + e.lms_books_fee
-//          - COALESCE(SUM(d.amount), 0)) AS total_amount
+//       (e.monthly_tuition * COALESCE(e.months_attended, e.months) + e.enrollment_fee + e.misc_fee + e.pta_fee + e.lms_books_fee
+//          - COALESCE(SUM(CASE WHEN d.scope = 'tuition' THEN ROUND(d.amount * COALESCE(e.months_attended, e.months) / e.months, 2) ELSE d.amount END), 0)) AS total_amount
 //     FROM enrollments e
 //     LEFT JOIN discounts d ON d.enrollment_id = e.id AND d.deleted_at IS NULL
 //     LEFT JOIN students s ON s.id = e.student_id AND s.deleted_at IS NULL
@@ -421,45 +401,77 @@ func (s *EnrollmentStore) GetAll(ctx context.Context) ([]models.EnrollmentsTable
 // 	return enrollments, nil
 // }
 
+// Create enrolls a student, or puts them on the waitlist when the grade level
+// is full and the enrollment allows it.
 func (s *EnrollmentStore) Create(ctx context.Context, enrollment *models.Enrollment) error {
-	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		return s.create(ctx, tx, enrollment)
+	})
+}
 
-		if enrollment.Type == "new" {
-			if err := s.createStudent(ctx, tx, enrollment); err != nil {
-				return err
-			}
+// create enrolls a student inside tx. A new student is created with the
+// enrollment unless they already have a record.
+func (s *EnrollmentStore) create(ctx context.Context, tx *sql.Tx, enrollment *models.Enrollment) error {
+	if err := checkSchoolYearOpen(ctx, tx, enrollment.SchoolYear); err != nil {
+		return err
+	}
+
+	seats, err := gradeSeatsLeft(ctx, tx, enrollment.SchoolYear, enrollment.GradeLevel)
+	if err != nil {
+		return err
+	}
+
+	enrollment.Status = constants.Enrolled
+	if seats == 0 {
+		if !enrollment.AllowWaitlist {
+			return ErrGradeFull
 		}
+		enrollment.Status = constants.Waitlisted
+	}
 
-		if err := s.createEnrollment(ctx, tx, enrollment); err != nil {
+	if enrollment.Type == "new" && enrollment.Student.ID == uuid.Nil {
+		if err := s.createStudent(ctx, tx, enrollment); err != nil {
 			return err
 		}
+	}
 
-		if len(enrollment.Discounts) > 0 {
-			for _, discount := range enrollment.Discounts {
-				switch discount.Type {
-				case constants.Rank_1:
-					discount.Scope = constants.LmsBooks
-				case constants.Sibling, constants.FullYear, constants.Scholar:
-					discount.Scope = constants.Tuition
-				case constants.Carpool:
-					discount.Scope = constants.Carpool
-				default:
-					continue
-				}
-
-				if err := s.createDiscount(ctx, tx, enrollment.ID, discount); err != nil {
-					return err
-				}
+	if err := s.createEnrollment(ctx, tx, enrollment); err != nil {
+		return err
+	}
+
+	if len(enrollment.Discounts) > 0 {
+		for _, discount := range enrollment.Discounts {
+			switch discount.Type {
+			case constants.Rank_1:
+				discount.Scope = constants.LmsBooks
+			case constants.Sibling, constants.FullYear, constants.Scholar:
+				discount.Scope = constants.Tuition
+			case constants.Carpool:
+				discount.Scope = constants.Carpool
+			default:
+				continue
+			}
+
+			if err := s.createDiscount(ctx, tx, enrollment.ID, discount); err != nil {
+				return err
 			}
 		}
+	}
 
-		return nil
-	})
+	return syncEnrollmentLedger(ctx, tx, enrollment.ID)
 }
 
 func (s *EnrollmentStore) Update(ctx context.Context, enrollment *models.Enrollment, enrollmentID uuid.UUID) error {
-	return withTx(ctx, s.db, func(tx *sql.Tx) error {
-		if err := s.updateStudent(ctx, tx, enrollment); err != nil {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := checkEnrollmentPeriodOpen(ctx, tx, enrollmentID); err != nil {
+			return err
+		}
+
+		if err := checkSchoolYearOpen(ctx, tx, enrollment.SchoolYear); err != nil {
+			return err
+		}
+
+		if err := s.checkGradeChange(ctx, tx, enrollment, enrollmentID); err != nil {
 			return err
 		}
 
@@ -467,6 +479,11 @@ func (s *EnrollmentStore) Update(ctx context.Context, enrollment *models.Enrollm
 			return err
 		}
 
+		if err := s.updateStudent(ctx, tx, enrollment); err != nil {
+			return err
+		}
+
+		discounts := make([]*models.Discount, 0, len(enrollment.Discounts))
 		activeTypes := make([]string, 0, len(enrollment.Discounts))
 		for _, discount := range enrollment.Discounts {
 			switch discount.Type {
@@ -480,13 +497,12 @@ func (s *EnrollmentStore) Update(ctx context.Context, enrollment *models.Enrollm
 				continue
 			}
 
-			if err := s.updateDiscount(ctx, tx, enrollmentID, discount); err != nil {
-				return err
-			}
-
+			discounts = append(discounts, discount)
 			activeTypes = append(activeTypes, discount.Type)
 		}
 
+		// Dropped discounts go first so that swapping one tuition discount
+		// for another does not trip the discount rules trigger.
 		if len(activeTypes) == 0 {
 			if err := s.softDeleteDiscounts(ctx, tx, enrollmentID); err != nil {
 				return err
@@ -497,12 +513,22 @@ func (s *EnrollmentStore) Update(ctx context.Context, enrollment *models.Enrollm
 			}
 		}
 
-		return nil
+		for _, discount := range discounts {
+			if err := s.updateDiscount(ctx, tx, enrollmentID, discount); err != nil {
+				return err
+			}
+		}
+
+		return syncEnrollmentLedger(ctx, tx, enrollmentID)
 	})
 }
 
 func (s *EnrollmentStore) Delete(ctx context.Context, enrollmentID uuid.UUID) error {
-	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := checkEnrollmentPeriodOpen(ctx, tx, enrollmentID); err != nil {
+			return err
+		}
+
 		studentID, err := s.softDeleteEnrollment(ctx, tx, enrollmentID)
 		if err != nil {
 			return err
@@ -519,8 +545,35 @@ func (s *EnrollmentStore) Delete(ctx context.Context, enrollmentID uuid.UUID) er
 			}
 		}
 
-		return nil
+		return syncEnrollmentLedger(ctx, tx, enrollmentID)
+	})
+}
+
+func (s *EnrollmentStore) Withdraw(ctx context.Context, withdrawal *models.Withdrawal) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.withdrawEnrollment(ctx, tx, withdrawal); err != nil {
+			return err
+		}
+
+		if err := syncEnrollmentLedger(ctx, tx, withdrawal.EnrollmentID); err != nil {
+			return err
+		}
+
+		return s.getWithdrawalSummary(ctx, tx, withdrawal)
+	})
+}
+
+func (s *EnrollmentStore) GetWithdrawal(ctx context.Context, enrollmentID uuid.UUID) (models.Withdrawal, error) {
+	withdrawal := models.Withdrawal{EnrollmentID: enrollmentID}
+
+	err := withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		return s.getWithdrawalSummary(ctx, tx, &withdrawal)
 	})
+	if err != nil {
+		return withdrawal, err
+	}
+
+	return withdrawal, nil
 }
 
 func (s *EnrollmentStore) createStudent(ctx context.Context, tx *sql.Tx, enrollment *models.Enrollment) error {
@@ -561,12 +614,7 @@ func (s *EnrollmentStore) createStudent(ctx context.Context, tx *sql.Tx, enrollm
 	)
 
 	if err != nil {
-		switch err.Error() {
-		case `pq: duplicate key value violates unique constraint "idx_unique_student_name_birthday_gender"`:
-			return ErrDuplicate
-		default:
-			return err
-		}
+		return parsePgError(err)
 	}
 
 	return nil
@@ -576,8 +624,8 @@ func (s *EnrollmentStore) createEnrollment(ctx context.Context, tx *sql.Tx, enro
 	query := `
 		INSERT INTO enrollments
 			(student_id, school_year, grade_level, type, monthly_tuition, months, enrollment_fee, misc_fee, pta_fee, 
-			lms_books_fee)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			lms_books_fee, status, waitlisted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, CASE WHEN $11 = 'waitlisted' THEN now() END)
 		RETURNING id, created_at
 	`
 
@@ -597,6 +645,7 @@ func (s *EnrollmentStore) createEnrollment(ctx context.Context, tx *sql.Tx, enro
 		enrollment.MiscFee,
 		enrollment.PtaFee,
 		enrollment.LmsFee,
+		enrollment.Status,
 	).Scan(
 		&enrollment.ID,
 		&enrollment.CreatedAt,
@@ -634,7 +683,7 @@ func (s *EnrollmentStore) createDiscount(ctx context.Context, tx *sql.Tx, enroll
 	)
 
 	if err != nil {
-		return err
+		return parsePgError(err)
 	}
 
 	return nil
@@ -693,12 +742,7 @@ func (s *EnrollmentStore) updateStudent(ctx context.Context, tx *sql.Tx, enrollm
 		if err == sql.ErrNoRows {
 			return ErrNotFound
 		}
-		switch err.Error() {
-		case `pq: duplicate key value violates unique constraint "idx_unique_student_name_birthday_gender"`:
-			return ErrDuplicate
-		default:
-			return err
-		}
+		return parsePgError(err)
 	}
 
 	return nil
@@ -715,10 +759,12 @@ func (s *EnrollmentStore) updateEnrollment(ctx context.Context, tx *sql.Tx, enro
 			misc_fee = $5,
 			pta_fee = $6,
 			lms_books_fee = $7,
+			section_id = CASE WHEN school_year = $1 AND grade_level = $2 THEN section_id END,
+			version = version + 1,
 			updated_at = now()
 		WHERE
-			id = $8 AND deleted_at IS NULL
-		RETURNING updated_at
+			id = $8 AND deleted_at IS NULL AND ($9 = 0 OR version = $9)
+		RETURNING version, updated_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
@@ -735,12 +781,14 @@ func (s *EnrollmentStore) updateEnrollment(ctx context.Context, tx *sql.Tx, enro
 		enrollment.PtaFee,
 		enrollment.LmsFee,
 		enrollmentID,
+		enrollment.Version,
 	).Scan(
+		&enrollment.Version,
 		&enrollment.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return ErrNotFound
+			return s.versionConflictOrNotFound(ctx, tx, enrollmentID)
 		}
 		return parsePgError(err)
 	}
@@ -748,6 +796,67 @@ func (s *EnrollmentStore) updateEnrollment(ctx context.Context, tx *sql.Tx, enro
 	return nil
 }
 
+// checkGradeChange rejects moving an enrolled student into a school year or
+// grade level that is already full. Waitlisted enrollments can move freely.
+func (s *EnrollmentStore) checkGradeChange(ctx context.Context, tx *sql.Tx, enrollment *models.Enrollment, enrollmentID uuid.UUID) error {
+	query := `
+		SELECT school_year, grade_level, status
+		FROM enrollments
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var schoolYear, gradeLevel, status string
+	err := tx.QueryRowContext(ctx, query, enrollmentID).Scan(&schoolYear, &gradeLevel, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Reported by updateEnrollment.
+			return nil
+		}
+		return err
+	}
+
+	if status != constants.Enrolled || (schoolYear == enrollment.SchoolYear && gradeLevel == enrollment.GradeLevel) {
+		return nil
+	}
+
+	seats, err := gradeSeatsLeft(ctx, tx, enrollment.SchoolYear, enrollment.GradeLevel)
+	if err != nil {
+		return err
+	}
+
+	if seats == 0 {
+		return ErrGradeFull
+	}
+
+	return nil
+}
+
+// versionConflictOrNotFound tells a stale version apart from a missing
+// enrollment after a conditional update matched no rows.
+func (s *EnrollmentStore) versionConflictOrNotFound(ctx context.Context, tx *sql.Tx, enrollmentID uuid.UUID) error {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM enrollments
+			WHERE id = $1 AND deleted_at IS NULL
+		)
+	`
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, query, enrollmentID).Scan(&exists); err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrVersionConflict
+	}
+
+	return ErrNotFound
+}
+
 func (s *EnrollmentStore) updateDiscount(ctx context.Context, tx *sql.Tx, enrollmentID uuid.UUID, discount *models.Discount) error {
 	query := `
 	INSERT INTO discounts 
@@ -784,7 +893,7 @@ func (s *EnrollmentStore) updateDiscount(ctx context.Context, tx *sql.Tx, enroll
 		&discount.UpdatedAt,
 	)
 	if err != nil {
-		return err
+		return parsePgError(err)
 	}
 
 	return nil
@@ -815,7 +924,7 @@ func (s *EnrollmentStore) softDeleteEnrollment(ctx context.Context, tx *sql.Tx,
 	query := `
 		UPDATE enrollments SET
 			deleted_at = now()
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 		RETURNING student_id
 	`
 
@@ -837,8 +946,8 @@ func (s *EnrollmentStore) softDeleteEnrollment(ctx context.Context, tx *sql.Tx,
 
 func (s *EnrollmentStore) checkStudentOtherEnrollments(ctx context.Context, tx *sql.Tx, studentID uuid.UUID) (bool, error) {
 	query := `
-		SELECT COUNT(1) FROM students
-		WHERE id = $1 AND deleted_at IS NULL
+		SELECT COUNT(1) FROM enrollments
+		WHERE student_id = $1 AND deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
@@ -858,7 +967,7 @@ func (s *EnrollmentStore) softDeleteStudent(ctx context.Context, tx *sql.Tx, stu
 	query := `
 		UPDATE students SET
 		deleted_at = now()
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
@@ -866,7 +975,7 @@ func (s *EnrollmentStore) softDeleteStudent(ctx context.Context, tx *sql.Tx, stu
 
 	rows, err := tx.ExecContext(ctx, query, studentID)
 	if err != nil {
-		return nil
+		return err
 	}
 
 	row, err := rows.RowsAffected()
@@ -894,9 +1003,147 @@ func (s *EnrollmentStore) softDeleteDiscounts(ctx context.Context, tx *sql.Tx, e
 	defer cancel()
 
 	_, err := tx.ExecContext(ctx, query, enrollmentID)
-	if err == nil {
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *EnrollmentStore) withdrawEnrollment(ctx context.Context, tx *sql.Tx, withdrawal *models.Withdrawal) error {
+	query := `
+		SELECT school_year, months, status
+		FROM enrollments
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var status string
+	err := tx.QueryRowContext(ctx, query, withdrawal.EnrollmentID).Scan(
+		&withdrawal.SchoolYear,
+		&withdrawal.Months,
+		&status,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	switch status {
+	case constants.Withdrawn:
+		return ErrAlreadyWithdrawn
+	case constants.Waitlisted:
+		return ErrWaitlisted
+	}
+
+	// A withdrawal rewrites the enrollment's billing, so like any other change
+	// it must not reach into a closed month or school year.
+	if err := checkEnrollmentPeriodOpen(ctx, tx, withdrawal.EnrollmentID); err != nil {
+		return err
+	}
+
+	withdrawal.MonthsAttended = monthsAttended(withdrawal.SchoolYear, withdrawal.WithdrawalDate, withdrawal.Months)
+
+	query = `
+		UPDATE enrollments
+		SET
+			status = $1,
+			withdrawal_date = $2,
+			withdrawal_reason = $3,
+			months_attended = $4,
+			version = version + 1,
+			updated_at = now()
+		WHERE id = $5
+	`
+
+	_, err = tx.ExecContext(
+		ctx,
+		query,
+		constants.Withdrawn,
+		withdrawal.WithdrawalDate,
+		withdrawal.Reason,
+		withdrawal.MonthsAttended,
+		withdrawal.EnrollmentID,
+	)
+	if err != nil {
+		return parsePgError(err)
+	}
+
+	return nil
+}
+
+func (s *EnrollmentStore) getWithdrawalSummary(ctx context.Context, tx *sql.Tx, withdrawal *models.Withdrawal) error {
+	// Tuition and tuition-scoped discounts are prorated to the months attended;
+	// the one-time fees and the lms/books discount are kept whole.
+	query := `
+		SELECT
+			e.school_year,
+			e.withdrawal_date,
+			COALESCE(e.withdrawal_reason, ''),
+			e.months,
+			e.months_attended,
+			e.monthly_tuition * e.months_attended AS prorated_tuition,
+			t.total_amount,
+			t.total_paid
+		FROM enrollments e
+		JOIN enrollment_totals t ON t.enrollment_id = e.id
+		WHERE e.id = $1 AND e.deleted_at IS NULL AND e.status = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, withdrawal.EnrollmentID, constants.Withdrawn).Scan(
+		&withdrawal.SchoolYear,
+		&withdrawal.WithdrawalDate,
+		&withdrawal.Reason,
+		&withdrawal.Months,
+		&withdrawal.MonthsAttended,
+		&withdrawal.ProratedTuition,
+		&withdrawal.TotalAmount,
+		&withdrawal.TotalPaid,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
 		return err
 	}
 
+	withdrawal.RefundAmount = decimal.Zero
+	withdrawal.BalanceDue = decimal.Zero
+
+	difference := withdrawal.TotalPaid.Sub(withdrawal.TotalAmount)
+	if difference.IsPositive() {
+		withdrawal.RefundAmount = difference
+	} else {
+		withdrawal.BalanceDue = difference.Neg()
+	}
+
 	return nil
 }
+
+// monthsAttended counts the school months from the start of the school year up
+// to and including the withdrawal month, bounded by the months billed.
+func monthsAttended(schoolYear string, withdrawalDate time.Time, months int) int {
+	startYear, err := strconv.Atoi(strings.SplitN(schoolYear, "-", 2)[0])
+	if err != nil {
+		return months
+	}
+
+	attended := (withdrawalDate.Year()-startYear)*12 + int(withdrawalDate.Month()-constants.SchoolYearStartMonth) + 1
+
+	switch {
+	case attended < 0:
+		return 0
+	case attended > months:
+		return months
+	default:
+		return attended
+	}
+}
@@ -0,0 +1,445 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestEnrollmentStoreCreate(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	enrollment := createTestEnrollment(t, s, "Maria", constants.Rank_1, constants.Sibling)
+
+	details, err := s.Enrollments.GetEnrollmentByID(ctx, enrollment.ID)
+	if err != nil {
+		t.Fatalf("GetEnrollmentByID: %v", err)
+	}
+
+	if details.Student.ID != enrollment.Student.ID {
+		t.Errorf("student = %s, want %s", details.Student.ID, enrollment.Student.ID)
+	}
+	if details.Status != constants.Enrolled || details.Version != 1 {
+		t.Errorf("status = %q, version = %d, want enrolled at version 1", details.Status, details.Version)
+	}
+	if !slices.Equal(details.DiscountTypes, []string{constants.Rank_1, constants.Sibling}) {
+		t.Errorf("discount types = %v", details.DiscountTypes)
+	}
+
+	assertDecimal(t, "total amount", details.TotalAmount, 10500)
+	assertDecimal(t, "total paid", details.TotalPaid, 0)
+	assertDecimal(t, "remaining amount", details.RemainingAmount, 10500)
+	if details.PaymentStatus != "unpaid" {
+		t.Errorf("payment status = %q, want unpaid", details.PaymentStatus)
+	}
+}
+
+func TestEnrollmentStoreCreateErrors(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		discounts []string
+		modify    func(e *models.Enrollment)
+		want      error
+	}{
+		{
+			name:      "carpool with another discount",
+			discounts: []string{constants.Sibling, constants.Carpool},
+			want:      ErrCarpoolDiscountExclusive,
+		},
+		{
+			name:      "two tuition discounts",
+			discounts: []string{constants.Sibling, constants.Scholar},
+			want:      ErrTuitionDiscountExclusive,
+		},
+		{
+			name:   "zero tuition",
+			modify: func(e *models.Enrollment) { e.MonthlyTuition = decimal.Zero },
+			want:   ErrRequiredFees,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+
+			enrollment := newTestEnrollment(newTestStudent("Maria"), tt.discounts...)
+			if tt.modify != nil {
+				tt.modify(enrollment)
+			}
+
+			assertErr(t, s.Enrollments.Create(ctx, enrollment), tt.want)
+
+			if n := countRows(t, `SELECT COUNT(*) FROM students`); n != 0 {
+				t.Errorf("%d students left after a failed create, want the transaction rolled back", n)
+			}
+		})
+	}
+
+	t.Run("student already exists", func(t *testing.T) {
+		s := newTestStorage(t)
+		createTestEnrollment(t, s, "Maria")
+
+		enrollment := newTestEnrollment(newTestStudent("Maria"))
+		enrollment.SchoolYear = "2026-2027"
+
+		assertErr(t, s.Enrollments.Create(ctx, enrollment), ErrDuplicate)
+	})
+
+	t.Run("student already enrolled in the school year", func(t *testing.T) {
+		s := newTestStorage(t)
+		existing := createTestEnrollment(t, s, "Maria")
+
+		enrollment := newTestEnrollment(existing.Student)
+		enrollment.Type = "old"
+
+		assertErr(t, s.Enrollments.Create(ctx, enrollment), ErrDuplicate)
+	})
+
+	t.Run("closed school year", func(t *testing.T) {
+		s := newTestStorage(t)
+		closeSchoolYear(t, s, testSchoolYear)
+
+		enrollment := newTestEnrollment(newTestStudent("Maria"))
+
+		assertErr(t, s.Enrollments.Create(ctx, enrollment), ErrPeriodClosed)
+	})
+}
+
+func TestEnrollmentStoreGetAll(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	maria := createTestEnrollment(t, s, "Maria", constants.Sibling)
+	jose := createTestEnrollment(t, s, "Jose")
+	createTestPayment(t, s, maria.ID, "OR-0001", 2000)
+
+	enrollments, err := s.Enrollments.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+
+	if len(enrollments) != 2 {
+		t.Fatalf("GetAll returned %d enrollments, want 2", len(enrollments))
+	}
+
+	for _, enrollment := range enrollments {
+		switch enrollment.ID {
+		case maria.ID:
+			assertDecimal(t, "total amount", enrollment.TotalAmount, 11500)
+			assertDecimal(t, "total paid", enrollment.TotalPaid, 2000)
+			assertDecimal(t, "remaining amount", enrollment.RemainingAmount, 9500)
+			if enrollment.PaymentStatus != "partial" {
+				t.Errorf("payment status = %q, want partial", enrollment.PaymentStatus)
+			}
+		case jose.ID:
+			assertDecimal(t, "total amount", enrollment.TotalAmount, 12500)
+			if enrollment.FullName != "Jose R. Santos" {
+				t.Errorf("full name = %q, want Jose R. Santos", enrollment.FullName)
+			}
+		default:
+			t.Errorf("unexpected enrollment %s", enrollment.ID)
+		}
+	}
+}
+
+func TestEnrollmentTotalsMultipleDiscountsAndPayments(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	// Two discounts and several payments: joined row by row, each sum would
+	// be multiplied by the row count of the other side.
+	enrollment := createTestEnrollment(t, s, "Maria", constants.Rank_1, constants.Sibling)
+	for i, amount := range []int64{1000, 2000, 3000} {
+		createTestPayment(t, s, enrollment.ID, fmt.Sprintf("OR-%04d", i+1), amount)
+	}
+
+	assertTotals := func(t *testing.T, total, paid, remaining int64, status string) {
+		t.Helper()
+
+		details, err := s.Enrollments.GetEnrollmentByID(ctx, enrollment.ID)
+		if err != nil {
+			t.Fatalf("GetEnrollmentByID: %v", err)
+		}
+		assertDecimal(t, "total amount", details.TotalAmount, total)
+		assertDecimal(t, "total paid", details.TotalPaid, paid)
+		assertDecimal(t, "remaining amount", details.RemainingAmount, remaining)
+		if details.PaymentStatus != status {
+			t.Errorf("payment status = %q, want %s", details.PaymentStatus, status)
+		}
+		if !slices.Equal(details.DiscountTypes, []string{constants.Rank_1, constants.Sibling}) {
+			t.Errorf("discount types = %v", details.DiscountTypes)
+		}
+
+		enrollments, err := s.Enrollments.GetAll(ctx)
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+		if len(enrollments) != 1 {
+			t.Fatalf("GetAll returned %d enrollments, want 1", len(enrollments))
+		}
+		row := enrollments[0]
+		if !row.TotalAmount.Equal(details.TotalAmount) || !row.TotalPaid.Equal(details.TotalPaid) ||
+			!row.RemainingAmount.Equal(details.RemainingAmount) || row.PaymentStatus != details.PaymentStatus ||
+			!slices.Equal(row.DiscountTypes, details.DiscountTypes) {
+			t.Errorf("GetAll = %+v, want the totals of GetEnrollmentByID %+v", row, details)
+		}
+	}
+
+	// 12,500 less 1,000 for each discount, with 6,000 paid.
+	assertTotals(t, 10500, 6000, 4500, "partial")
+
+	createTestPayment(t, s, enrollment.ID, "OR-0004", 4500)
+	assertTotals(t, 10500, 10500, 0, "paid")
+
+	withdrawal := &models.Withdrawal{
+		EnrollmentID:   enrollment.ID,
+		WithdrawalDate: time.Date(2025, time.August, 15, 0, 0, 0, 0, time.UTC),
+	}
+	if err := s.Enrollments.Withdraw(ctx, withdrawal); err != nil {
+		t.Fatalf("Withdraw: %v", err)
+	}
+
+	// Three months of tuition plus the fees is 5,500, less 300 of the
+	// prorated sibling discount and the whole rank 1 discount.
+	assertDecimal(t, "withdrawal total amount", withdrawal.TotalAmount, 4200)
+	assertDecimal(t, "withdrawal total paid", withdrawal.TotalPaid, 10500)
+	assertDecimal(t, "refund amount", withdrawal.RefundAmount, 6300)
+	assertTotals(t, 4200, 10500, -6300, "paid")
+}
+
+func TestEnrollmentStoreGetEditEnrollmentDetails(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	enrollment := createTestEnrollment(t, s, "Maria", constants.Rank_1, constants.Sibling)
+
+	details, err := s.Enrollments.GetEditEnrollmentDetails(ctx, enrollment.ID)
+	if err != nil {
+		t.Fatalf("GetEditEnrollmentDetails: %v", err)
+	}
+
+	if !details.IsRankOne || !details.HasSiblingDiscount || details.HasWholeYearDiscount || details.HasScholarDiscount {
+		t.Errorf("discount flags = %+v, want rank 1 and sibling only", details)
+	}
+	assertDecimal(t, "monthly tuition", details.MonthlyTuition, 1000)
+	assertDecimal(t, "lms books fee", details.LmsFee, 1500)
+
+	_, err = s.Enrollments.GetEditEnrollmentDetails(ctx, uuid.New())
+	assertErr(t, err, ErrNotFound)
+}
+
+func TestEnrollmentStoreUpdate(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	created := createTestEnrollment(t, s, "Maria", constants.Rank_1, constants.Sibling)
+
+	update := newTestEnrollment(created.Student, constants.Rank_1, constants.FullYear)
+	update.MonthlyTuition = decimal.NewFromInt(1200)
+	update.Version = 1
+
+	if err := s.Enrollments.Update(ctx, update, created.ID); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	if update.Version != 2 {
+		t.Errorf("version = %d, want 2", update.Version)
+	}
+
+	details, err := s.Enrollments.GetEditEnrollmentDetails(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetEditEnrollmentDetails: %v", err)
+	}
+
+	assertDecimal(t, "monthly tuition", details.MonthlyTuition, 1200)
+	if !details.IsRankOne || details.HasSiblingDiscount || !details.HasWholeYearDiscount {
+		t.Errorf("discount flags = %+v, want sibling replaced by full year", details)
+	}
+
+	active := countRows(t, `SELECT COUNT(*) FROM discounts WHERE enrollment_id = $1 AND deleted_at IS NULL`, created.ID)
+	if active != 2 {
+		t.Errorf("%d active discounts, want 2", active)
+	}
+
+	t.Run("stale version", func(t *testing.T) {
+		stale := newTestEnrollment(created.Student)
+		stale.Version = 1
+
+		assertErr(t, s.Enrollments.Update(ctx, stale, created.ID), ErrVersionConflict)
+	})
+
+	t.Run("conflicting discounts", func(t *testing.T) {
+		conflicting := newTestEnrollment(created.Student, constants.FullYear, constants.Scholar)
+
+		assertErr(t, s.Enrollments.Update(ctx, conflicting, created.ID), ErrTuitionDiscountExclusive)
+	})
+
+	t.Run("removing every discount", func(t *testing.T) {
+		if err := s.Enrollments.Update(ctx, newTestEnrollment(created.Student), created.ID); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		active := countRows(t, `SELECT COUNT(*) FROM discounts WHERE enrollment_id = $1 AND deleted_at IS NULL`, created.ID)
+		if active != 0 {
+			t.Errorf("%d active discounts, want 0", active)
+		}
+	})
+
+	t.Run("missing enrollment", func(t *testing.T) {
+		assertErr(t, s.Enrollments.Update(ctx, newTestEnrollment(created.Student), uuid.New()), ErrNotFound)
+	})
+}
+
+func TestEnrollmentStoreDelete(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	only := createTestEnrollment(t, s, "Maria")
+
+	first := createTestEnrollment(t, s, "Jose")
+	second := newTestEnrollment(first.Student)
+	second.Type = "old"
+	second.SchoolYear = "2026-2027"
+	if err := s.Enrollments.Create(ctx, second); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if err := s.Enrollments.Delete(ctx, only.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Enrollments.Delete(ctx, first.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	_, err := s.Enrollments.GetEnrollmentByID(ctx, only.ID)
+	assertErr(t, err, ErrNotFound)
+
+	activeStudent := `SELECT COUNT(*) FROM students WHERE id = $1 AND deleted_at IS NULL`
+	if countRows(t, activeStudent, only.Student.ID) != 0 {
+		t.Error("student without other enrollments was not soft-deleted")
+	}
+	if countRows(t, activeStudent, first.Student.ID) != 1 {
+		t.Error("student with another enrollment was soft-deleted")
+	}
+
+	assertErr(t, s.Enrollments.Delete(ctx, only.ID), ErrNotFound)
+
+	t.Run("ledger reversed", func(t *testing.T) {
+		if err := s.Enrollments.Delete(ctx, second.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		receivable := accountBalance(t, s, constants.AccountTuitionReceivable)
+		if !receivable.IsZero() {
+			t.Errorf("tuition receivable = %s after deleting every enrollment, want 0", receivable)
+		}
+	})
+}
+
+func TestEnrollmentStoreWithdraw(t *testing.T) {
+	ctx := context.Background()
+	withdrawalDate := time.Date(2025, time.August, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run("balance due", func(t *testing.T) {
+		s := newTestStorage(t)
+
+		enrollment := createTestEnrollment(t, s, "Maria", constants.Sibling)
+		createTestPayment(t, s, enrollment.ID, "OR-0001", 2000)
+
+		withdrawal := &models.Withdrawal{
+			EnrollmentID:   enrollment.ID,
+			WithdrawalDate: withdrawalDate,
+			Reason:         "transferred",
+		}
+		if err := s.Enrollments.Withdraw(ctx, withdrawal); err != nil {
+			t.Fatalf("Withdraw: %v", err)
+		}
+
+		// June to August is three of ten months: 3,000 tuition plus 2,500
+		// in fees, less 300 of the prorated sibling discount.
+		if withdrawal.MonthsAttended != 3 {
+			t.Errorf("months attended = %d, want 3", withdrawal.MonthsAttended)
+		}
+		assertDecimal(t, "prorated tuition", withdrawal.ProratedTuition, 3000)
+		assertDecimal(t, "total amount", withdrawal.TotalAmount, 5200)
+		assertDecimal(t, "balance due", withdrawal.BalanceDue, 3200)
+		assertDecimal(t, "refund amount", withdrawal.RefundAmount, 0)
+
+		stored, err := s.Enrollments.GetWithdrawal(ctx, enrollment.ID)
+		if err != nil {
+			t.Fatalf("GetWithdrawal: %v", err)
+		}
+		if stored.Reason != "transferred" || !stored.BalanceDue.Equal(withdrawal.BalanceDue) {
+			t.Errorf("GetWithdrawal = %+v, want %+v", stored, withdrawal)
+		}
+
+		details, err := s.Enrollments.GetEnrollmentByID(ctx, enrollment.ID)
+		if err != nil {
+			t.Fatalf("GetEnrollmentByID: %v", err)
+		}
+		if details.Status != constants.Withdrawn {
+			t.Errorf("status = %q, want withdrawn", details.Status)
+		}
+		assertDecimal(t, "remaining amount", details.RemainingAmount, 3200)
+
+		assertErr(t, s.Enrollments.Withdraw(ctx, withdrawal), ErrAlreadyWithdrawn)
+	})
+
+	t.Run("refund", func(t *testing.T) {
+		s := newTestStorage(t)
+
+		enrollment := createTestEnrollment(t, s, "Maria", constants.Sibling)
+		createTestPayment(t, s, enrollment.ID, "OR-0001", 6000)
+
+		withdrawal := &models.Withdrawal{EnrollmentID: enrollment.ID, WithdrawalDate: withdrawalDate}
+		if err := s.Enrollments.Withdraw(ctx, withdrawal); err != nil {
+			t.Fatalf("Withdraw: %v", err)
+		}
+
+		assertDecimal(t, "refund amount", withdrawal.RefundAmount, 800)
+
+		refundsPayable := accountBalance(t, s, constants.AccountRefundsPayable)
+		assertDecimal(t, "refunds payable", refundsPayable, -800)
+	})
+
+	t.Run("closed payment month", func(t *testing.T) {
+		s := newTestStorage(t)
+
+		enrollment := createTestEnrollment(t, s, "Maria")
+		createTestPayment(t, s, enrollment.ID, "OR-0001", 2000)
+
+		// The withdrawal month is open, but recomputing the billing would
+		// change figures reported for June.
+		closeMonth(t, s, time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC))
+
+		withdrawal := &models.Withdrawal{EnrollmentID: enrollment.ID, WithdrawalDate: withdrawalDate}
+		assertErr(t, s.Enrollments.Withdraw(ctx, withdrawal), ErrPeriodClosed)
+	})
+
+	t.Run("not withdrawn", func(t *testing.T) {
+		s := newTestStorage(t)
+		enrollment := createTestEnrollment(t, s, "Maria")
+
+		_, err := s.Enrollments.GetWithdrawal(ctx, enrollment.ID)
+		assertErr(t, err, ErrNotFound)
+	})
+
+	t.Run("missing enrollment", func(t *testing.T) {
+		s := newTestStorage(t)
+
+		withdrawal := &models.Withdrawal{EnrollmentID: uuid.New(), WithdrawalDate: withdrawalDate}
+		assertErr(t, s.Enrollments.Withdraw(ctx, withdrawal), ErrNotFound)
+	})
+}
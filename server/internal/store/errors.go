@@ -0,0 +1,120 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Kind classifies a store error so callers can decide how to report it
+// without knowing every individual error.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindInvalid
+	KindPrecondition
+)
+
+// Error is a domain error returned by the store. Code is a stable,
+// machine-readable identifier and Message is safe to show to users.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// KindOf returns the Kind of err, or KindInternal if err is not a store Error.
+func KindOf(err error) Kind {
+	var storeErr *Error
+	if errors.As(err, &storeErr) {
+		return storeErr.Kind
+	}
+
+	return KindInternal
+}
+
+var (
+	ErrNotFound                 = newError(KindNotFound, "not_found", "record not found")
+	ErrConflict                 = newError(KindConflict, "conflict", "resource already exist")
+	ErrDuplicate                = newError(KindConflict, "duplicate_student", "student with that record already exist")
+	ErrDuplicateInvoice         = newError(KindConflict, "duplicate_invoice", "payment with that invoice number already exist")
+	ErrAlreadyWithdrawn         = newError(KindConflict, "already_withdrawn", "enrollment is already withdrawn")
+	ErrGradeFull                = newError(KindConflict, "grade_full", "grade level is at capacity")
+	ErrNotWaitlisted            = newError(KindConflict, "not_waitlisted", "enrollment is not on the waitlist")
+	ErrWaitlisted               = newError(KindConflict, "enrollment_waitlisted", "enrollment is on the waitlist")
+	ErrSectionFull              = newError(KindConflict, "section_full", "section is at capacity")
+	ErrSectionNotEmpty          = newError(KindConflict, "section_not_empty", "section still has students assigned")
+	ErrDuplicateSection         = newError(KindConflict, "duplicate_section", "section with that name already exist")
+	ErrAlreadyEnrolled          = newError(KindConflict, "already_enrolled", "student is already enrolled for that school year")
+	ErrDuplicateReservation     = newError(KindConflict, "duplicate_reservation", "student already has an open reservation for that school year")
+	ErrReservationClosed        = newError(KindConflict, "reservation_closed", "reservation is no longer open")
+	ErrReservationExpired       = newError(KindConflict, "reservation_expired", "reservation has expired")
+	ErrReservationCredit        = newError(KindConflict, "reservation_credit", "payment credits a reservation fee and cannot be changed")
+	ErrInvalidTransition        = newError(KindConflict, "invalid_status_transition", "applicant cannot move to that status")
+	ErrDocumentsMissing         = newError(KindConflict, "documents_missing", "applicant is missing required documents")
+	ErrApplicantClosed          = newError(KindConflict, "applicant_closed", "applicant has already been rejected or enrolled")
+	ErrNotAccepted              = newError(KindConflict, "applicant_not_accepted", "only accepted applicants can be enrolled")
+	ErrDuplicateGuardian        = newError(KindConflict, "duplicate_guardian", "guardian with that email already exist for the student")
+	ErrNotificationNotFailed    = newError(KindConflict, "notification_not_failed", "only failed notifications can be retried")
+	ErrPeriodClosed             = newError(KindConflict, "period_closed", "accounting period is closed")
+	ErrPeriodAlreadyClosed      = newError(KindConflict, "period_already_closed", "accounting period is already closed")
+	ErrRequiredFees             = newError(KindInvalid, "required_fees", "enrollment, tuition, misc, pta, lms_books fees must be greater than zero")
+	ErrInvalidPayment           = newError(KindInvalid, "invalid_payment", "payment amounts must not be negative and must total more than zero")
+	ErrSectionMismatch          = newError(KindInvalid, "section_mismatch", "section is for a different school year or grade level")
+	ErrSectionCapacity          = newError(KindInvalid, "section_capacity_too_low", "section capacity is below the students assigned to it")
+	ErrUnbalancedEntry          = newError(KindInvalid, "unbalanced_entry", "journal entry debits and credits do not balance")
+	ErrUnknownAccount           = newError(KindInvalid, "unknown_account", "account does not exist")
+	ErrCarpoolDiscountExclusive = newError(KindInvalid, "carpool_discount_exclusive", "carpool discount cannot be combined with other discounts")
+	ErrTuitionDiscountExclusive = newError(KindInvalid, "tuition_discount_exclusive", "sibling, full_year and scholar discounts cannot be combined")
+	ErrVersionConflict          = newError(KindPrecondition, "version_conflict", "enrollment was modified by another request")
+)
+
+// constraintErrors maps Postgres constraint names, including the names given
+// by triggers through RAISE ... USING CONSTRAINT, to domain errors.
+var constraintErrors = map[string]error{
+	"idx_unique_student_name_birthday_gender": ErrDuplicate,
+	"enrollments_student_id_school_year_key":  ErrDuplicate,
+	"check_positive_fees":                     ErrRequiredFees,
+	"tuition_payments_invoice_number_key":     ErrDuplicateInvoice,
+	"check_positive_payment":                  ErrInvalidPayment,
+	"journal_entry_balanced":                  ErrUnbalancedEntry,
+	"open_accounting_period":                  ErrPeriodClosed,
+	"idx_closed_periods_month":                ErrPeriodAlreadyClosed,
+	"idx_closed_periods_school_year":          ErrPeriodAlreadyClosed,
+	"discount_carpool_exclusive":              ErrCarpoolDiscountExclusive,
+	"discount_tuition_exclusive":              ErrTuitionDiscountExclusive,
+	"idx_sections_name":                       ErrDuplicateSection,
+	"idx_reservations_open_student":           ErrDuplicateReservation,
+	"reservations_invoice_number_key":         ErrDuplicateInvoice,
+	"idx_guardians_student_email":             ErrDuplicateGuardian,
+}
+
+// parsePgError translates a Postgres error into a domain error. Unknown
+// unique violations become ErrConflict; anything else is returned unchanged.
+func parsePgError(err error) error {
+	var pgErr *pq.Error
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	if mapped, ok := constraintErrors[pgErr.Constraint]; ok {
+		return mapped
+	}
+
+	if pgErr.Code == "23505" {
+		return ErrConflict
+	}
+
+	return err
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+)
+
+type ExpenseStore struct {
+	db *sql.DB
+}
+
+func (s *ExpenseStore) Create(ctx context.Context, expense *models.Expense) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.createExpense(ctx, tx, expense); err != nil {
+			return err
+		}
+
+		return syncExpenseLedger(ctx, tx, expense)
+	})
+}
+
+func (s *ExpenseStore) GetAll(ctx context.Context) ([]models.Expense, error) {
+	query := `
+		SELECT id, expense_date, category, description, amount, payment_method, created_at, updated_at
+		FROM expenses
+		WHERE deleted_at IS NULL
+		ORDER BY expense_date DESC, created_at DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var expenses []models.Expense
+
+	for rows.Next() {
+		var expense models.Expense
+		err := rows.Scan(
+			&expense.ID,
+			&expense.ExpenseDate,
+			&expense.Category,
+			&expense.Description,
+			&expense.Amount,
+			&expense.PaymentMethod,
+			&expense.CreatedAt,
+			&expense.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		expenses = append(expenses, expense)
+	}
+
+	return expenses, rows.Err()
+}
+
+func (s *ExpenseStore) createExpense(ctx context.Context, tx *sql.Tx, expense *models.Expense) error {
+	query := `
+		INSERT INTO expenses
+			(expense_date, category, description, amount, payment_method)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		expense.ExpenseDate,
+		expense.Category,
+		expense.Description,
+		expense.Amount,
+		expense.PaymentMethod,
+	).Scan(
+		&expense.ID,
+		&expense.CreatedAt,
+		&expense.UpdatedAt,
+	)
+	if err != nil {
+		return parsePgError(err)
+	}
+
+	return nil
+}
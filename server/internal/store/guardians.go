@@ -0,0 +1,175 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+)
+
+const guardianColumns = `
+	id, student_id, name, relationship, email, phone, email_opt_out, created_at, updated_at
+`
+
+type GuardianStore struct {
+	db *sql.DB
+}
+
+// Create adds a guardian to a student. Without a phone number of their own
+// they are reached on the student's first contact number.
+func (s *GuardianStore) Create(ctx context.Context, guardian *models.Guardian) error {
+	query := `
+		INSERT INTO guardians (student_id, name, relationship, email, phone, email_opt_out)
+		SELECT s.id, $2::text, $3::text, $4::text, COALESCE($5::text, s.contact_numbers[1]), $6::boolean
+		FROM students s
+		WHERE s.id = $1 AND s.deleted_at IS NULL
+		RETURNING ` + guardianColumns
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	err := scanGuardian(s.db.QueryRowContext(
+		ctx,
+		query,
+		guardian.StudentID,
+		guardian.Name,
+		guardian.Relationship,
+		guardian.Email,
+		guardian.Phone,
+		guardian.EmailOptOut,
+	), guardian)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return parsePgError(err)
+	}
+
+	return nil
+}
+
+func (s *GuardianStore) GetByID(ctx context.Context, id uuid.UUID) (models.Guardian, error) {
+	query := `SELECT ` + guardianColumns + ` FROM guardians WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var guardian models.Guardian
+	err := scanGuardian(s.db.QueryRowContext(ctx, query, id), &guardian)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return guardian, ErrNotFound
+		}
+		return guardian, err
+	}
+
+	return guardian, nil
+}
+
+func (s *GuardianStore) GetByStudentID(ctx context.Context, studentID uuid.UUID) ([]models.Guardian, error) {
+	query := `
+		SELECT ` + guardianColumns + `
+		FROM guardians
+		WHERE student_id = $1
+		ORDER BY created_at, id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var guardians []models.Guardian
+
+	for rows.Next() {
+		var guardian models.Guardian
+		if err := scanGuardian(rows, &guardian); err != nil {
+			return nil, err
+		}
+
+		guardians = append(guardians, guardian)
+	}
+
+	return guardians, rows.Err()
+}
+
+// Update changes a guardian's details, including whether they have opted
+// out of emails.
+func (s *GuardianStore) Update(ctx context.Context, guardian *models.Guardian) error {
+	query := `
+		UPDATE guardians
+		SET
+			name = $1,
+			relationship = $2,
+			email = $3,
+			phone = $4,
+			email_opt_out = $5,
+			updated_at = now()
+		WHERE id = $6
+		RETURNING ` + guardianColumns
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	err := scanGuardian(s.db.QueryRowContext(
+		ctx,
+		query,
+		guardian.Name,
+		guardian.Relationship,
+		guardian.Email,
+		guardian.Phone,
+		guardian.EmailOptOut,
+		guardian.ID,
+	), guardian)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return parsePgError(err)
+	}
+
+	return nil
+}
+
+// Delete removes a guardian. Emails already sent to them stay in the outbox
+// history; any still pending are cancelled when the worker reaches them.
+func (s *GuardianStore) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM guardians WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func scanGuardian(row rowScanner, guardian *models.Guardian) error {
+	return row.Scan(
+		&guardian.ID,
+		&guardian.StudentID,
+		&guardian.Name,
+		&guardian.Relationship,
+		&guardian.Email,
+		&guardian.Phone,
+		&guardian.EmailOptOut,
+		&guardian.CreatedAt,
+		&guardian.UpdatedAt,
+	)
+}
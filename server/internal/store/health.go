@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+type HealthStore struct {
+	db *sql.DB
+}
+
+func (s *HealthStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// SchemaVersion reads the migration state recorded by golang-migrate.
+func (s *HealthStore) SchemaVersion(ctx context.Context) (int, bool, error) {
+	query := `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+	var (
+		version int
+		dirty   bool
+	)
+
+	err := s.db.QueryRowContext(ctx, query).Scan(&version, &dirty)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	return version, dirty, nil
+}
+
+func (s *HealthStore) Stats() sql.DBStats {
+	return s.db.Stats()
+}
@@ -0,0 +1,72 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+)
+
+func TestHealthStore(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	if err := s.Health.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	version, dirty, err := s.Health.SchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("SchemaVersion: %v", err)
+	}
+	if uint(version) != testSchemaVersion || dirty {
+		t.Errorf("SchemaVersion = %d, dirty %v, want %d clean", version, dirty, testSchemaVersion)
+	}
+
+	if stats := s.Health.Stats(); stats.MaxOpenConnections != 10 {
+		t.Errorf("max open connections = %d, want 10", stats.MaxOpenConnections)
+	}
+}
+
+func TestMetricsStore(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	createTestEnrollment(t, s, "Maria")
+	withdrawn := createTestEnrollment(t, s, "Jose")
+	createTestPayment(t, s, withdrawn.ID, "OR-0001", 2000)
+
+	withdrawal := &models.Withdrawal{
+		EnrollmentID:   withdrawn.ID,
+		WithdrawalDate: time.Date(2025, time.August, 15, 0, 0, 0, 0, time.UTC),
+	}
+	if err := s.Enrollments.Withdraw(ctx, withdrawal); err != nil {
+		t.Fatalf("Withdraw: %v", err)
+	}
+
+	metrics, err := s.Metrics.GetBusinessMetrics(ctx)
+	if err != nil {
+		t.Fatalf("GetBusinessMetrics: %v", err)
+	}
+
+	want := []models.EnrollmentCount{
+		{SchoolYear: testSchoolYear, Status: constants.Enrolled, Count: 1},
+		{SchoolYear: testSchoolYear, Status: constants.Withdrawn, Count: 1},
+	}
+	if len(metrics.Enrollments) != len(want) {
+		t.Fatalf("enrollment counts = %+v, want %+v", metrics.Enrollments, want)
+	}
+	for i := range want {
+		if metrics.Enrollments[i] != want[i] {
+			t.Errorf("enrollment counts = %+v, want %+v", metrics.Enrollments, want)
+		}
+	}
+
+	// 12,500 for the enrolled student and 5,500 billed to the withdrawn
+	// one, less the 2,000 it paid.
+	assertDecimal(t, "outstanding receivables", metrics.OutstandingReceivables, 16000)
+}
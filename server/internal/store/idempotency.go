@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+)
+
+type IdempotencyStore struct {
+	db *sql.DB
+}
+
+// Claim reserves a key for the calling request until record.LockedUntil. It
+// returns true when the key was free, had expired, or was left in progress by
+// an identical request whose lock has lapsed; otherwise the stored record is
+// loaded into record, or ErrNotFound returned if the holder released the key
+// in the meantime. The insert is a single statement, so concurrent duplicates
+// cannot both win.
+func (s *IdempotencyStore) Claim(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (key, method, path, request_hash, status, locked_until, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (key, method, path) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			status = EXCLUDED.status,
+			response_status = NULL,
+			response_headers = NULL,
+			response_body = NULL,
+			created_at = now(),
+			locked_until = EXCLUDED.locked_until,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < now() OR (
+			idempotency_keys.status = EXCLUDED.status AND
+			idempotency_keys.locked_until < now() AND
+			idempotency_keys.request_hash = EXCLUDED.request_hash
+		)
+		RETURNING created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		record.Key,
+		record.Method,
+		record.Path,
+		record.RequestHash,
+		constants.IdempotencyInProgress,
+		record.LockedUntil,
+		record.ExpiresAt,
+	).Scan(&record.CreatedAt)
+	if err == nil {
+		record.Status = constants.IdempotencyInProgress
+		return true, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	return false, s.get(ctx, record)
+}
+
+func (s *IdempotencyStore) Get(ctx context.Context, record *models.IdempotencyRecord) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	return s.get(ctx, record)
+}
+
+func (s *IdempotencyStore) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status = $1, response_status = $2, response_headers = $3, response_body = $4
+		WHERE key = $5 AND method = $6 AND path = $7
+	`
+
+	headers, err := json.Marshal(record.ResponseHeaders)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	_, err = s.db.ExecContext(
+		ctx,
+		query,
+		constants.IdempotencyCompleted,
+		record.ResponseStatus,
+		headers,
+		record.ResponseBody,
+		record.Key,
+		record.Method,
+		record.Path,
+	)
+	if err != nil {
+		return err
+	}
+
+	record.Status = constants.IdempotencyCompleted
+
+	return nil
+}
+
+// Release frees a key still in progress so that a retry can run the request
+// again, used when the original attempt failed on the server side.
+func (s *IdempotencyStore) Release(ctx context.Context, record *models.IdempotencyRecord) error {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE key = $1 AND method = $2 AND path = $3 AND status = $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, record.Key, record.Method, record.Path, constants.IdempotencyInProgress)
+
+	return err
+}
+
+func (s *IdempotencyStore) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE expires_at < now()`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (s *IdempotencyStore) get(ctx context.Context, record *models.IdempotencyRecord) error {
+	query := `
+		SELECT request_hash, status, COALESCE(response_status, 0), response_headers, response_body,
+			created_at, locked_until, expires_at
+		FROM idempotency_keys
+		WHERE key = $1 AND method = $2 AND path = $3
+	`
+
+	var headers []byte
+
+	err := s.db.QueryRowContext(ctx, query, record.Key, record.Method, record.Path).Scan(
+		&record.RequestHash,
+		&record.Status,
+		&record.ResponseStatus,
+		&headers,
+		&record.ResponseBody,
+		&record.CreatedAt,
+		&record.LockedUntil,
+		&record.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &record.ResponseHeaders); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+)
+
+func newTestIdempotencyRecord(key string, ttl time.Duration) *models.IdempotencyRecord {
+	return &models.IdempotencyRecord{
+		Key:         key,
+		Method:      http.MethodPost,
+		Path:        "/api/enrollments/new",
+		RequestHash: "4d967a30111bf29f0eba01c448b375c1629b2fed01cdfcc3aed91f1b57d5dd5e",
+		LockedUntil: time.Now().Add(time.Minute),
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+}
+
+func TestIdempotencyStoreLifecycle(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	record := newTestIdempotencyRecord("retry-1", time.Hour)
+
+	claimed, err := s.Idempotency.Claim(ctx, record)
+	if err != nil || !claimed {
+		t.Fatalf("Claim = %v, %v, want the key claimed", claimed, err)
+	}
+
+	duplicate := newTestIdempotencyRecord("retry-1", time.Hour)
+	claimed, err = s.Idempotency.Claim(ctx, duplicate)
+	if err != nil || claimed {
+		t.Fatalf("second Claim = %v, %v, want the key already taken", claimed, err)
+	}
+	if duplicate.Status != constants.IdempotencyInProgress {
+		t.Errorf("duplicate status = %q, want in_progress", duplicate.Status)
+	}
+
+	record.ResponseStatus = http.StatusCreated
+	record.ResponseHeaders = map[string]string{"Content-Type": "application/json"}
+	record.ResponseBody = []byte(`{"data":{}}`)
+	if err := s.Idempotency.Complete(ctx, record); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	// Completed keys are kept; only keys still in progress are released.
+	if err := s.Idempotency.Release(ctx, record); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	stored := newTestIdempotencyRecord("retry-1", time.Hour)
+	if err := s.Idempotency.Get(ctx, stored); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Status != constants.IdempotencyCompleted || stored.ResponseStatus != http.StatusCreated ||
+		stored.ResponseHeaders["Content-Type"] != "application/json" || string(stored.ResponseBody) != `{"data":{}}` {
+		t.Errorf("Get = %+v, want the completed response", stored)
+	}
+}
+
+func TestIdempotencyStoreRelease(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	record := newTestIdempotencyRecord("retry-1", time.Hour)
+	if _, err := s.Idempotency.Claim(ctx, record); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+
+	if err := s.Idempotency.Release(ctx, record); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	assertErr(t, s.Idempotency.Get(ctx, record), ErrNotFound)
+}
+
+func TestIdempotencyStoreExpiry(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	expired := newTestIdempotencyRecord("retry-1", -time.Hour)
+	if _, err := s.Idempotency.Claim(ctx, expired); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+
+	// An expired key can be claimed again by a new request.
+	reused := newTestIdempotencyRecord("retry-1", -time.Hour)
+	claimed, err := s.Idempotency.Claim(ctx, reused)
+	if err != nil || !claimed {
+		t.Fatalf("Claim of an expired key = %v, %v, want it claimed", claimed, err)
+	}
+
+	if _, err := s.Idempotency.Claim(ctx, newTestIdempotencyRecord("retry-2", time.Hour)); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+
+	deleted, err := s.Idempotency.DeleteExpired(ctx)
+	if err != nil {
+		t.Fatalf("DeleteExpired: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("DeleteExpired removed %d keys, want 1", deleted)
+	}
+}
+
+func TestIdempotencyStoreLapsedLock(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	// The request holding the key died without completing or releasing it.
+	abandoned := newTestIdempotencyRecord("retry-1", time.Hour)
+	abandoned.LockedUntil = time.Now().Add(-time.Minute)
+	if _, err := s.Idempotency.Claim(ctx, abandoned); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+
+	other := newTestIdempotencyRecord("retry-1", time.Hour)
+	other.RequestHash = "0000000000000000000000000000000000000000000000000000000000000000"
+	claimed, err := s.Idempotency.Claim(ctx, other)
+	if err != nil || claimed {
+		t.Fatalf("Claim with another request = %v, %v, want the key left to the original", claimed, err)
+	}
+
+	retry := newTestIdempotencyRecord("retry-1", time.Hour)
+	claimed, err = s.Idempotency.Claim(ctx, retry)
+	if err != nil || !claimed {
+		t.Fatalf("Claim of a lapsed key = %v, %v, want it taken over", claimed, err)
+	}
+
+	// The new lock holds off further retries.
+	claimed, err = s.Idempotency.Claim(ctx, newTestIdempotencyRecord("retry-1", time.Hour))
+	if err != nil || claimed {
+		t.Fatalf("Claim of a locked key = %v, %v, want it taken", claimed, err)
+	}
+
+	// A completed response is never taken over, however old its lock.
+	retry.ResponseStatus = http.StatusCreated
+	if err := s.Idempotency.Complete(ctx, retry); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if _, err := testDB.ExecContext(ctx, `UPDATE idempotency_keys SET locked_until = now() - interval '1 hour'`); err != nil {
+		t.Fatalf("lapsing lock: %v", err)
+	}
+
+	replay := newTestIdempotencyRecord("retry-1", time.Hour)
+	claimed, err = s.Idempotency.Claim(ctx, replay)
+	if err != nil || claimed || replay.Status != constants.IdempotencyCompleted {
+		t.Fatalf("Claim of a completed key = %v, %v with status %q, want the stored response", claimed, err, replay.Status)
+	}
+}
+
+func TestIdempotencyStoreConcurrentClaims(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	const attempts = 10
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		winners int
+	)
+
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			claimed, err := s.Idempotency.Claim(ctx, newTestIdempotencyRecord("retry-1", time.Hour))
+			if err != nil {
+				t.Errorf("Claim: %v", err)
+				return
+			}
+
+			if claimed {
+				mu.Lock()
+				winners++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if winners != 1 {
+		t.Errorf("%d concurrent claims won, want exactly 1", winners)
+	}
+}
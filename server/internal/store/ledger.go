@@ -0,0 +1,586 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ledgerSource identifies the document a journal entry is posted for.
+type ledgerSource struct {
+	Type        string
+	ID          uuid.UUID
+	Date        time.Time
+	Description string
+}
+
+type LedgerStore struct {
+	db *sql.DB
+}
+
+func (s *LedgerStore) GetAccounts(ctx context.Context) ([]models.Account, error) {
+	query := `
+		SELECT id, code, name, type, created_at
+		FROM accounts
+		ORDER BY code
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var accounts []models.Account
+
+	for rows.Next() {
+		var account models.Account
+		err := rows.Scan(
+			&account.ID,
+			&account.Code,
+			&account.Name,
+			&account.Type,
+			&account.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+func (s *LedgerStore) GetTrialBalance(ctx context.Context, asOf time.Time) (models.TrialBalance, error) {
+	query := `
+		SELECT
+			a.code,
+			a.name,
+			a.type,
+			COALESCE(SUM(jl.debit), 0) - COALESCE(SUM(jl.credit), 0) AS balance
+		FROM accounts a
+		LEFT JOIN journal_lines jl ON jl.account_id = a.id
+			AND EXISTS (
+				SELECT 1 FROM journal_entries je
+				WHERE je.id = jl.journal_entry_id AND je.entry_date <= $1
+			)
+		GROUP BY a.id, a.code, a.name, a.type
+		ORDER BY a.code
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	trialBalance := models.TrialBalance{
+		AsOf:        asOf,
+		TotalDebit:  decimal.Zero,
+		TotalCredit: decimal.Zero,
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, asOf)
+	if err != nil {
+		return trialBalance, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			row     models.TrialBalanceRow
+			balance decimal.Decimal
+		)
+		err := rows.Scan(
+			&row.Code,
+			&row.Name,
+			&row.Type,
+			&balance,
+		)
+		if err != nil {
+			return trialBalance, err
+		}
+
+		row.Debit = decimal.Zero
+		row.Credit = decimal.Zero
+		if balance.IsPositive() {
+			row.Debit = balance
+		} else {
+			row.Credit = balance.Neg()
+		}
+
+		trialBalance.TotalDebit = trialBalance.TotalDebit.Add(row.Debit)
+		trialBalance.TotalCredit = trialBalance.TotalCredit.Add(row.Credit)
+		trialBalance.Accounts = append(trialBalance.Accounts, row)
+	}
+	if err := rows.Err(); err != nil {
+		return trialBalance, err
+	}
+
+	trialBalance.Balanced = trialBalance.TotalDebit.Equal(trialBalance.TotalCredit)
+
+	return trialBalance, nil
+}
+
+// CreateAdjustment records a manual adjusting entry. Adjusting entries are the
+// only postings allowed into a closed month.
+func (s *LedgerStore) CreateAdjustment(ctx context.Context, entry *models.JournalEntry) error {
+	totalDebit, totalCredit := decimal.Zero, decimal.Zero
+	for _, line := range entry.Lines {
+		if line.Debit.IsNegative() || line.Credit.IsNegative() || line.Debit.IsZero() == line.Credit.IsZero() {
+			return ErrUnbalancedEntry
+		}
+		totalDebit = totalDebit.Add(line.Debit)
+		totalCredit = totalCredit.Add(line.Credit)
+	}
+
+	if len(entry.Lines) < 2 || !totalDebit.Equal(totalCredit) {
+		return ErrUnbalancedEntry
+	}
+
+	entry.ID = uuid.New()
+	entry.SourceType = constants.SourceAdjustment
+	entry.SourceID = entry.ID
+	entry.IsAdjustment = true
+
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO journal_entries
+				(id, entry_date, description, source_type, source_id, is_adjustment, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING created_at`,
+			entry.ID,
+			entry.EntryDate,
+			entry.Description,
+			entry.SourceType,
+			entry.SourceID,
+			entry.IsAdjustment,
+			entry.CreatedBy,
+		).Scan(&entry.CreatedAt)
+		if err != nil {
+			return parsePgError(err)
+		}
+
+		for _, line := range entry.Lines {
+			res, err := tx.ExecContext(
+				ctx,
+				`INSERT INTO journal_lines (journal_entry_id, account_id, debit, credit)
+				SELECT $1, id, $2, $3 FROM accounts WHERE code = $4`,
+				entry.ID,
+				line.Debit,
+				line.Credit,
+				line.AccountCode,
+			)
+			if err != nil {
+				return parsePgError(err)
+			}
+
+			rows, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+
+			if rows == 0 {
+				return ErrUnknownAccount
+			}
+		}
+
+		return nil
+	})
+}
+
+// postLedgerEntry brings the ledger balances held by a source document in line
+// with target by posting one balanced journal entry for the difference. Target
+// amounts are keyed by account code, debits positive and credits negative, so
+// an empty target reverses everything previously posted for the source.
+func postLedgerEntry(ctx context.Context, tx *sql.Tx, source ledgerSource, target map[string]decimal.Decimal) error {
+	query := `
+		SELECT a.code, SUM(jl.debit) - SUM(jl.credit)
+		FROM journal_lines jl
+		JOIN journal_entries je ON je.id = jl.journal_entry_id
+		JOIN accounts a ON a.id = jl.account_id
+		WHERE je.source_type = $1 AND je.source_id = $2
+		GROUP BY a.code
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := tx.QueryContext(ctx, query, source.Type, source.ID)
+	if err != nil {
+		return err
+	}
+
+	posted := make(map[string]decimal.Decimal)
+	for rows.Next() {
+		var (
+			code    string
+			balance decimal.Decimal
+		)
+		if err := rows.Scan(&code, &balance); err != nil {
+			rows.Close()
+			return err
+		}
+		posted[code] = balance
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	lines := make(map[string]decimal.Decimal)
+	total := decimal.Zero
+	for code, amount := range target {
+		lines[code] = amount.Round(2)
+	}
+	for code, balance := range posted {
+		lines[code] = lines[code].Sub(balance)
+	}
+	for code, amount := range lines {
+		if amount.IsZero() {
+			delete(lines, code)
+			continue
+		}
+		total = total.Add(amount)
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	if !total.IsZero() {
+		return ErrUnbalancedEntry
+	}
+
+	var entryID uuid.UUID
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO journal_entries (entry_date, description, source_type, source_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`,
+		source.Date,
+		source.Description,
+		source.Type,
+		source.ID,
+	).Scan(&entryID)
+	if err != nil {
+		return parsePgError(err)
+	}
+
+	for code, amount := range lines {
+		debit, credit := decimal.Zero, decimal.Zero
+		if amount.IsPositive() {
+			debit = amount
+		} else {
+			credit = amount.Neg()
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO journal_lines (journal_entry_id, account_id, debit, credit)
+			SELECT $1, id, $2, $3 FROM accounts WHERE code = $4`,
+			entryID,
+			debit,
+			credit,
+			code,
+		)
+		if err != nil {
+			return parsePgError(err)
+		}
+	}
+
+	return nil
+}
+
+// syncEnrollmentLedger posts the billing, discount and refund entries of an
+// enrollment from its current state. Withdrawn enrollments are billed for the
+// months attended, waitlisted ones are not billed until they are promoted and
+// deleted enrollments are reversed.
+func syncEnrollmentLedger(ctx context.Context, tx *sql.Tx, enrollmentID uuid.UUID) error {
+	query := `
+		SELECT
+			e.monthly_tuition * COALESCE(e.months_attended, e.months),
+			e.enrollment_fee,
+			e.misc_fee,
+			e.pta_fee,
+			e.lms_books_fee,
+			e.status,
+			e.deleted_at IS NOT NULL,
+			COALESCE((
+				SELECT SUM(COALESCE(tp.reservation_fee, 0) + COALESCE(tp.tuition_fee, 0) + COALESCE(tp.advance_payment, 0))
+				FROM tuition_payments tp
+				WHERE tp.enrollment_id = e.id AND tp.deleted_at IS NULL
+			), 0)
+		FROM enrollments e
+		WHERE e.id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var (
+		tuition, enrollmentFee, miscFee, ptaFee, lmsFee, totalPaid decimal.Decimal
+		status                                                     string
+		deleted                                                    bool
+	)
+
+	err := tx.QueryRowContext(ctx, query, enrollmentID).Scan(
+		&tuition,
+		&enrollmentFee,
+		&miscFee,
+		&ptaFee,
+		&lmsFee,
+		&status,
+		&deleted,
+		&totalPaid,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	query = `
+		SELECT
+			d.scope,
+			SUM(
+				CASE WHEN d.scope = 'tuition'
+					THEN ROUND(COALESCE(d.amount, 0) * COALESCE(e.months_attended, e.months) / e.months, 2)
+					ELSE COALESCE(d.amount, 0)
+				END
+			)
+		FROM discounts d
+		JOIN enrollments e ON e.id = d.enrollment_id
+		WHERE d.enrollment_id = $1 AND d.deleted_at IS NULL
+		GROUP BY d.scope
+	`
+
+	rows, err := tx.QueryContext(ctx, query, enrollmentID)
+	if err != nil {
+		return err
+	}
+
+	discounts := make(map[string]decimal.Decimal)
+	for rows.Next() {
+		var (
+			scope  string
+			amount decimal.Decimal
+		)
+		if err := rows.Scan(&scope, &amount); err != nil {
+			rows.Close()
+			return err
+		}
+		discounts[scope] = amount
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	billing := map[string]decimal.Decimal{}
+	discount := map[string]decimal.Decimal{}
+	refund := map[string]decimal.Decimal{}
+
+	if !deleted && status != constants.Waitlisted {
+		billing = map[string]decimal.Decimal{
+			constants.AccountTuitionRevenue:    tuition.Neg(),
+			constants.AccountEnrollmentRevenue: enrollmentFee.Neg(),
+			constants.AccountMiscRevenue:       miscFee.Neg(),
+			constants.AccountPtaRevenue:        ptaFee.Neg(),
+			constants.AccountLmsBooksRevenue:   lmsFee.Neg(),
+			constants.AccountTuitionReceivable: tuition.Add(enrollmentFee).Add(miscFee).Add(ptaFee).Add(lmsFee),
+		}
+
+		totalDiscount := decimal.Zero
+		for scope, amount := range discounts {
+			discount[discountAccount(scope)] = discount[discountAccount(scope)].Add(amount)
+			totalDiscount = totalDiscount.Add(amount)
+		}
+		discount[constants.AccountTuitionReceivable] = totalDiscount.Neg()
+
+		if status == constants.Withdrawn {
+			billed := billing[constants.AccountTuitionReceivable].Sub(totalDiscount)
+			if overpaid := totalPaid.Sub(billed); overpaid.IsPositive() {
+				refund[constants.AccountTuitionReceivable] = overpaid
+				refund[constants.AccountRefundsPayable] = overpaid.Neg()
+			}
+		}
+	}
+
+	now := time.Now()
+	entries := []struct {
+		source ledgerSource
+		target map[string]decimal.Decimal
+	}{
+		{ledgerSource{constants.SourceEnrollment, enrollmentID, now, "Enrollment billing"}, billing},
+		{ledgerSource{constants.SourceDiscount, enrollmentID, now, "Enrollment discounts"}, discount},
+		{ledgerSource{constants.SourceRefund, enrollmentID, now, "Withdrawal refund"}, refund},
+	}
+
+	for _, entry := range entries {
+		if err := postLedgerEntry(ctx, tx, entry.source, entry.target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncPaymentLedger posts a payment against tuition receivable and refreshes
+// the refund owed on its enrollment.
+func syncPaymentLedger(ctx context.Context, tx *sql.Tx, paymentID uuid.UUID) error {
+	query := `
+		SELECT
+			enrollment_id,
+			invoice_number,
+			payment_date,
+			payment_method,
+			COALESCE(reservation_fee, 0) + COALESCE(tuition_fee, 0) + COALESCE(advance_payment, 0),
+			reservation_id IS NOT NULL,
+			deleted_at IS NOT NULL
+		FROM tuition_payments
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var (
+		enrollmentID  uuid.UUID
+		invoiceNumber string
+		paymentDate   time.Time
+		paymentMethod string
+		amount        decimal.Decimal
+		credit        bool
+		deleted       bool
+	)
+
+	err := tx.QueryRowContext(ctx, query, paymentID).Scan(
+		&enrollmentID,
+		&invoiceNumber,
+		&paymentDate,
+		&paymentMethod,
+		&amount,
+		&credit,
+		&deleted,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	// A reservation credit was collected when the reservation was made, so it
+	// is drawn from the deposits held rather than from cash.
+	debitAccount := cashAccount(paymentMethod)
+	if credit {
+		debitAccount = constants.AccountReservations
+	}
+
+	target := map[string]decimal.Decimal{}
+	if !deleted {
+		target[debitAccount] = amount
+		target[constants.AccountTuitionReceivable] = amount.Neg()
+	}
+
+	source := ledgerSource{constants.SourcePayment, paymentID, paymentDate, "Tuition payment " + invoiceNumber}
+	if err := postLedgerEntry(ctx, tx, source, target); err != nil {
+		return err
+	}
+
+	return syncEnrollmentLedger(ctx, tx, enrollmentID)
+}
+
+// syncReservationLedger posts a reservation fee as a deposit held for the
+// student, and moves it to revenue once the reservation expires or is
+// forfeited. A converted reservation's deposit is drawn down by the payment
+// that credits it.
+func syncReservationLedger(ctx context.Context, tx *sql.Tx, reservationID uuid.UUID) error {
+	query := `
+		SELECT invoice_number, fee, payment_method, reserved_on, status
+		FROM reservations
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var (
+		invoiceNumber, paymentMethod, status string
+		fee                                  decimal.Decimal
+		reservedOn                           time.Time
+	)
+
+	err := tx.QueryRowContext(ctx, query, reservationID).Scan(&invoiceNumber, &fee, &paymentMethod, &reservedOn, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	source := ledgerSource{constants.SourceReservation, reservationID, reservedOn, "Reservation fee " + invoiceNumber}
+	target := map[string]decimal.Decimal{
+		cashAccount(paymentMethod):    fee,
+		constants.AccountReservations: fee.Neg(),
+	}
+
+	if status == constants.Expired || status == constants.Forfeited {
+		source.Date = time.Now()
+		source.Description = "Forfeited reservation " + invoiceNumber
+		target = map[string]decimal.Decimal{
+			cashAccount(paymentMethod):        fee,
+			constants.AccountForfeitedRevenue: fee.Neg(),
+		}
+	}
+
+	return postLedgerEntry(ctx, tx, source, target)
+}
+
+// syncExpenseLedger posts an expense against the account it was paid from.
+func syncExpenseLedger(ctx context.Context, tx *sql.Tx, expense *models.Expense) error {
+	target := map[string]decimal.Decimal{
+		constants.AccountOperatingExpenses: expense.Amount,
+		cashAccount(expense.PaymentMethod): expense.Amount.Neg(),
+	}
+
+	source := ledgerSource{constants.SourceExpense, expense.ID, expense.ExpenseDate, expense.Category + ": " + expense.Description}
+
+	return postLedgerEntry(ctx, tx, source, target)
+}
+
+func cashAccount(paymentMethod string) string {
+	switch paymentMethod {
+	case constants.GCash:
+		return constants.AccountGCash
+	case constants.Bank:
+		return constants.AccountBank
+	default:
+		return constants.AccountCash
+	}
+}
+
+func discountAccount(scope string) string {
+	switch scope {
+	case constants.LmsBooks:
+		return constants.AccountLmsBooksDiscounts
+	case constants.Carpool:
+		return constants.AccountCarpoolDiscounts
+	default:
+		return constants.AccountTuitionDiscounts
+	}
+}
@@ -0,0 +1,235 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// accountBalance returns the debit balance of an account, negative when the
+// account carries a credit balance.
+func accountBalance(t *testing.T, s Storage, code string) decimal.Decimal {
+	t.Helper()
+
+	trialBalance, err := s.Ledger.GetTrialBalance(context.Background(), time.Now())
+	if err != nil {
+		t.Fatalf("GetTrialBalance: %v", err)
+	}
+
+	for _, row := range trialBalance.Accounts {
+		if row.Code == code {
+			return row.Debit.Sub(row.Credit)
+		}
+	}
+
+	t.Fatalf("account %s not in trial balance", code)
+	return decimal.Zero
+}
+
+func closeMonth(t *testing.T, s Storage, month time.Time) *models.ClosedPeriod {
+	t.Helper()
+
+	period := &models.ClosedPeriod{PeriodType: constants.PeriodMonth, PeriodMonth: &month, ClosedBy: "admin"}
+	if err := s.Periods.Close(context.Background(), period); err != nil {
+		t.Fatalf("closing %s: %v", month.Format("2006-01"), err)
+	}
+
+	return period
+}
+
+func closeSchoolYear(t *testing.T, s Storage, schoolYear string) *models.ClosedPeriod {
+	t.Helper()
+
+	period := &models.ClosedPeriod{PeriodType: constants.PeriodSchoolYear, SchoolYear: schoolYear, ClosedBy: "admin"}
+	if err := s.Periods.Close(context.Background(), period); err != nil {
+		t.Fatalf("closing %s: %v", schoolYear, err)
+	}
+
+	return period
+}
+
+func newTestExpense(amount int64) *models.Expense {
+	return &models.Expense{
+		ExpenseDate:   time.Date(2025, time.July, 3, 0, 0, 0, 0, time.UTC),
+		Category:      "supplies",
+		Description:   "bond paper",
+		Amount:        decimal.NewFromInt(amount),
+		PaymentMethod: constants.Cash,
+	}
+}
+
+func TestLedgerStoreGetAccounts(t *testing.T) {
+	s := newTestStorage(t)
+
+	accounts, err := s.Ledger.GetAccounts(context.Background())
+	if err != nil {
+		t.Fatalf("GetAccounts: %v", err)
+	}
+
+	if len(accounts) != 15 || accounts[0].Code != constants.AccountCash {
+		t.Fatalf("GetAccounts = %+v, want the 15 seeded accounts ordered by code", accounts)
+	}
+}
+
+func TestLedgerStoreGetTrialBalance(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	enrollment := createTestEnrollment(t, s, "Maria", constants.Sibling)
+	createTestPayment(t, s, enrollment.ID, "OR-0001", 2000)
+	if err := s.Expenses.Create(ctx, newTestExpense(750)); err != nil {
+		t.Fatalf("creating expense: %v", err)
+	}
+
+	trialBalance, err := s.Ledger.GetTrialBalance(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("GetTrialBalance: %v", err)
+	}
+
+	if !trialBalance.Balanced {
+		t.Errorf("trial balance not balanced: debit %s, credit %s", trialBalance.TotalDebit, trialBalance.TotalCredit)
+	}
+
+	assertDecimal(t, "tuition receivable", accountBalance(t, s, constants.AccountTuitionReceivable), 9500)
+	assertDecimal(t, "cash", accountBalance(t, s, constants.AccountCash), 1250)
+	assertDecimal(t, "tuition revenue", accountBalance(t, s, constants.AccountTuitionRevenue), -10000)
+	assertDecimal(t, "tuition discounts", accountBalance(t, s, constants.AccountTuitionDiscounts), 1000)
+	assertDecimal(t, "operating expenses", accountBalance(t, s, constants.AccountOperatingExpenses), 750)
+
+	// Only the payment and the expense are dated before today.
+	past, err := s.Ledger.GetTrialBalance(ctx, time.Date(2025, time.December, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetTrialBalance: %v", err)
+	}
+	if !past.Balanced || !past.TotalDebit.Equal(decimal.NewFromInt(2000)) {
+		t.Errorf("trial balance as of 2025 = %s debit, want 2000", past.TotalDebit)
+	}
+}
+
+func TestLedgerStoreCreateAdjustment(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	newEntry := func(lines ...models.JournalLine) *models.JournalEntry {
+		return &models.JournalEntry{
+			EntryDate:   time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC),
+			Description: "opening balance",
+			CreatedBy:   "admin",
+			Lines:       lines,
+		}
+	}
+	debit := func(code string, amount int64) models.JournalLine {
+		return models.JournalLine{AccountCode: code, Debit: decimal.NewFromInt(amount), Credit: decimal.Zero}
+	}
+	credit := func(code string, amount int64) models.JournalLine {
+		return models.JournalLine{AccountCode: code, Debit: decimal.Zero, Credit: decimal.NewFromInt(amount)}
+	}
+
+	// Adjustments are allowed into a closed month.
+	closeMonth(t, s, time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC))
+
+	entry := newEntry(debit(constants.AccountCash, 5000), credit("3000", 5000))
+	if err := s.Ledger.CreateAdjustment(ctx, entry); err != nil {
+		t.Fatalf("CreateAdjustment: %v", err)
+	}
+	if entry.CreatedAt.IsZero() || !entry.IsAdjustment {
+		t.Errorf("CreateAdjustment = %+v", entry)
+	}
+	assertDecimal(t, "cash", accountBalance(t, s, constants.AccountCash), 5000)
+
+	t.Run("unbalanced", func(t *testing.T) {
+		entry := newEntry(debit(constants.AccountCash, 100), credit("3000", 50))
+		assertErr(t, s.Ledger.CreateAdjustment(ctx, entry), ErrUnbalancedEntry)
+	})
+
+	t.Run("unknown account", func(t *testing.T) {
+		entry := newEntry(debit("9999", 100), credit("3000", 100))
+		assertErr(t, s.Ledger.CreateAdjustment(ctx, entry), ErrUnknownAccount)
+	})
+}
+
+func TestJournalBalanceTrigger(t *testing.T) {
+	newTestStorage(t)
+
+	// The deferred balance check fires at commit, after the store's own
+	// validation, so it is exercised with a hand-written entry.
+	err := withTx(context.Background(), testDB, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			WITH entry AS (
+				INSERT INTO journal_entries (entry_date, description, source_type, source_id, is_adjustment)
+				VALUES (CURRENT_DATE, 'one-sided', 'adjustment', gen_random_uuid(), true)
+				RETURNING id
+			)
+			INSERT INTO journal_lines (journal_entry_id, account_id, debit, credit)
+			SELECT entry.id, accounts.id, 100, 0
+			FROM entry, accounts
+			WHERE accounts.code = $1
+		`, constants.AccountCash)
+		return err
+	})
+
+	assertErr(t, err, ErrUnbalancedEntry)
+}
+
+func TestExpenseStore(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	expense := newTestExpense(750)
+	if err := s.Expenses.Create(ctx, expense); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	expenses, err := s.Expenses.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+
+	if len(expenses) != 1 || expenses[0].ID != expense.ID {
+		t.Fatalf("GetAll = %+v, want the created expense", expenses)
+	}
+	assertDecimal(t, "amount", expenses[0].Amount, 750)
+
+	t.Run("closed month", func(t *testing.T) {
+		closeMonth(t, s, time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC))
+
+		assertErr(t, s.Expenses.Create(ctx, newTestExpense(100)), ErrPeriodClosed)
+	})
+}
+
+func TestPeriodStore(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	june := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	month := closeMonth(t, s, june)
+	closeSchoolYear(t, s, testSchoolYear)
+
+	t.Run("already closed", func(t *testing.T) {
+		again := &models.ClosedPeriod{PeriodType: constants.PeriodMonth, PeriodMonth: &june, ClosedBy: "admin"}
+		assertErr(t, s.Periods.Close(ctx, again), ErrPeriodAlreadyClosed)
+
+		year := &models.ClosedPeriod{PeriodType: constants.PeriodSchoolYear, SchoolYear: testSchoolYear, ClosedBy: "admin"}
+		assertErr(t, s.Periods.Close(ctx, year), ErrPeriodAlreadyClosed)
+	})
+
+	periods, err := s.Periods.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(periods) != 2 {
+		t.Fatalf("GetAll returned %d periods, want 2", len(periods))
+	}
+
+	if err := s.Periods.Reopen(ctx, month.ID); err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	assertErr(t, s.Periods.Reopen(ctx, month.ID), ErrNotFound)
+}
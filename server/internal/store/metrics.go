@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+)
+
+type MetricsStore struct {
+	db *sql.DB
+}
+
+func (s *MetricsStore) GetBusinessMetrics(ctx context.Context) (models.BusinessMetrics, error) {
+	var metrics models.BusinessMetrics
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	enrollments, err := s.getEnrollmentCounts(ctx)
+	if err != nil {
+		return metrics, err
+	}
+	metrics.Enrollments = enrollments
+
+	// Receivables are read from the ledger so the gauge agrees with the
+	// trial balance.
+	query := `
+		SELECT COALESCE(SUM(jl.debit), 0) - COALESCE(SUM(jl.credit), 0)
+		FROM journal_lines jl
+		JOIN accounts a ON a.id = jl.account_id
+		WHERE a.code = $1
+	`
+
+	err = s.db.QueryRowContext(ctx, query, constants.AccountTuitionReceivable).Scan(&metrics.OutstandingReceivables)
+	if err != nil {
+		return metrics, err
+	}
+
+	return metrics, nil
+}
+
+func (s *MetricsStore) getEnrollmentCounts(ctx context.Context) ([]models.EnrollmentCount, error) {
+	query := `
+		SELECT school_year, status, COUNT(*)
+		FROM enrollments
+		WHERE deleted_at IS NULL
+		GROUP BY school_year, status
+		ORDER BY school_year, status
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var counts []models.EnrollmentCount
+
+	for rows.Next() {
+		var count models.EnrollmentCount
+		if err := rows.Scan(&count.SchoolYear, &count.Status, &count.Count); err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,391 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+)
+
+const notificationColumns = `
+	id, kind, guardian_id, enrollment_id, payment_id, recipient, data, status,
+	attempts, next_attempt_at, last_error, sent_at, created_at
+`
+
+// studentNameSQL is the name a student is addressed by in emails.
+const studentNameSQL = `CONCAT_WS(' ', s.first_name, s.last_name, NULLIF(s.suffix, ''))`
+
+type NotificationStore struct {
+	db *sql.DB
+}
+
+// GetAll lists the outbox, newest first, optionally only the notifications
+// at one status or about one enrollment.
+func (s *NotificationStore) GetAll(ctx context.Context, status string, enrollmentID *uuid.UUID) ([]models.Notification, error) {
+	query := `
+		SELECT ` + notificationColumns + `
+		FROM notification_outbox
+		WHERE ($1 = '' OR status = $1) AND ($2::uuid IS NULL OR enrollment_id = $2)
+		ORDER BY created_at DESC, id
+		LIMIT 500
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, status, enrollmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var notifications []models.Notification
+
+	for rows.Next() {
+		var notification models.Notification
+		if err := scanNotification(rows, &notification); err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+// EnqueueReminders queues an upcoming due reminder for the installment due
+// leadDays after today and an overdue notice for the last installment that
+// fell due before today, for every enrolled student who has not paid enough
+// to cover it. Installments are equal shares of an enrollment's total, due
+// on dueDay of each school month. Running it again the same day queues
+// nothing new.
+func (s *NotificationStore) EnqueueReminders(ctx context.Context, today time.Time, dueDay, leadDays int) (int, error) {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+
+	lastDue := time.Date(today.Year(), today.Month(), dueDay, 0, 0, 0, 0, time.UTC)
+	if !lastDue.Before(today) {
+		lastDue = lastDue.AddDate(0, -1, 0)
+	}
+
+	reminders := []struct {
+		kind    string
+		dueDate time.Time
+	}{
+		{constants.UpcomingDue, today.AddDate(0, 0, leadDays)},
+		{constants.Overdue, lastDue},
+	}
+
+	var queued int
+
+	err := withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		for _, reminder := range reminders {
+			schoolYear, installment, ok := dueInstallment(reminder.dueDate, dueDay)
+			if !ok {
+				continue
+			}
+
+			count, err := enqueueReminder(ctx, tx, reminder.kind, schoolYear, installment, reminder.dueDate)
+			if err != nil {
+				return err
+			}
+
+			queued += count
+		}
+
+		return nil
+	})
+
+	return queued, err
+}
+
+// Claim takes up to limit pending notifications that are due to be sent and
+// hides them from other workers for lease, after which they are retried if
+// the claiming worker never reported back. Notifications to guardians who
+// have since opted out or been removed are cancelled instead.
+func (s *NotificationStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.Notification, error) {
+	var notifications []models.Notification
+
+	err := withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		_, err := tx.ExecContext(ctx, `
+			UPDATE notification_outbox o
+			SET status = $1, last_error = 'guardian opted out or was removed', updated_at = now()
+			WHERE o.status = $2
+				AND NOT EXISTS (
+					SELECT 1 FROM guardians g
+					WHERE g.id = o.guardian_id AND NOT g.email_opt_out
+				)
+		`, constants.Cancelled, constants.Pending)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, `
+			WITH due AS (
+				SELECT id
+				FROM notification_outbox
+				WHERE status = $1 AND next_attempt_at <= now()
+				ORDER BY next_attempt_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			UPDATE notification_outbox o
+			SET
+				attempts = o.attempts + 1,
+				next_attempt_at = now() + make_interval(secs => $3),
+				updated_at = now()
+			FROM due
+			WHERE o.id = due.id
+			RETURNING
+				o.id, o.kind, o.guardian_id, o.enrollment_id, o.payment_id, o.recipient, o.data, o.status,
+				o.attempts, o.next_attempt_at, o.last_error, o.sent_at, o.created_at
+		`,
+			constants.Pending,
+			limit,
+			lease.Seconds(),
+		)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var notification models.Notification
+			if err := scanNotification(rows, &notification); err != nil {
+				return err
+			}
+
+			notifications = append(notifications, notification)
+		}
+
+		return rows.Err()
+	})
+
+	return notifications, err
+}
+
+func (s *NotificationStore) MarkSent(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE notification_outbox
+		SET status = $1, sent_at = now(), last_error = '', updated_at = now()
+		WHERE id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, constants.Sent, id)
+
+	return err
+}
+
+// MarkFailed records a failed send. The notification is retried after
+// retryAfter until it has been attempted maxAttempts times, when it is given
+// up on.
+func (s *NotificationStore) MarkFailed(ctx context.Context, id uuid.UUID, reason string, retryAfter time.Duration, maxAttempts int) error {
+	query := `
+		UPDATE notification_outbox
+		SET
+			status = CASE WHEN attempts >= $1 THEN $2 ELSE $3 END,
+			next_attempt_at = now() + make_interval(secs => $4),
+			last_error = $5,
+			updated_at = now()
+		WHERE id = $6
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		query,
+		maxAttempts,
+		constants.Failed,
+		constants.Pending,
+		retryAfter.Seconds(),
+		reason,
+		id,
+	)
+
+	return err
+}
+
+// Retry puts a notification that was given up on back in the queue with its
+// attempts reset.
+func (s *NotificationStore) Retry(ctx context.Context, id uuid.UUID) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		var status string
+		err := tx.QueryRowContext(ctx, `SELECT status FROM notification_outbox WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		if status != constants.Failed {
+			return ErrNotificationNotFailed
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE notification_outbox
+			SET status = $1, attempts = 0, next_attempt_at = now(), updated_at = now()
+			WHERE id = $2
+		`, constants.Pending, id)
+
+		return err
+	})
+}
+
+// enqueueReceipt queues a receipt for a payment to each guardian of the
+// student who can be emailed. Reservation credits are not new money and get
+// no receipt.
+func enqueueReceipt(ctx context.Context, tx *sql.Tx, paymentID uuid.UUID) error {
+	query := `
+		INSERT INTO notification_outbox (kind, guardian_id, enrollment_id, payment_id, recipient, data, dedupe_key)
+		SELECT
+			$1::text,
+			g.id,
+			e.id,
+			p.id,
+			g.email,
+			jsonb_build_object(
+				'guardian_name', g.name,
+				'student_name', ` + studentNameSQL + `,
+				'school_year', e.school_year,
+				'grade_level', e.grade_level,
+				'total_paid', t.total_paid::text,
+				'remaining_amount', t.remaining_amount::text,
+				'invoice_number', p.invoice_number,
+				'payment_date', p.payment_date,
+				'payment_method', p.payment_method,
+				'amount', (COALESCE(p.reservation_fee, 0) + COALESCE(p.tuition_fee, 0) + COALESCE(p.advance_payment, 0))::text
+			),
+			$1::text || ':' || p.id || ':' || g.id
+		FROM tuition_payments p
+		JOIN enrollments e ON e.id = p.enrollment_id
+		JOIN students s ON s.id = e.student_id
+		JOIN enrollment_totals t ON t.enrollment_id = e.id
+		JOIN guardians g ON g.student_id = s.id AND g.email IS NOT NULL AND NOT g.email_opt_out
+		WHERE p.id = $2 AND p.reservation_id IS NULL
+		ON CONFLICT (dedupe_key) DO NOTHING
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, constants.Receipt, paymentID)
+
+	return err
+}
+
+func enqueueReminder(ctx context.Context, tx *sql.Tx, kind, schoolYear string, installment int, dueDate time.Time) (int, error) {
+	query := `
+		WITH due AS (
+			SELECT
+				e.id,
+				e.student_id,
+				e.school_year,
+				e.grade_level,
+				` + studentNameSQL + ` AS student_name,
+				t.total_paid,
+				t.remaining_amount,
+				LEAST(t.total_amount, ROUND(t.total_amount * $3::int / e.months, 2)) AS due_amount
+			FROM enrollments e
+			JOIN students s ON s.id = e.student_id
+			JOIN enrollment_totals t ON t.enrollment_id = e.id
+			WHERE e.school_year = $2
+				AND e.status = $5
+				AND e.deleted_at IS NULL
+				AND e.months >= $3::int
+		)
+		INSERT INTO notification_outbox (kind, guardian_id, enrollment_id, recipient, data, dedupe_key)
+		SELECT
+			$1::text,
+			g.id,
+			d.id,
+			g.email,
+			jsonb_build_object(
+				'guardian_name', g.name,
+				'student_name', d.student_name,
+				'school_year', d.school_year,
+				'grade_level', d.grade_level,
+				'total_paid', d.total_paid::text,
+				'remaining_amount', d.remaining_amount::text,
+				'installment', $3::int,
+				'due_date', $4::date,
+				'amount_due', (d.due_amount - d.total_paid)::text
+			),
+			$1::text || ':' || d.id || ':' || $3::int || ':' || g.id
+		FROM due d
+		JOIN guardians g ON g.student_id = d.student_id AND g.email IS NOT NULL AND NOT g.email_opt_out
+		WHERE d.total_paid < d.due_amount
+		ON CONFLICT (dedupe_key) DO NOTHING
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, kind, schoolYear, installment, dueDate, constants.Enrolled)
+	if err != nil {
+		return 0, err
+	}
+
+	queued, err := result.RowsAffected()
+
+	return int(queued), err
+}
+
+// dueInstallment returns the school year and the number, counted from one in
+// the first school month, of the installment due on date.
+func dueInstallment(date time.Time, dueDay int) (string, int, bool) {
+	if date.Day() != dueDay {
+		return "", 0, false
+	}
+
+	startYear := date.Year()
+	if date.Month() < constants.SchoolYearStartMonth {
+		startYear--
+	}
+
+	installment := (date.Year()-startYear)*12 + int(date.Month()-constants.SchoolYearStartMonth) + 1
+
+	return fmt.Sprintf("%d-%d", startYear, startYear+1), installment, true
+}
+
+func scanNotification(row rowScanner, notification *models.Notification) error {
+	var data []byte
+
+	err := row.Scan(
+		&notification.ID,
+		&notification.Kind,
+		&notification.GuardianID,
+		&notification.EnrollmentID,
+		&notification.PaymentID,
+		&notification.Recipient,
+		&data,
+		&notification.Status,
+		&notification.Attempts,
+		&notification.NextAttemptAt,
+		&notification.LastError,
+		&notification.SentAt,
+		&notification.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, &notification.Data)
+}
@@ -0,0 +1,265 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+)
+
+func createTestGuardian(t *testing.T, s Storage, studentID uuid.UUID, name, email string) *models.Guardian {
+	t.Helper()
+
+	guardian := &models.Guardian{
+		StudentID:    studentID,
+		Name:         name,
+		Relationship: "mother",
+		Email:        &email,
+	}
+	if err := s.Guardians.Create(context.Background(), guardian); err != nil {
+		t.Fatalf("creating guardian: %v", err)
+	}
+
+	return guardian
+}
+
+func TestGuardianStore(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	enrollment := createTestEnrollment(t, s, "Maria")
+	studentID := enrollment.Student.ID
+
+	ana := createTestGuardian(t, s, studentID, "Ana Santos", "ana@example.com")
+	if ana.Phone == nil || *ana.Phone != "09171234567" {
+		t.Errorf("phone = %v, want the student's contact number", ana.Phone)
+	}
+
+	duplicate := &models.Guardian{StudentID: studentID, Name: "Ana", Relationship: "guardian", Email: new(string)}
+	*duplicate.Email = "ANA@example.com"
+	assertErr(t, s.Guardians.Create(ctx, duplicate), ErrDuplicateGuardian)
+
+	missing := &models.Guardian{StudentID: uuid.New(), Name: "Jose", Relationship: "father"}
+	assertErr(t, s.Guardians.Create(ctx, missing), ErrNotFound)
+
+	ana.EmailOptOut = true
+	if err := s.Guardians.Update(ctx, ana); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	guardians, err := s.Guardians.GetByStudentID(ctx, studentID)
+	if err != nil {
+		t.Fatalf("GetByStudentID: %v", err)
+	}
+	if len(guardians) != 1 || !guardians[0].EmailOptOut {
+		t.Errorf("guardians = %+v, want Ana opted out", guardians)
+	}
+
+	if err := s.Guardians.Delete(ctx, ana.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	_, err = s.Guardians.GetByID(ctx, ana.ID)
+	assertErr(t, err, ErrNotFound)
+	assertErr(t, s.Guardians.Delete(ctx, ana.ID), ErrNotFound)
+}
+
+func TestNotificationStoreReceipts(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	enrollment := createTestEnrollment(t, s, "Maria")
+	ana := createTestGuardian(t, s, enrollment.Student.ID, "Ana Santos", "ana@example.com")
+	jose := createTestGuardian(t, s, enrollment.Student.ID, "Jose Santos", "jose@example.com")
+
+	// Guardians without an email get nothing.
+	noEmail := &models.Guardian{StudentID: enrollment.Student.ID, Name: "Lola Santos", Relationship: "guardian"}
+	if err := s.Guardians.Create(ctx, noEmail); err != nil {
+		t.Fatalf("creating guardian: %v", err)
+	}
+
+	createTestPayment(t, s, enrollment.ID, "INV-001", 1000)
+
+	queued, err := s.Notifications.GetAll(ctx, constants.Pending, &enrollment.ID)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(queued) != 2 {
+		t.Fatalf("queued %d receipts, want one per guardian with an email", len(queued))
+	}
+
+	receipt := queued[0]
+	if receipt.Kind != constants.Receipt || receipt.Data.InvoiceNumber != "INV-001" || receipt.Data.StudentName != "Maria Santos" {
+		t.Errorf("receipt = %+v", receipt)
+	}
+	assertDecimal(t, "amount", receipt.Data.Amount, 1000)
+	assertDecimal(t, "remaining", receipt.Data.RemainingAmount, 11500)
+
+	// Jose opts out after the receipt was queued, so his copy is cancelled
+	// rather than sent.
+	jose.EmailOptOut = true
+	if err := s.Guardians.Update(ctx, jose); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	claimed, err := s.Notifications.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if len(claimed) != 1 || *claimed[0].GuardianID != ana.ID || claimed[0].Attempts != 1 {
+		t.Fatalf("claimed = %+v, want Ana's receipt on its first attempt", claimed)
+	}
+
+	// A claimed notification is leased and not handed out again.
+	again, err := s.Notifications.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if len(again) != 0 {
+		t.Errorf("claimed %d notifications again during the lease", len(again))
+	}
+
+	if err := s.Notifications.MarkSent(ctx, claimed[0].ID); err != nil {
+		t.Fatalf("MarkSent: %v", err)
+	}
+
+	for status, want := range map[string]int{constants.Sent: 1, constants.Cancelled: 1, constants.Pending: 0} {
+		notifications, err := s.Notifications.GetAll(ctx, status, nil)
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+		if len(notifications) != want {
+			t.Errorf("%s notifications = %d, want %d", status, len(notifications), want)
+		}
+	}
+}
+
+func TestNotificationStoreReminders(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	// Each of the ten installments is 1,250. Maria has paid 1,000, short of
+	// the first; Jose has paid 3,000, covering the second.
+	maria := createTestEnrollment(t, s, "Maria")
+	createTestGuardian(t, s, maria.Student.ID, "Ana Santos", "ana@example.com")
+	createTestPayment(t, s, maria.ID, "INV-001", 1000)
+
+	jose := createTestEnrollment(t, s, "Jose")
+	createTestGuardian(t, s, jose.Student.ID, "Rosa Cruz", "rosa@example.com")
+	createTestPayment(t, s, jose.ID, "INV-002", 3000)
+
+	// On 2 July the second installment is due in three days and the first
+	// fell due on 5 June.
+	today := time.Date(2025, time.July, 2, 10, 0, 0, 0, time.UTC)
+
+	queued, err := s.Notifications.EnqueueReminders(ctx, today, 5, 3)
+	if err != nil {
+		t.Fatalf("EnqueueReminders: %v", err)
+	}
+	if queued != 2 {
+		t.Fatalf("queued %d reminders, want an upcoming and an overdue for Maria", queued)
+	}
+
+	queued, err = s.Notifications.EnqueueReminders(ctx, today, 5, 3)
+	if err != nil {
+		t.Fatalf("EnqueueReminders: %v", err)
+	}
+	if queued != 0 {
+		t.Errorf("queued %d reminders on a second run, want none", queued)
+	}
+
+	notifications, err := s.Notifications.GetAll(ctx, constants.Pending, &maria.ID)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+
+	byKind := make(map[string]models.Notification)
+	for _, notification := range notifications {
+		byKind[notification.Kind] = notification
+	}
+
+	upcoming := byKind[constants.UpcomingDue]
+	if upcoming.Data.Installment != 2 || upcoming.Data.DueDate != "2025-07-05" {
+		t.Errorf("upcoming = %+v, want the second installment due 5 July", upcoming.Data)
+	}
+	assertDecimal(t, "upcoming amount due", upcoming.Data.AmountDue, 1500)
+
+	overdue := byKind[constants.Overdue]
+	if overdue.Data.Installment != 1 || overdue.Data.DueDate != "2025-06-05" {
+		t.Errorf("overdue = %+v, want the first installment due 5 June", overdue.Data)
+	}
+	assertDecimal(t, "overdue amount due", overdue.Data.AmountDue, 250)
+
+	// Nothing is due between due dates.
+	queued, err = s.Notifications.EnqueueReminders(ctx, time.Date(2025, time.July, 10, 0, 0, 0, 0, time.UTC), 5, 3)
+	if err != nil {
+		t.Fatalf("EnqueueReminders: %v", err)
+	}
+	if queued != 0 {
+		t.Errorf("queued %d reminders between due dates, want none", queued)
+	}
+}
+
+func TestNotificationStoreFailures(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	enrollment := createTestEnrollment(t, s, "Maria")
+	createTestGuardian(t, s, enrollment.Student.ID, "Ana Santos", "ana@example.com")
+	createTestPayment(t, s, enrollment.ID, "INV-001", 1000)
+
+	claimed, err := s.Notifications.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if len(claimed) != 1 {
+		t.Fatalf("claimed %d notifications, want 1", len(claimed))
+	}
+	id := claimed[0].ID
+
+	assertErr(t, s.Notifications.Retry(ctx, id), ErrNotificationNotFailed)
+
+	// With attempts left the send is retried once the delay passes.
+	if err := s.Notifications.MarkFailed(ctx, id, "connection refused", 0, 2); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+
+	claimed, err = s.Notifications.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].Attempts != 2 || claimed[0].LastError != "connection refused" {
+		t.Fatalf("claimed = %+v, want the second attempt", claimed)
+	}
+
+	// The last attempt failing gives up on it until it is retried by hand.
+	if err := s.Notifications.MarkFailed(ctx, id, "connection refused", 0, 2); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+
+	failed, err := s.Notifications.GetAll(ctx, constants.Failed, nil)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(failed) != 1 || failed[0].ID != id {
+		t.Fatalf("failed = %+v, want the receipt", failed)
+	}
+
+	if err := s.Notifications.Retry(ctx, id); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+
+	claimed, err = s.Notifications.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].Attempts != 1 {
+		t.Errorf("claimed = %+v, want a fresh first attempt", claimed)
+	}
+
+	assertErr(t, s.Notifications.Retry(ctx, uuid.New()), ErrNotFound)
+}
@@ -0,0 +1,361 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+)
+
+type PaymentStore struct {
+	db *sql.DB
+}
+
+func (s *PaymentStore) Create(ctx context.Context, payment *models.Payment) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := checkNotWaitlisted(ctx, tx, payment.EnrollmentID); err != nil {
+			return err
+		}
+
+		if err := s.createPayment(ctx, tx, payment); err != nil {
+			return err
+		}
+
+		if err := checkPaymentPeriodOpen(ctx, tx, payment.ID); err != nil {
+			return err
+		}
+
+		if err := syncPaymentLedger(ctx, tx, payment.ID); err != nil {
+			return err
+		}
+
+		return enqueueReceipt(ctx, tx, payment.ID)
+	})
+}
+
+func (s *PaymentStore) GetByID(ctx context.Context, id uuid.UUID) (models.Payment, error) {
+	query := `
+		SELECT id, enrollment_id, invoice_number, payment_date, payment_method,
+			COALESCE(reservation_fee, 0), COALESCE(tuition_fee, 0), COALESCE(advance_payment, 0),
+			COALESCE(notes, ''), reservation_id, created_at, updated_at
+		FROM tuition_payments
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var payment models.Payment
+
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&payment.ID,
+		&payment.EnrollmentID,
+		&payment.InvoiceNumber,
+		&payment.PaymentDate,
+		&payment.PaymentMethod,
+		&payment.ReservationFee,
+		&payment.TuitionFee,
+		&payment.AdvancePayment,
+		&payment.Notes,
+		&payment.ReservationID,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return payment, ErrNotFound
+		default:
+			return payment, err
+		}
+	}
+
+	return payment, nil
+}
+
+func (s *PaymentStore) GetByEnrollmentID(ctx context.Context, enrollmentID uuid.UUID) ([]models.Payment, error) {
+	query := `
+		SELECT id, enrollment_id, invoice_number, payment_date, payment_method,
+			COALESCE(reservation_fee, 0), COALESCE(tuition_fee, 0), COALESCE(advance_payment, 0),
+			COALESCE(notes, ''), reservation_id, created_at, updated_at
+		FROM tuition_payments
+		WHERE enrollment_id = $1 AND deleted_at IS NULL
+		ORDER BY payment_date DESC, created_at DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, enrollmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var payments []models.Payment
+
+	for rows.Next() {
+		var payment models.Payment
+		err := rows.Scan(
+			&payment.ID,
+			&payment.EnrollmentID,
+			&payment.InvoiceNumber,
+			&payment.PaymentDate,
+			&payment.PaymentMethod,
+			&payment.ReservationFee,
+			&payment.TuitionFee,
+			&payment.AdvancePayment,
+			&payment.Notes,
+			&payment.ReservationID,
+			&payment.CreatedAt,
+			&payment.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
+
+func (s *PaymentStore) Update(ctx context.Context, payment *models.Payment) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := checkPaymentPeriodOpen(ctx, tx, payment.ID); err != nil {
+			return err
+		}
+
+		if err := s.checkReservationCredit(ctx, tx, payment.ID); err != nil {
+			return err
+		}
+
+		if err := checkInvoiceNotReserved(ctx, tx, payment.InvoiceNumber); err != nil {
+			return err
+		}
+
+		if err := s.updatePayment(ctx, tx, payment); err != nil {
+			return err
+		}
+
+		if err := checkPaymentPeriodOpen(ctx, tx, payment.ID); err != nil {
+			return err
+		}
+
+		return syncPaymentLedger(ctx, tx, payment.ID)
+	})
+}
+
+func (s *PaymentStore) Delete(ctx context.Context, id uuid.UUID) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := checkPaymentPeriodOpen(ctx, tx, id); err != nil {
+			return err
+		}
+
+		if err := s.checkReservationCredit(ctx, tx, id); err != nil {
+			return err
+		}
+
+		if err := s.softDeletePayment(ctx, tx, id); err != nil {
+			return err
+		}
+
+		return syncPaymentLedger(ctx, tx, id)
+	})
+}
+
+func (s *PaymentStore) createPayment(ctx context.Context, tx *sql.Tx, payment *models.Payment) error {
+	if err := checkInvoiceNotReserved(ctx, tx, payment.InvoiceNumber); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO tuition_payments
+			(enrollment_id, invoice_number, payment_date, payment_method,
+			reservation_fee, tuition_fee, advance_payment, notes, reservation_id)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9
+		FROM enrollments
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, created_at, updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		payment.EnrollmentID,
+		payment.InvoiceNumber,
+		payment.PaymentDate,
+		payment.PaymentMethod,
+		payment.ReservationFee,
+		payment.TuitionFee,
+		payment.AdvancePayment,
+		payment.Notes,
+		payment.ReservationID,
+	).Scan(
+		&payment.ID,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return parsePgError(err)
+	}
+
+	return nil
+}
+
+// checkInvoiceNotReserved rejects an invoice number held by an open
+// reservation, which needs it for the payment that credits its fee on
+// conversion.
+func checkInvoiceNotReserved(ctx context.Context, tx *sql.Tx, invoiceNumber string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var reserved bool
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM reservations WHERE invoice_number = $1 AND status = $2)`,
+		invoiceNumber,
+		constants.Reserved,
+	).Scan(&reserved)
+	if err != nil {
+		return err
+	}
+
+	if reserved {
+		return ErrDuplicateInvoice
+	}
+
+	return nil
+}
+
+func (s *PaymentStore) updatePayment(ctx context.Context, tx *sql.Tx, payment *models.Payment) error {
+	query := `
+		UPDATE tuition_payments
+		SET
+			invoice_number = $1,
+			payment_date = $2,
+			payment_method = $3,
+			reservation_fee = $4,
+			tuition_fee = $5,
+			advance_payment = $6,
+			notes = $7,
+			updated_at = now()
+		WHERE id = $8 AND deleted_at IS NULL
+		RETURNING enrollment_id, created_at, updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		payment.InvoiceNumber,
+		payment.PaymentDate,
+		payment.PaymentMethod,
+		payment.ReservationFee,
+		payment.TuitionFee,
+		payment.AdvancePayment,
+		payment.Notes,
+		payment.ID,
+	).Scan(
+		&payment.EnrollmentID,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return parsePgError(err)
+	}
+
+	return nil
+}
+
+func (s *PaymentStore) softDeletePayment(ctx context.Context, tx *sql.Tx, id uuid.UUID) error {
+	query := `
+		UPDATE tuition_payments
+		SET deleted_at = now(), updated_at = now()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	res, err := tx.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// checkNotWaitlisted rejects payments for a waitlisted enrollment. It is not
+// billed until it is promoted, so anything paid would show as overpaid.
+func checkNotWaitlisted(ctx context.Context, tx *sql.Tx, enrollmentID uuid.UUID) error {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM enrollments
+			WHERE id = $1 AND status = $2 AND deleted_at IS NULL
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var waitlisted bool
+	if err := tx.QueryRowContext(ctx, query, enrollmentID, constants.Waitlisted).Scan(&waitlisted); err != nil {
+		return err
+	}
+
+	if waitlisted {
+		return ErrWaitlisted
+	}
+
+	return nil
+}
+
+// checkReservationCredit rejects changes to the payment that credits a
+// converted reservation. The fee belongs to the reservation, which has
+// already been closed.
+func (s *PaymentStore) checkReservationCredit(ctx context.Context, tx *sql.Tx, id uuid.UUID) error {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM tuition_payments
+			WHERE id = $1 AND reservation_id IS NOT NULL
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var credit bool
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&credit); err != nil {
+		return err
+	}
+
+	if credit {
+		return ErrReservationCredit
+	}
+
+	return nil
+}
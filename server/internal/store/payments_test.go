@@ -0,0 +1,137 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestPaymentStoreCreate(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	enrollment := createTestEnrollment(t, s, "Maria")
+	payment := createTestPayment(t, s, enrollment.ID, "OR-0001", 2000)
+
+	stored, err := s.Payments.GetByID(ctx, payment.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if stored.InvoiceNumber != "OR-0001" || stored.EnrollmentID != enrollment.ID {
+		t.Errorf("GetByID = %+v", stored)
+	}
+	assertDecimal(t, "tuition fee", stored.TuitionFee, 2000)
+	assertDecimal(t, "reservation fee", stored.ReservationFee, 0)
+
+	t.Run("duplicate invoice number", func(t *testing.T) {
+		duplicate := newTestPayment(enrollment.ID, "OR-0001", 500)
+		assertErr(t, s.Payments.Create(ctx, duplicate), ErrDuplicateInvoice)
+	})
+
+	t.Run("zero amount", func(t *testing.T) {
+		empty := newTestPayment(enrollment.ID, "OR-0002", 0)
+		assertErr(t, s.Payments.Create(ctx, empty), ErrInvalidPayment)
+	})
+
+	t.Run("missing enrollment", func(t *testing.T) {
+		orphan := newTestPayment(uuid.New(), "OR-0003", 500)
+		assertErr(t, s.Payments.Create(ctx, orphan), ErrNotFound)
+	})
+}
+
+func TestPaymentStoreGetByEnrollmentID(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	enrollment := createTestEnrollment(t, s, "Maria")
+	createTestEnrollment(t, s, "Jose")
+
+	first := createTestPayment(t, s, enrollment.ID, "OR-0001", 2000)
+
+	second := newTestPayment(enrollment.ID, "OR-0002", 1500)
+	second.PaymentDate = second.PaymentDate.AddDate(0, 1, 0)
+	second.PaymentMethod = constants.GCash
+	if err := s.Payments.Create(ctx, second); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	payments, err := s.Payments.GetByEnrollmentID(ctx, enrollment.ID)
+	if err != nil {
+		t.Fatalf("GetByEnrollmentID: %v", err)
+	}
+
+	if len(payments) != 2 || payments[0].ID != second.ID || payments[1].ID != first.ID {
+		t.Fatalf("GetByEnrollmentID = %+v, want the two payments newest first", payments)
+	}
+}
+
+func TestPaymentStoreUpdate(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	enrollment := createTestEnrollment(t, s, "Maria")
+	payment := createTestPayment(t, s, enrollment.ID, "OR-0001", 2000)
+
+	payment.TuitionFee = decimal.NewFromInt(2500)
+	payment.PaymentMethod = constants.Bank
+	if err := s.Payments.Update(ctx, payment); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	details, err := s.Enrollments.GetEnrollmentByID(ctx, enrollment.ID)
+	if err != nil {
+		t.Fatalf("GetEnrollmentByID: %v", err)
+	}
+	assertDecimal(t, "total paid", details.TotalPaid, 2500)
+
+	// The payment moves from cash to the bank account in the ledger.
+	assertDecimal(t, "cash", accountBalance(t, s, constants.AccountCash), 0)
+	assertDecimal(t, "bank", accountBalance(t, s, constants.AccountBank), 2500)
+
+	missing := newTestPayment(enrollment.ID, "OR-0002", 500)
+	missing.ID = uuid.New()
+	assertErr(t, s.Payments.Update(ctx, missing), ErrNotFound)
+}
+
+func TestPaymentStoreDelete(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	enrollment := createTestEnrollment(t, s, "Maria")
+	payment := createTestPayment(t, s, enrollment.ID, "OR-0001", 2000)
+
+	if err := s.Payments.Delete(ctx, payment.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	_, err := s.Payments.GetByID(ctx, payment.ID)
+	assertErr(t, err, ErrNotFound)
+
+	assertDecimal(t, "tuition receivable", accountBalance(t, s, constants.AccountTuitionReceivable), 12500)
+
+	assertErr(t, s.Payments.Delete(ctx, payment.ID), ErrNotFound)
+}
+
+func TestPaymentStoreClosedPeriod(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	enrollment := createTestEnrollment(t, s, "Maria")
+	payment := createTestPayment(t, s, enrollment.ID, "OR-0001", 2000)
+
+	closeMonth(t, s, time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC))
+
+	assertErr(t, s.Payments.Create(ctx, newTestPayment(enrollment.ID, "OR-0002", 500)), ErrPeriodClosed)
+
+	payment.TuitionFee = decimal.NewFromInt(2500)
+	assertErr(t, s.Payments.Update(ctx, payment), ErrPeriodClosed)
+
+	assertErr(t, s.Payments.Delete(ctx, payment.ID), ErrPeriodClosed)
+}
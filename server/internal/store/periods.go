@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+)
+
+type PeriodStore struct {
+	db *sql.DB
+}
+
+func (s *PeriodStore) Close(ctx context.Context, period *models.ClosedPeriod) error {
+	query := `
+		INSERT INTO closed_periods (period_type, school_year, period_month, closed_by)
+		VALUES ($1, NULLIF($2, ''), $3, $4)
+		RETURNING id, closed_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		period.PeriodType,
+		period.SchoolYear,
+		period.PeriodMonth,
+		period.ClosedBy,
+	).Scan(
+		&period.ID,
+		&period.ClosedAt,
+	)
+	if err != nil {
+		return parsePgError(err)
+	}
+
+	return nil
+}
+
+func (s *PeriodStore) Reopen(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM closed_periods WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *PeriodStore) GetAll(ctx context.Context) ([]models.ClosedPeriod, error) {
+	query := `
+		SELECT id, period_type, COALESCE(school_year, ''), period_month, closed_by, closed_at
+		FROM closed_periods
+		ORDER BY closed_at DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var periods []models.ClosedPeriod
+
+	for rows.Next() {
+		var period models.ClosedPeriod
+		err := rows.Scan(
+			&period.ID,
+			&period.PeriodType,
+			&period.SchoolYear,
+			&period.PeriodMonth,
+			&period.ClosedBy,
+			&period.ClosedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		periods = append(periods, period)
+	}
+
+	return periods, rows.Err()
+}
+
+// checkSchoolYearOpen rejects changes to a school year that has been closed.
+func checkSchoolYearOpen(ctx context.Context, tx *sql.Tx, schoolYear string) error {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM closed_periods
+			WHERE period_type = $1 AND school_year = $2
+		)
+	`
+
+	return checkPeriodOpen(ctx, tx, query, constants.PeriodSchoolYear, schoolYear)
+}
+
+// checkMonthOpen rejects changes dated inside a closed month.
+func checkMonthOpen(ctx context.Context, tx *sql.Tx, date time.Time) error {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM closed_periods
+			WHERE period_type = $1 AND period_month = date_trunc('month', $2::date)::date
+		)
+	`
+
+	return checkPeriodOpen(ctx, tx, query, constants.PeriodMonth, date)
+}
+
+// checkEnrollmentPeriodOpen rejects changes to an enrollment whose school year,
+// billing month or any of whose payment months has been closed.
+func checkEnrollmentPeriodOpen(ctx context.Context, tx *sql.Tx, enrollmentID uuid.UUID) error {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM enrollments e
+			JOIN closed_periods cp ON
+				(cp.period_type = 'school_year' AND cp.school_year = e.school_year) OR
+				(cp.period_type = 'month' AND cp.period_month = date_trunc('month', e.created_at)::date)
+			WHERE e.id = $1
+			UNION ALL
+			SELECT 1
+			FROM tuition_payments tp
+			JOIN closed_periods cp ON
+				cp.period_type = 'month' AND cp.period_month = date_trunc('month', tp.payment_date)::date
+			WHERE tp.enrollment_id = $1 AND tp.deleted_at IS NULL
+		)
+	`
+
+	return checkPeriodOpen(ctx, tx, query, enrollmentID)
+}
+
+// checkPaymentPeriodOpen rejects changes to a payment made in a closed month or
+// for an enrollment whose school year has been closed.
+func checkPaymentPeriodOpen(ctx context.Context, tx *sql.Tx, paymentID uuid.UUID) error {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM tuition_payments tp
+			JOIN enrollments e ON e.id = tp.enrollment_id
+			JOIN closed_periods cp ON
+				(cp.period_type = 'school_year' AND cp.school_year = e.school_year) OR
+				(cp.period_type = 'month' AND cp.period_month = date_trunc('month', tp.payment_date)::date)
+			WHERE tp.id = $1
+		)
+	`
+
+	return checkPeriodOpen(ctx, tx, query, paymentID)
+}
+
+func checkPeriodOpen(ctx context.Context, tx *sql.Tx, query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var closed bool
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&closed); err != nil {
+		return err
+	}
+
+	if closed {
+		return ErrPeriodClosed
+	}
+
+	return nil
+}
@@ -0,0 +1,467 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// reservationExpiringDays is how close to its expiry an open reservation is
+// reported as expiring soon.
+const reservationExpiringDays = 7
+
+const reservationSelect = `
+	SELECT
+		r.id,
+		r.student_id,
+		TRIM(CONCAT_WS(' ',
+			s.first_name,
+			CASE
+				WHEN s.middle_name IS NOT NULL AND s.middle_name <> ''
+				THEN LEFT(s.middle_name, 1) || '.'
+				ELSE NULL
+			END,
+			s.last_name,
+			s.suffix
+		)) AS full_name,
+		r.school_year,
+		r.grade_level,
+		r.invoice_number,
+		r.fee,
+		r.payment_method,
+		r.reserved_on,
+		r.expires_on,
+		r.status,
+		r.enrollment_id,
+		r.notes,
+		r.closed_at,
+		r.created_at,
+		r.updated_at
+	FROM reservations r
+	JOIN students s ON s.id = r.student_id
+`
+
+type ReservationStore struct {
+	db *sql.DB
+}
+
+// Create reserves a seat for a student who is not yet enrolled for the school
+// year. The seat counts against the grade level's capacity until the
+// reservation is converted or lapses.
+func (s *ReservationStore) Create(ctx context.Context, reservation *models.Reservation) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := checkSchoolYearOpen(ctx, tx, reservation.SchoolYear); err != nil {
+			return err
+		}
+
+		if err := checkMonthOpen(ctx, tx, reservation.ReservedOn); err != nil {
+			return err
+		}
+
+		if err := s.checkNotEnrolled(ctx, tx, reservation); err != nil {
+			return err
+		}
+
+		if err := s.checkInvoiceUnused(ctx, tx, reservation.InvoiceNumber); err != nil {
+			return err
+		}
+
+		seats, err := gradeSeatsLeft(ctx, tx, reservation.SchoolYear, reservation.GradeLevel)
+		if err != nil {
+			return err
+		}
+
+		if seats == 0 {
+			return ErrGradeFull
+		}
+
+		query := `
+			INSERT INTO reservations
+				(student_id, school_year, grade_level, invoice_number, fee, payment_method,
+				reserved_on, expires_on, notes)
+			SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9
+			FROM students
+			WHERE id = $1 AND deleted_at IS NULL
+			RETURNING id, status, created_at, updated_at
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		err = tx.QueryRowContext(
+			ctx,
+			query,
+			reservation.StudentID,
+			reservation.SchoolYear,
+			reservation.GradeLevel,
+			reservation.InvoiceNumber,
+			reservation.Fee,
+			reservation.PaymentMethod,
+			reservation.ReservedOn,
+			reservation.ExpiresOn,
+			reservation.Notes,
+		).Scan(
+			&reservation.ID,
+			&reservation.Status,
+			&reservation.CreatedAt,
+			&reservation.UpdatedAt,
+		)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return parsePgError(err)
+		}
+
+		return syncReservationLedger(ctx, tx, reservation.ID)
+	})
+}
+
+func (s *ReservationStore) GetByID(ctx context.Context, id uuid.UUID) (models.Reservation, error) {
+	query := reservationSelect + `WHERE r.id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var reservation models.Reservation
+	err := scanReservation(s.db.QueryRowContext(ctx, query, id), &reservation)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return reservation, ErrNotFound
+		}
+		return reservation, err
+	}
+
+	return reservation, nil
+}
+
+// GetAll lists the reservations of a school year, optionally only those with
+// the given status, soonest to expire first.
+func (s *ReservationStore) GetAll(ctx context.Context, schoolYear, status string) ([]models.Reservation, error) {
+	query := reservationSelect + `
+		WHERE r.school_year = $1 AND ($2 = '' OR r.status = $2)
+		ORDER BY r.expires_on, r.created_at, r.id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, schoolYear, status)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var reservations []models.Reservation
+
+	for rows.Next() {
+		var reservation models.Reservation
+		if err := scanReservation(rows, &reservation); err != nil {
+			return nil, err
+		}
+
+		reservations = append(reservations, reservation)
+	}
+
+	return reservations, rows.Err()
+}
+
+// GetOpenReport summarizes the open reservations of a school year as of today.
+func (s *ReservationStore) GetOpenReport(ctx context.Context, schoolYear string, today time.Time) (models.ReservationReport, error) {
+	report := models.ReservationReport{SchoolYear: schoolYear, TotalFees: decimal.Zero}
+
+	reservations, err := s.GetAll(ctx, schoolYear, constants.Reserved)
+	if err != nil {
+		return report, err
+	}
+	report.Reservations = reservations
+
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	soon := today.AddDate(0, 0, reservationExpiringDays)
+	grades := make(map[string]int)
+
+	for _, reservation := range reservations {
+		report.Count++
+		report.TotalFees = report.TotalFees.Add(reservation.Fee)
+
+		switch {
+		case reservation.ExpiresOn.Before(today):
+			report.Overdue++
+		case !reservation.ExpiresOn.After(soon):
+			report.ExpiringSoon++
+		}
+
+		if grades[reservation.GradeLevel] == 0 {
+			report.ByGradeLevel = append(report.ByGradeLevel, models.GroupCount{Group: reservation.GradeLevel})
+		}
+		grades[reservation.GradeLevel]++
+	}
+
+	for i := range report.ByGradeLevel {
+		report.ByGradeLevel[i].Count = grades[report.ByGradeLevel[i].Group]
+	}
+
+	return report, nil
+}
+
+// Convert enrolls the student of an open reservation for its school year and
+// grade level, and credits the reservation fee to the new enrollment as a
+// payment. The reservation's own seat is released first so it can be taken
+// by the enrollment.
+func (s *ReservationStore) Convert(ctx context.Context, reservationID uuid.UUID, enrollment *models.Enrollment) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		reservation, err := s.close(ctx, tx, reservationID, constants.Converted)
+		if err != nil {
+			return err
+		}
+
+		enrollment.Student = &models.Student{ID: reservation.StudentID}
+		enrollment.SchoolYear = reservation.SchoolYear
+		enrollment.GradeLevel = reservation.GradeLevel
+		enrollment.AllowWaitlist = false
+
+		enrollments := &EnrollmentStore{s.db}
+		if err := enrollments.create(ctx, tx, enrollment); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE reservations SET enrollment_id = $1 WHERE id = $2`,
+			enrollment.ID,
+			reservationID,
+		)
+		if err != nil {
+			return err
+		}
+
+		credit := &models.Payment{
+			EnrollmentID:   enrollment.ID,
+			InvoiceNumber:  reservation.InvoiceNumber,
+			PaymentDate:    time.Now(),
+			PaymentMethod:  reservation.PaymentMethod,
+			ReservationFee: reservation.Fee,
+			Notes:          "Reservation fee credit",
+			ReservationID:  &reservation.ID,
+		}
+
+		payments := &PaymentStore{s.db}
+		if err := payments.createPayment(ctx, tx, credit); err != nil {
+			return err
+		}
+
+		return syncPaymentLedger(ctx, tx, credit.ID)
+	})
+}
+
+// Forfeit closes an open reservation and keeps its fee, for a student who
+// will not be enrolling.
+func (s *ReservationStore) Forfeit(ctx context.Context, reservationID uuid.UUID) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := s.close(ctx, tx, reservationID, constants.Forfeited); err != nil {
+			return err
+		}
+
+		return syncReservationLedger(ctx, tx, reservationID)
+	})
+}
+
+// ExpireDue expires every open reservation whose expiry date is before today
+// and returns how many were expired.
+func (s *ReservationStore) ExpireDue(ctx context.Context, today time.Time) (int, error) {
+	var expired int
+
+	err := withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		query := `
+			SELECT id
+			FROM reservations
+			WHERE status = $1 AND expires_on < $2::date
+			ORDER BY id
+			FOR UPDATE SKIP LOCKED
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		rows, err := tx.QueryContext(ctx, query, constants.Reserved, today)
+		if err != nil {
+			return err
+		}
+
+		var ids []uuid.UUID
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			_, err := tx.ExecContext(
+				ctx,
+				`UPDATE reservations SET status = $1, closed_at = now(), updated_at = now() WHERE id = $2`,
+				constants.Expired,
+				id,
+			)
+			if err != nil {
+				return err
+			}
+
+			if err := syncReservationLedger(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+
+		expired = len(ids)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return expired, nil
+}
+
+// close moves an open reservation to status. A reservation past its expiry
+// can no longer be converted.
+func (s *ReservationStore) close(ctx context.Context, tx *sql.Tx, reservationID uuid.UUID, status string) (models.Reservation, error) {
+	query := `
+		SELECT student_id, school_year, grade_level, invoice_number, fee, payment_method, status,
+			expires_on < CURRENT_DATE
+		FROM reservations
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var lapsed bool
+	reservation := models.Reservation{ID: reservationID}
+	err := tx.QueryRowContext(ctx, query, reservationID).Scan(
+		&reservation.StudentID,
+		&reservation.SchoolYear,
+		&reservation.GradeLevel,
+		&reservation.InvoiceNumber,
+		&reservation.Fee,
+		&reservation.PaymentMethod,
+		&reservation.Status,
+		&lapsed,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return reservation, ErrNotFound
+		}
+		return reservation, err
+	}
+
+	if reservation.Status != constants.Reserved {
+		return reservation, ErrReservationClosed
+	}
+
+	if status == constants.Converted && lapsed {
+		return reservation, ErrReservationExpired
+	}
+
+	if err := checkSchoolYearOpen(ctx, tx, reservation.SchoolYear); err != nil {
+		return reservation, err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE reservations SET status = $1, closed_at = now(), updated_at = now() WHERE id = $2`,
+		status,
+		reservationID,
+	)
+	if err != nil {
+		return reservation, err
+	}
+	reservation.Status = status
+
+	return reservation, nil
+}
+
+// checkNotEnrolled rejects a reservation for a student who is already
+// enrolled or waitlisted for the school year.
+func (s *ReservationStore) checkNotEnrolled(ctx context.Context, tx *sql.Tx, reservation *models.Reservation) error {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM enrollments
+			WHERE student_id = $1 AND school_year = $2 AND deleted_at IS NULL
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var enrolled bool
+	if err := tx.QueryRowContext(ctx, query, reservation.StudentID, reservation.SchoolYear).Scan(&enrolled); err != nil {
+		return err
+	}
+
+	if enrolled {
+		return ErrAlreadyEnrolled
+	}
+
+	return nil
+}
+
+// checkInvoiceUnused rejects a reservation receipt already used by a
+// payment, since the fee is credited to the enrollment under the same number.
+func (s *ReservationStore) checkInvoiceUnused(ctx context.Context, tx *sql.Tx, invoiceNumber string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var used bool
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM tuition_payments WHERE invoice_number = $1)`,
+		invoiceNumber,
+	).Scan(&used)
+	if err != nil {
+		return err
+	}
+
+	if used {
+		return ErrDuplicateInvoice
+	}
+
+	return nil
+}
+
+func scanReservation(row rowScanner, reservation *models.Reservation) error {
+	return row.Scan(
+		&reservation.ID,
+		&reservation.StudentID,
+		&reservation.FullName,
+		&reservation.SchoolYear,
+		&reservation.GradeLevel,
+		&reservation.InvoiceNumber,
+		&reservation.Fee,
+		&reservation.PaymentMethod,
+		&reservation.ReservedOn,
+		&reservation.ExpiresOn,
+		&reservation.Status,
+		&reservation.EnrollmentID,
+		&reservation.Notes,
+		&reservation.ClosedAt,
+		&reservation.CreatedAt,
+		&reservation.UpdatedAt,
+	)
+}
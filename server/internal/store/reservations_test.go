@@ -0,0 +1,180 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+func createTestReservation(t *testing.T, s Storage, firstName, invoice string, expiresOn time.Time) *models.Reservation {
+	t.Helper()
+
+	student := newTestStudent(firstName)
+	if err := s.Students.Create(context.Background(), student); err != nil {
+		t.Fatalf("creating student: %v", err)
+	}
+
+	reservation := newTestReservation(student, invoice, expiresOn)
+	if err := s.Reservations.Create(context.Background(), reservation); err != nil {
+		t.Fatalf("creating reservation: %v", err)
+	}
+
+	return reservation
+}
+
+func newTestReservation(student *models.Student, invoice string, expiresOn time.Time) *models.Reservation {
+	return &models.Reservation{
+		StudentID:     student.ID,
+		SchoolYear:    testSchoolYear,
+		GradeLevel:    "grade-1",
+		InvoiceNumber: invoice,
+		Fee:           decimal.NewFromInt(1000),
+		PaymentMethod: "cash",
+		ReservedOn:    time.Date(2025, time.June, 2, 0, 0, 0, 0, time.UTC),
+		ExpiresOn:     expiresOn,
+	}
+}
+
+func TestReservationStoreConvert(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	setTestCapacity(t, s, "grade-1", 1)
+
+	maria := createTestReservation(t, s, "Maria", "R-001", time.Now().AddDate(0, 1, 0))
+	assertDecimal(t, "cash", accountBalance(t, s, constants.AccountCash), 1000)
+	assertDecimal(t, "reservation deposits", accountBalance(t, s, constants.AccountReservations), -1000)
+
+	again := newTestReservation(&models.Student{ID: maria.StudentID}, "R-002", maria.ExpiresOn)
+	assertErr(t, s.Reservations.Create(ctx, again), ErrDuplicateReservation)
+
+	// The reservation holds the only seat.
+	jose := newTestStudent("Jose")
+	if err := s.Students.Create(ctx, jose); err != nil {
+		t.Fatalf("creating student: %v", err)
+	}
+	assertErr(t, s.Reservations.Create(ctx, newTestReservation(jose, "R-003", maria.ExpiresOn)), ErrGradeFull)
+	assertErr(t, s.Enrollments.Create(ctx, newTestEnrollment(newTestStudent("Ana"))), ErrGradeFull)
+
+	enrollment := newTestEnrollment(nil)
+	if err := s.Reservations.Convert(ctx, maria.ID, enrollment); err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+
+	details, err := s.Enrollments.GetEnrollmentByID(ctx, enrollment.ID)
+	if err != nil {
+		t.Fatalf("GetEnrollmentByID: %v", err)
+	}
+	if details.Student.ID != maria.StudentID || details.Status != constants.Enrolled {
+		t.Errorf("enrollment = %+v, want Maria enrolled", details)
+	}
+	assertDecimal(t, "total paid", details.TotalPaid, 1000)
+	assertDecimal(t, "remaining amount", details.RemainingAmount, 11500)
+
+	// The fee moves from deposits to the enrollment without touching cash
+	// again.
+	assertDecimal(t, "cash", accountBalance(t, s, constants.AccountCash), 1000)
+	assertDecimal(t, "reservation deposits", accountBalance(t, s, constants.AccountReservations), 0)
+	assertDecimal(t, "tuition receivable", accountBalance(t, s, constants.AccountTuitionReceivable), 11500)
+
+	reservation, err := s.Reservations.GetByID(ctx, maria.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if reservation.Status != constants.Converted || reservation.EnrollmentID == nil || *reservation.EnrollmentID != enrollment.ID {
+		t.Errorf("reservation = %+v, want converted into %s", reservation, enrollment.ID)
+	}
+
+	payments, err := s.Payments.GetByEnrollmentID(ctx, enrollment.ID)
+	if err != nil {
+		t.Fatalf("GetByEnrollmentID: %v", err)
+	}
+	if len(payments) != 1 || payments[0].ReservationID == nil {
+		t.Fatalf("payments = %+v, want the reservation credit", payments)
+	}
+	assertErr(t, s.Payments.Delete(ctx, payments[0].ID), ErrReservationCredit)
+
+	assertErr(t, s.Reservations.Convert(ctx, maria.ID, newTestEnrollment(nil)), ErrReservationClosed)
+	assertErr(t, s.Reservations.Create(ctx, newTestReservation(&models.Student{ID: maria.StudentID}, "R-004", maria.ExpiresOn)), ErrAlreadyEnrolled)
+}
+
+func TestReservationStoreExpire(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	lapsed := createTestReservation(t, s, "Maria", "R-001", time.Date(2025, time.June, 9, 0, 0, 0, 0, time.UTC))
+	open := createTestReservation(t, s, "Jose", "R-002", time.Now().AddDate(0, 0, 3))
+
+	assertErr(t, s.Reservations.Convert(ctx, lapsed.ID, newTestEnrollment(nil)), ErrReservationExpired)
+
+	report, err := s.Reservations.GetOpenReport(ctx, testSchoolYear, time.Now())
+	if err != nil {
+		t.Fatalf("GetOpenReport: %v", err)
+	}
+	if report.Count != 2 || report.Overdue != 1 || report.ExpiringSoon != 1 {
+		t.Errorf("report = %+v, want 2 open, 1 overdue and 1 expiring soon", report)
+	}
+	assertDecimal(t, "total fees", report.TotalFees, 2000)
+
+	expired, err := s.Reservations.ExpireDue(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("ExpireDue: %v", err)
+	}
+	if expired != 1 {
+		t.Errorf("expired %d reservations, want 1", expired)
+	}
+
+	// The lapsed fee is kept as revenue; the open one is still held.
+	assertDecimal(t, "forfeited revenue", accountBalance(t, s, constants.AccountForfeitedRevenue), -1000)
+	assertDecimal(t, "reservation deposits", accountBalance(t, s, constants.AccountReservations), -1000)
+
+	assertErr(t, s.Reservations.Forfeit(ctx, lapsed.ID), ErrReservationClosed)
+
+	if err := s.Reservations.Forfeit(ctx, open.ID); err != nil {
+		t.Fatalf("Forfeit: %v", err)
+	}
+	assertDecimal(t, "forfeited revenue", accountBalance(t, s, constants.AccountForfeitedRevenue), -2000)
+	assertDecimal(t, "reservation deposits", accountBalance(t, s, constants.AccountReservations), 0)
+	assertDecimal(t, "cash", accountBalance(t, s, constants.AccountCash), 2000)
+}
+
+func TestReservationStoreInvoiceNumbers(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	maria := createTestReservation(t, s, "Maria", "R-001", time.Now().AddDate(0, 1, 0))
+	jose := createTestEnrollment(t, s, "Jose")
+
+	// An open reservation keeps its number for the credit it converts into,
+	// and a payment's number cannot be reused by a reservation.
+	assertErr(t, s.Payments.Create(ctx, newTestPayment(jose.ID, "R-001", 1000)), ErrDuplicateInvoice)
+
+	payment := createTestPayment(t, s, jose.ID, "INV-001", 1000)
+	payment.InvoiceNumber = "R-001"
+	assertErr(t, s.Payments.Update(ctx, payment), ErrDuplicateInvoice)
+
+	ana := newTestStudent("Ana")
+	if err := s.Students.Create(ctx, ana); err != nil {
+		t.Fatalf("creating student: %v", err)
+	}
+	assertErr(t, s.Reservations.Create(ctx, newTestReservation(ana, "INV-001", maria.ExpiresOn)), ErrDuplicateInvoice)
+
+	enrollment := newTestEnrollment(nil)
+	if err := s.Reservations.Convert(ctx, maria.ID, enrollment); err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+
+	payments, err := s.Payments.GetByEnrollmentID(ctx, enrollment.ID)
+	if err != nil {
+		t.Fatalf("GetByEnrollmentID: %v", err)
+	}
+	if len(payments) != 1 || payments[0].InvoiceNumber != "R-001" {
+		t.Errorf("payments = %+v, want the credit under R-001", payments)
+	}
+}
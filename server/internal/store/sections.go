@@ -0,0 +1,601 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Only enrolled students count toward a section. Withdrawn students keep
+// their section so their records still show where they were.
+const sectionSelect = `
+	SELECT
+		sec.id,
+		sec.school_year,
+		sec.grade_level,
+		sec.name,
+		sec.adviser,
+		sec.capacity,
+		COUNT(e.id),
+		COUNT(e.id) FILTER (WHERE s.gender = 'male'),
+		COUNT(e.id) FILTER (WHERE s.gender = 'female'),
+		sec.created_at,
+		sec.updated_at
+	FROM sections sec
+	LEFT JOIN enrollments e
+		ON e.section_id = sec.id AND e.deleted_at IS NULL AND e.status = $1
+	LEFT JOIN students s ON s.id = e.student_id
+`
+
+type SectionStore struct {
+	db *sql.DB
+}
+
+func (s *SectionStore) Create(ctx context.Context, section *models.Section) error {
+	query := `
+		INSERT INTO sections (school_year, grade_level, name, adviser, capacity)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		section.SchoolYear,
+		section.GradeLevel,
+		section.Name,
+		section.Adviser,
+		section.Capacity,
+	).Scan(
+		&section.ID,
+		&section.CreatedAt,
+		&section.UpdatedAt,
+	)
+	if err != nil {
+		return parsePgError(err)
+	}
+
+	return nil
+}
+
+// GetAll lists the sections of a school year, optionally for a single grade
+// level.
+func (s *SectionStore) GetAll(ctx context.Context, schoolYear, gradeLevel string) ([]models.Section, error) {
+	query := sectionSelect + `
+		WHERE sec.school_year = $2 AND ($3 = '' OR sec.grade_level = $3)
+		GROUP BY sec.id
+		ORDER BY sec.grade_level, LOWER(sec.name)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, constants.Enrolled, schoolYear, gradeLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var sections []models.Section
+
+	for rows.Next() {
+		var section models.Section
+		if err := scanSection(rows, &section); err != nil {
+			return nil, err
+		}
+
+		sections = append(sections, section)
+	}
+
+	return sections, rows.Err()
+}
+
+func (s *SectionStore) GetByID(ctx context.Context, id uuid.UUID) (models.Section, error) {
+	query := sectionSelect + `
+		WHERE sec.id = $2
+		GROUP BY sec.id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	var section models.Section
+	err := scanSection(s.db.QueryRowContext(ctx, query, constants.Enrolled, id), &section)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return section, ErrNotFound
+		}
+		return section, err
+	}
+
+	return section, nil
+}
+
+// Update renames a section, changes its adviser or resizes it. A section
+// cannot be made smaller than the students already assigned to it.
+func (s *SectionStore) Update(ctx context.Context, section *models.Section) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		enrolled, err := lockSection(ctx, tx, section.ID, nil)
+		if err != nil {
+			return err
+		}
+
+		if section.Capacity < enrolled {
+			return ErrSectionCapacity
+		}
+
+		query := `
+			UPDATE sections
+			SET
+				name = $1,
+				adviser = $2,
+				capacity = $3,
+				updated_at = now()
+			WHERE id = $4
+			RETURNING updated_at
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		err = tx.QueryRowContext(
+			ctx,
+			query,
+			section.Name,
+			section.Adviser,
+			section.Capacity,
+			section.ID,
+		).Scan(
+			&section.UpdatedAt,
+		)
+		if err != nil {
+			return parsePgError(err)
+		}
+
+		return nil
+	})
+}
+
+// Delete removes a section that has no enrolled students. Withdrawn and
+// deleted enrollments that still point at it are unassigned first.
+func (s *SectionStore) Delete(ctx context.Context, id uuid.UUID) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		enrolled, err := lockSection(ctx, tx, id, nil)
+		if err != nil {
+			return err
+		}
+
+		if enrolled > 0 {
+			return ErrSectionNotEmpty
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		_, err = tx.ExecContext(ctx, `UPDATE enrollments SET section_id = NULL WHERE section_id = $1`, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM sections WHERE id = $1`, id)
+
+		return err
+	})
+}
+
+// Assign moves an enrolled student into a section of their school year and
+// grade level, or out of any section when sectionID is nil.
+func (s *SectionStore) Assign(ctx context.Context, enrollmentID uuid.UUID, sectionID *uuid.UUID) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		// The section is locked before the enrollment, in the same order
+		// AutoAssign takes them.
+		var section models.Section
+		if sectionID != nil {
+			enrolled, err := lockSection(ctx, tx, *sectionID, &section)
+			if err != nil {
+				return err
+			}
+			section.Enrolled = enrolled
+		}
+
+		query := `
+			SELECT school_year, grade_level, status, section_id
+			FROM enrollments
+			WHERE id = $1 AND deleted_at IS NULL
+			FOR UPDATE
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		var (
+			schoolYear, gradeLevel, status string
+			current                        uuid.NullUUID
+		)
+		err := tx.QueryRowContext(ctx, query, enrollmentID).Scan(&schoolYear, &gradeLevel, &status, &current)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		switch status {
+		case constants.Withdrawn:
+			return ErrAlreadyWithdrawn
+		case constants.Waitlisted:
+			return ErrWaitlisted
+		}
+
+		if sectionID != nil {
+			if section.SchoolYear != schoolYear || section.GradeLevel != gradeLevel {
+				return ErrSectionMismatch
+			}
+
+			if current.Valid && current.UUID == *sectionID {
+				return nil
+			}
+
+			if section.Enrolled >= section.Capacity {
+				return ErrSectionFull
+			}
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE enrollments SET section_id = $1, updated_at = now() WHERE id = $2`,
+			sectionID,
+			enrollmentID,
+		)
+
+		return err
+	})
+}
+
+// AutoAssign spreads the enrolled students of a grade level who have no
+// section across its sections, keeping both the class sizes and the number of
+// boys and girls in each as even as capacity allows. Students already in a
+// section stay where they are.
+func (s *SectionStore) AutoAssign(ctx context.Context, schoolYear, gradeLevel string) (models.SectionBalance, error) {
+	var balance models.SectionBalance
+
+	err := withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		sections, err := s.lockGradeSections(ctx, tx, schoolYear, gradeLevel)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			SELECT e.id, s.gender
+			FROM enrollments e
+			JOIN students s ON s.id = e.student_id
+			WHERE e.deleted_at IS NULL AND e.status = $1 AND e.section_id IS NULL
+				AND e.school_year = $2 AND e.grade_level = $3
+			ORDER BY s.gender, LOWER(s.last_name), LOWER(s.first_name), e.id
+			FOR UPDATE OF e
+		`
+
+		rows, err := tx.QueryContext(ctx, query, constants.Enrolled, schoolYear, gradeLevel)
+		if err != nil {
+			return err
+		}
+
+		var students []sectionCandidate
+		for rows.Next() {
+			var student sectionCandidate
+			if err := rows.Scan(&student.enrollmentID, &student.gender); err != nil {
+				rows.Close()
+				return err
+			}
+			students = append(students, student)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		assignments := balanceSections(sections, students)
+
+		balance.Assigned = len(assignments)
+		balance.Unassigned = len(students) - len(assignments)
+
+		if len(assignments) == 0 {
+			return nil
+		}
+
+		enrollmentIDs := make([]string, 0, len(assignments))
+		sectionIDs := make([]string, 0, len(assignments))
+		for _, student := range students {
+			if sectionID, ok := assignments[student.enrollmentID]; ok {
+				enrollmentIDs = append(enrollmentIDs, student.enrollmentID.String())
+				sectionIDs = append(sectionIDs, sectionID.String())
+			}
+		}
+
+		query = `
+			UPDATE enrollments e
+			SET section_id = a.section_id, updated_at = now()
+			FROM unnest($1::uuid[], $2::uuid[]) AS a(enrollment_id, section_id)
+			WHERE e.id = a.enrollment_id
+		`
+
+		_, err = tx.ExecContext(ctx, query, pq.Array(enrollmentIDs), pq.Array(sectionIDs))
+
+		return err
+	})
+	if err != nil {
+		return balance, err
+	}
+
+	balance.Sections, err = s.GetAll(ctx, schoolYear, gradeLevel)
+
+	return balance, err
+}
+
+// GetClassList returns a section with its enrolled students in alphabetical
+// order.
+func (s *SectionStore) GetClassList(ctx context.Context, sectionID uuid.UUID) (models.ClassList, error) {
+	var list models.ClassList
+
+	section, err := s.GetByID(ctx, sectionID)
+	if err != nil {
+		return list, err
+	}
+	list.Section = section
+
+	query := `
+		SELECT
+			e.id,
+			s.id,
+			s.last_name,
+			s.first_name,
+			s.middle_name,
+			COALESCE(s.suffix, ''),
+			s.gender,
+			s.birthdate,
+			s.contact_numbers
+		FROM enrollments e
+		JOIN students s ON s.id = e.student_id
+		WHERE e.section_id = $1 AND e.deleted_at IS NULL AND e.status = $2
+		ORDER BY LOWER(s.last_name), LOWER(s.first_name), LOWER(s.middle_name), e.id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, sectionID, constants.Enrolled)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+
+	list.Students = []models.ClassListStudent{}
+
+	for rows.Next() {
+		var student models.ClassListStudent
+		err := rows.Scan(
+			&student.EnrollmentID,
+			&student.StudentID,
+			&student.LastName,
+			&student.FirstName,
+			&student.MiddleName,
+			&student.Suffix,
+			&student.Gender,
+			&student.Birthdate,
+			pq.Array(&student.ContactNumbers),
+		)
+		if err != nil {
+			return list, err
+		}
+
+		list.Students = append(list.Students, student)
+	}
+
+	return list, rows.Err()
+}
+
+// lockGradeSections locks the sections of a grade level in id order and
+// returns them with their current enrolled counts.
+func (s *SectionStore) lockGradeSections(ctx context.Context, tx *sql.Tx, schoolYear, gradeLevel string) ([]models.Section, error) {
+	query := `
+		SELECT id, capacity
+		FROM sections
+		WHERE school_year = $1 AND grade_level = $2
+		ORDER BY id
+		FOR UPDATE
+	`
+
+	rows, err := tx.QueryContext(ctx, query, schoolYear, gradeLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	var sections []models.Section
+	for rows.Next() {
+		section := models.Section{SchoolYear: schoolYear, GradeLevel: gradeLevel}
+		if err := rows.Scan(&section.ID, &section.Capacity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT e.section_id, s.gender, COUNT(*)
+		FROM enrollments e
+		JOIN students s ON s.id = e.student_id
+		WHERE e.section_id = ANY($1::uuid[]) AND e.deleted_at IS NULL AND e.status = $2
+		GROUP BY e.section_id, s.gender
+	`
+
+	ids := make([]string, len(sections))
+	index := make(map[uuid.UUID]int, len(sections))
+	for i, section := range sections {
+		ids[i] = section.ID.String()
+		index[section.ID] = i
+	}
+
+	rows, err = tx.QueryContext(ctx, query, pq.Array(ids), constants.Enrolled)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			sectionID uuid.UUID
+			gender    string
+			count     int
+		)
+
+		if err := rows.Scan(&sectionID, &gender, &count); err != nil {
+			return nil, err
+		}
+
+		section := &sections[index[sectionID]]
+		section.Enrolled += count
+		switch gender {
+		case "male":
+			section.Male += count
+		case "female":
+			section.Female += count
+		}
+	}
+
+	return sections, rows.Err()
+}
+
+// sectionCandidate is an enrolled student waiting for a section.
+type sectionCandidate struct {
+	enrollmentID uuid.UUID
+	gender       string
+}
+
+// balanceSections places each student in the open section with the fewest
+// students of the same gender, then the fewest students overall, then the
+// first in order. Students are expected grouped by gender so each group is
+// dealt out in turn. Students left over once every section is full are not
+// in the result.
+func balanceSections(sections []models.Section, students []sectionCandidate) map[uuid.UUID]uuid.UUID {
+	assignments := make(map[uuid.UUID]uuid.UUID, len(students))
+
+	sameGender := func(section *models.Section, gender string) int {
+		switch gender {
+		case "male":
+			return section.Male
+		case "female":
+			return section.Female
+		}
+		return section.Enrolled - section.Male - section.Female
+	}
+
+	for _, student := range students {
+		var best *models.Section
+
+		for i := range sections {
+			section := &sections[i]
+			if section.Enrolled >= section.Capacity {
+				continue
+			}
+
+			if best == nil {
+				best = section
+				continue
+			}
+
+			got, want := sameGender(section, student.gender), sameGender(best, student.gender)
+			if got < want || (got == want && section.Enrolled < best.Enrolled) {
+				best = section
+			}
+		}
+
+		if best == nil {
+			break
+		}
+
+		assignments[student.enrollmentID] = best.ID
+		best.Enrolled++
+		switch student.gender {
+		case "male":
+			best.Male++
+		case "female":
+			best.Female++
+		}
+	}
+
+	return assignments
+}
+
+// lockSection locks a section for the rest of the transaction and returns how
+// many enrolled students are in it. The section itself is read into section
+// when it is not nil.
+func lockSection(ctx context.Context, tx *sql.Tx, id uuid.UUID, section *models.Section) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	if section == nil {
+		section = &models.Section{}
+	}
+
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT id, school_year, grade_level, capacity FROM sections WHERE id = $1 FOR UPDATE`,
+		id,
+	).Scan(&section.ID, &section.SchoolYear, &section.GradeLevel, &section.Capacity)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	var enrolled int
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM enrollments WHERE section_id = $1 AND status = $2 AND deleted_at IS NULL`,
+		id,
+		constants.Enrolled,
+	).Scan(&enrolled)
+	if err != nil {
+		return 0, err
+	}
+
+	return enrolled, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSection(row rowScanner, section *models.Section) error {
+	return row.Scan(
+		&section.ID,
+		&section.SchoolYear,
+		&section.GradeLevel,
+		&section.Name,
+		&section.Adviser,
+		&section.Capacity,
+		&section.Enrolled,
+		&section.Male,
+		&section.Female,
+		&section.CreatedAt,
+		&section.UpdatedAt,
+	)
+}
@@ -0,0 +1,189 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+)
+
+func createTestSection(t *testing.T, s Storage, name string, capacity int) *models.Section {
+	t.Helper()
+
+	section := &models.Section{
+		SchoolYear: testSchoolYear,
+		GradeLevel: "grade-1",
+		Name:       name,
+		Capacity:   capacity,
+	}
+	if err := s.Sections.Create(context.Background(), section); err != nil {
+		t.Fatalf("creating section: %v", err)
+	}
+
+	return section
+}
+
+func createTestGenderEnrollment(t *testing.T, s Storage, firstName, gender string) *models.Enrollment {
+	t.Helper()
+
+	student := newTestStudent(firstName)
+	student.Gender = gender
+
+	enrollment := newTestEnrollment(student)
+	if err := s.Enrollments.Create(context.Background(), enrollment); err != nil {
+		t.Fatalf("creating enrollment: %v", err)
+	}
+
+	return enrollment
+}
+
+func TestSectionStoreAssign(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	rizal := createTestSection(t, s, "Rizal", 1)
+	mabini := createTestSection(t, s, "Mabini", 2)
+
+	assertErr(t, s.Sections.Create(ctx, &models.Section{
+		SchoolYear: testSchoolYear,
+		GradeLevel: "grade-1",
+		Name:       "RIZAL",
+		Capacity:   10,
+	}), ErrDuplicateSection)
+
+	maria := createTestEnrollment(t, s, "Maria")
+	jose := createTestEnrollment(t, s, "Jose")
+
+	if err := s.Sections.Assign(ctx, maria.ID, &rizal.ID); err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+	// Assigning a student to the section they are in is a no-op, even when
+	// it is full.
+	if err := s.Sections.Assign(ctx, maria.ID, &rizal.ID); err != nil {
+		t.Fatalf("Assign again: %v", err)
+	}
+	assertErr(t, s.Sections.Assign(ctx, jose.ID, &rizal.ID), ErrSectionFull)
+
+	missing := uuid.New()
+	assertErr(t, s.Sections.Assign(ctx, jose.ID, &missing), ErrNotFound)
+
+	details, err := s.Enrollments.GetEnrollmentByID(ctx, maria.ID)
+	if err != nil {
+		t.Fatalf("GetEnrollmentByID: %v", err)
+	}
+	if details.SectionName == nil || *details.SectionName != "Rizal" {
+		t.Errorf("section name = %v, want Rizal", details.SectionName)
+	}
+
+	assertErr(t, s.Sections.Delete(ctx, rizal.ID), ErrSectionNotEmpty)
+
+	rizal.Capacity = 0
+	assertErr(t, s.Sections.Update(ctx, rizal), ErrSectionCapacity)
+
+	t.Run("other grade level", func(t *testing.T) {
+		other := &models.Section{SchoolYear: testSchoolYear, GradeLevel: "grade-2", Name: "Bonifacio", Capacity: 5}
+		if err := s.Sections.Create(ctx, other); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		assertErr(t, s.Sections.Assign(ctx, jose.ID, &other.ID), ErrSectionMismatch)
+	})
+
+	t.Run("grade change clears the section", func(t *testing.T) {
+		if err := s.Sections.Assign(ctx, jose.ID, &mabini.ID); err != nil {
+			t.Fatalf("Assign: %v", err)
+		}
+
+		jose.GradeLevel = "grade-2"
+		if err := s.Enrollments.Update(ctx, jose, jose.ID); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		details, err := s.Enrollments.GetEnrollmentByID(ctx, jose.ID)
+		if err != nil {
+			t.Fatalf("GetEnrollmentByID: %v", err)
+		}
+		if details.SectionID != nil {
+			t.Errorf("section id = %v, want none", details.SectionID)
+		}
+	})
+
+	t.Run("unassign and delete", func(t *testing.T) {
+		if err := s.Sections.Assign(ctx, maria.ID, nil); err != nil {
+			t.Fatalf("Assign nil: %v", err)
+		}
+
+		if err := s.Sections.Delete(ctx, rizal.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		_, err := s.Sections.GetByID(ctx, rizal.ID)
+		assertErr(t, err, ErrNotFound)
+	})
+}
+
+func TestSectionStoreAutoAssign(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	createTestSection(t, s, "Rizal", 3)
+	createTestSection(t, s, "Mabini", 3)
+
+	for _, name := range []string{"Ana", "Bea", "Cora", "Dina"} {
+		createTestGenderEnrollment(t, s, name, "female")
+	}
+	for _, name := range []string{"Ariel", "Ben", "Carlo"} {
+		createTestGenderEnrollment(t, s, name, "male")
+	}
+
+	balance, err := s.Sections.AutoAssign(ctx, testSchoolYear, "grade-1")
+	if err != nil {
+		t.Fatalf("AutoAssign: %v", err)
+	}
+
+	if balance.Assigned != 6 || balance.Unassigned != 1 {
+		t.Errorf("assigned %d, unassigned %d, want 6 and 1", balance.Assigned, balance.Unassigned)
+	}
+
+	if len(balance.Sections) != 2 {
+		t.Fatalf("got %d sections, want 2", len(balance.Sections))
+	}
+
+	for _, section := range balance.Sections {
+		if section.Enrolled != 3 || section.Female != 2 {
+			t.Errorf("%s: %d enrolled, %d female, want 3 and 2", section.Name, section.Enrolled, section.Female)
+		}
+
+		list, err := s.Sections.GetClassList(ctx, section.ID)
+		if err != nil {
+			t.Fatalf("GetClassList: %v", err)
+		}
+		if len(list.Students) != section.Enrolled {
+			t.Errorf("%s: class list has %d students, want %d", section.Name, len(list.Students), section.Enrolled)
+		}
+	}
+}
+
+func TestBalanceSections(t *testing.T) {
+	a := models.Section{ID: uuid.New(), Capacity: 10, Enrolled: 2, Female: 2}
+	b := models.Section{ID: uuid.New(), Capacity: 10}
+
+	students := []sectionCandidate{
+		{enrollmentID: uuid.New(), gender: "female"},
+		{enrollmentID: uuid.New(), gender: "male"},
+		{enrollmentID: uuid.New(), gender: "male"},
+	}
+
+	assignments := balanceSections([]models.Section{a, b}, students)
+
+	// The girl evens out the girls; the boys then go one to each section.
+	want := []uuid.UUID{b.ID, b.ID, a.ID}
+	for i, student := range students {
+		if got := assignments[student.enrollmentID]; got != want[i] {
+			t.Errorf("student %d in %v, want %v", i, got, want[i])
+		}
+	}
+}
@@ -3,21 +3,17 @@ package store
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"runtime"
+	"strings"
 	"time"
 
 	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/edzhabs/bookkeeping/internal/tracing"
 	"github.com/google/uuid"
-	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/codes"
 )
 
-var (
-	ErrConflict       = errors.New("resource already exist")
-	ErrRequiredFees   = errors.New("enrollment, tuition, misc, pta, lms_books fees must be greater than zero")
-	ErrDuplicate      = errors.New("student with that record already exist")
-	ErrNotFound       = errors.New("record not found")
-	QueryTimeDuration = time.Second * 5
-)
+var QueryTimeDuration = time.Second * 5
 
 type Storage struct {
 	Students interface {
@@ -32,41 +28,185 @@ type Storage struct {
 		GetEditEnrollmentDetails(ctx context.Context, id uuid.UUID) (models.EditEnrollmentDetails, error)
 		Update(ctx context.Context, enrollment *models.Enrollment, enrollmentID uuid.UUID) error
 		Delete(ctx context.Context, enrollmentID uuid.UUID) error
+		Withdraw(ctx context.Context, withdrawal *models.Withdrawal) error
+		GetWithdrawal(ctx context.Context, enrollmentID uuid.UUID) (models.Withdrawal, error)
+		GetWaitlist(ctx context.Context, schoolYear, gradeLevel string) ([]models.WaitlistEntry, error)
+		Promote(ctx context.Context, enrollmentID uuid.UUID) error
+		PromoteWaitlist(ctx context.Context, schoolYear, gradeLevel string) ([]uuid.UUID, error)
+	}
+	Payments interface {
+		Create(ctx context.Context, payment *models.Payment) error
+		GetByID(ctx context.Context, id uuid.UUID) (models.Payment, error)
+		GetByEnrollmentID(ctx context.Context, enrollmentID uuid.UUID) ([]models.Payment, error)
+		Update(ctx context.Context, payment *models.Payment) error
+		Delete(ctx context.Context, id uuid.UUID) error
+	}
+	Expenses interface {
+		Create(ctx context.Context, expense *models.Expense) error
+		GetAll(ctx context.Context) ([]models.Expense, error)
+	}
+	Ledger interface {
+		GetAccounts(ctx context.Context) ([]models.Account, error)
+		GetTrialBalance(ctx context.Context, asOf time.Time) (models.TrialBalance, error)
+		CreateAdjustment(ctx context.Context, entry *models.JournalEntry) error
+	}
+	Capacities interface {
+		Set(ctx context.Context, capacity *models.GradeCapacity) error
+		GetUtilization(ctx context.Context, schoolYear string) ([]models.GradeUtilization, error)
+	}
+	Sections interface {
+		Create(ctx context.Context, section *models.Section) error
+		GetAll(ctx context.Context, schoolYear, gradeLevel string) ([]models.Section, error)
+		GetByID(ctx context.Context, id uuid.UUID) (models.Section, error)
+		Update(ctx context.Context, section *models.Section) error
+		Delete(ctx context.Context, id uuid.UUID) error
+		Assign(ctx context.Context, enrollmentID uuid.UUID, sectionID *uuid.UUID) error
+		AutoAssign(ctx context.Context, schoolYear, gradeLevel string) (models.SectionBalance, error)
+		GetClassList(ctx context.Context, sectionID uuid.UUID) (models.ClassList, error)
+	}
+	Reservations interface {
+		Create(ctx context.Context, reservation *models.Reservation) error
+		GetByID(ctx context.Context, id uuid.UUID) (models.Reservation, error)
+		GetAll(ctx context.Context, schoolYear, status string) ([]models.Reservation, error)
+		GetOpenReport(ctx context.Context, schoolYear string, today time.Time) (models.ReservationReport, error)
+		Convert(ctx context.Context, reservationID uuid.UUID, enrollment *models.Enrollment) error
+		Forfeit(ctx context.Context, reservationID uuid.UUID) error
+		ExpireDue(ctx context.Context, today time.Time) (int, error)
+	}
+	Applicants interface {
+		Create(ctx context.Context, applicant *models.Applicant) error
+		GetByID(ctx context.Context, id uuid.UUID) (models.Applicant, error)
+		GetAll(ctx context.Context, schoolYear, status string) ([]models.Applicant, error)
+		GetPipeline(ctx context.Context, schoolYear string) ([]models.GroupCount, error)
+		Update(ctx context.Context, applicant *models.Applicant) error
+		SetStatus(ctx context.Context, id uuid.UUID, status string) error
+		SetDocument(ctx context.Context, document *models.ApplicantDocument) error
+		RemoveDocument(ctx context.Context, applicantID uuid.UUID, documentType string) error
+		Convert(ctx context.Context, applicantID uuid.UUID, enrollment *models.Enrollment) error
+	}
+	Attachments interface {
+		Create(ctx context.Context, attachment *models.Attachment) error
+		GetByID(ctx context.Context, id uuid.UUID) (models.Attachment, error)
+		GetAll(ctx context.Context, owner models.AttachmentOwner) ([]models.Attachment, error)
+		Delete(ctx context.Context, id uuid.UUID) (models.Attachment, error)
+	}
+	Guardians interface {
+		Create(ctx context.Context, guardian *models.Guardian) error
+		GetByID(ctx context.Context, id uuid.UUID) (models.Guardian, error)
+		GetByStudentID(ctx context.Context, studentID uuid.UUID) ([]models.Guardian, error)
+		Update(ctx context.Context, guardian *models.Guardian) error
+		Delete(ctx context.Context, id uuid.UUID) error
+	}
+	Notifications interface {
+		GetAll(ctx context.Context, status string, enrollmentID *uuid.UUID) ([]models.Notification, error)
+		EnqueueReminders(ctx context.Context, today time.Time, dueDay, leadDays int) (int, error)
+		Claim(ctx context.Context, limit int, lease time.Duration) ([]models.Notification, error)
+		MarkSent(ctx context.Context, id uuid.UUID) error
+		MarkFailed(ctx context.Context, id uuid.UUID, reason string, retryAfter time.Duration, maxAttempts int) error
+		Retry(ctx context.Context, id uuid.UUID) error
+	}
+	Dashboard interface {
+		Get(ctx context.Context, schoolYear string, today time.Time) (models.Dashboard, error)
+	}
+	Balances interface {
+		Rebuild(ctx context.Context) (int64, error)
+		CheckDrift(ctx context.Context) ([]models.BalanceDrift, error)
+	}
+	Idempotency interface {
+		Claim(ctx context.Context, record *models.IdempotencyRecord) (bool, error)
+		Get(ctx context.Context, record *models.IdempotencyRecord) error
+		Complete(ctx context.Context, record *models.IdempotencyRecord) error
+		Release(ctx context.Context, record *models.IdempotencyRecord) error
+		DeleteExpired(ctx context.Context) (int64, error)
+	}
+	Health interface {
+		Ping(ctx context.Context) error
+		SchemaVersion(ctx context.Context) (int, bool, error)
+		Stats() sql.DBStats
+	}
+	Metrics interface {
+		GetBusinessMetrics(ctx context.Context) (models.BusinessMetrics, error)
+	}
+	Periods interface {
+		Close(ctx context.Context, period *models.ClosedPeriod) error
+		Reopen(ctx context.Context, id uuid.UUID) error
+		GetAll(ctx context.Context) ([]models.ClosedPeriod, error)
 	}
 }
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Students:    &StudentStore{db},
-		Enrollments: &EnrollmentStore{db},
+		Students:      &StudentStore{db},
+		Enrollments:   &EnrollmentStore{db},
+		Payments:      &PaymentStore{db},
+		Expenses:      &ExpenseStore{db},
+		Ledger:        &LedgerStore{db},
+		Periods:       &PeriodStore{db},
+		Balances:      &BalanceStore{db},
+		Dashboard:     &DashboardStore{db},
+		Capacities:    &CapacityStore{db},
+		Sections:      &SectionStore{db},
+		Reservations:  &ReservationStore{db},
+		Applicants:    &ApplicantStore{db},
+		Attachments:   &AttachmentStore{db},
+		Guardians:     &GuardianStore{db},
+		Notifications: &NotificationStore{db},
+		Idempotency:   &IdempotencyStore{db},
+		Metrics:       &MetricsStore{db},
+		Health:        &HealthStore{db},
 	}
 }
 
-func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
-	tx, err := db.BeginTx(ctx, nil)
+// withTx runs fn in a transaction under a span named after the calling store
+// method. fn receives the span's context so its queries nest beneath it.
+func withTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	return runTx(ctx, db, nil, callerName(), fn)
+}
+
+// withTxOptions is withTx with transaction options, such as a stricter
+// isolation level for reads that must see one snapshot.
+func withTxOptions(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	return runTx(ctx, db, opts, callerName(), fn)
+}
+
+func runTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, name string, fn func(ctx context.Context, tx *sql.Tx) error) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "tx "+name)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	tx, err := db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
 
-	err = fn(tx)
+	err = fn(ctx, tx)
 	if err != nil {
 		_ = tx.Rollback()
 		return err
 	}
 
-	return tx.Commit()
+	// Deferred constraint triggers, such as the journal balance check, only
+	// fire here.
+	return parsePgError(tx.Commit())
 }
 
-func parsePgError(err error) error {
-	var pgErr *pq.Error
-	if errors.As(err, &pgErr) {
-		switch pgErr.Constraint {
-		case "enrollments_student_id_school_year_key":
-			return ErrDuplicate
-		case "check_positive_fees":
-			return ErrRequiredFees
-		}
+// callerName returns the store method that called withTx or withTxOptions,
+// e.g. "PaymentStore.Create".
+func callerName() string {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return "store"
 	}
 
-	return err
+	name := runtime.FuncForPC(pc).Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	name = strings.TrimPrefix(name, "store.")
+	name = strings.NewReplacer("(*", "", ")", "").Replace(name)
+
+	return name
 }
@@ -55,12 +55,7 @@ func (s *StudentStore) Create(ctx context.Context, student *models.Student) erro
 	)
 
 	if err != nil {
-		switch err.Error() {
-		case `pq: duplicate key value violates unique constraint "idx_unique_student_name_birthday_gender"`:
-			return ErrDuplicate
-		default:
-			return err
-		}
+		return parsePgError(err)
 	}
 
 	return nil
@@ -82,12 +77,12 @@ func (s *StudentStore) GetAll(ctx context.Context) ([]StudentWithAge, error) {
 	// `
 
 	query := `
-		SELECT id, first_name, middle_name, last_name, suffix, 
+		SELECT id, first_name, middle_name, last_name, COALESCE(suffix, ''),
 			CONCAT_WS(' ', first_name, middle_name, last_name) AS full_name,
-			gender, birthdate, EXTRACT(YEAR FROM age(birthdate)) AS age,  
-			address, mother_name, mother_occupation,
-			mother_education_attain, father_name, father_occupation, father_education_attain,
-			contact_numbers, living_with, created_at
+			gender, birthdate, EXTRACT(YEAR FROM age(birthdate))::int AS age,
+			address, COALESCE(mother_name, ''), COALESCE(mother_job, ''),
+			COALESCE(mother_education, ''), COALESCE(father_name, ''), COALESCE(father_job, ''),
+			COALESCE(father_education, ''), contact_numbers, COALESCE(living_with, ''), created_at
 		FROM students
 		WHERE
 			deleted_at IS NULL
@@ -139,12 +134,13 @@ func (s *StudentStore) GetAll(ctx context.Context) ([]StudentWithAge, error) {
 		students = append(students, student)
 	}
 
-	return students, nil
+	return students, rows.Err()
 }
 
 func (s *StudentStore) GetDropdown(ctx context.Context) ([]models.StudentDropdown, error) {
 	query := `
-		SELECT s.id, s.first_name, s.middle_name, s.last_name, s.suffix, s.address, e.grade_level, e.school_year
+		SELECT s.id, s.first_name, s.middle_name, s.last_name, COALESCE(s.suffix, ''), s.address,
+			COALESCE(e.grade_level, ''), COALESCE(e.school_year, '')
 		FROM students s
 		LEFT JOIN LATERAL (
 			SELECT e.grade_level, e.school_year, e.created_at
@@ -165,6 +161,8 @@ func (s *StudentStore) GetDropdown(ctx context.Context) ([]models.StudentDropdow
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	var students []models.StudentDropdown
 
 	for rows.Next() {
@@ -186,5 +184,5 @@ func (s *StudentStore) GetDropdown(ctx context.Context) ([]models.StudentDropdow
 		students = append(students, student)
 	}
 
-	return students, nil
+	return students, rows.Err()
 }
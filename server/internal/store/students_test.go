@@ -0,0 +1,99 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestStudentStoreCreate(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	student := newTestStudent("Maria")
+	if err := s.Students.Create(ctx, student); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if student.ID == uuid.Nil || student.CreatedAt.IsZero() {
+		t.Fatalf("Create did not fill id and created_at: %+v", student)
+	}
+
+	t.Run("duplicate name, birthdate and gender", func(t *testing.T) {
+		duplicate := newTestStudent("MARIA")
+		assertErr(t, s.Students.Create(ctx, duplicate), ErrDuplicate)
+	})
+
+	t.Run("same name with a suffix", func(t *testing.T) {
+		junior := newTestStudent("Maria")
+		junior.Suffix = "Jr."
+		if err := s.Students.Create(ctx, junior); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	})
+}
+
+func TestStudentStoreGetAll(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"Maria", "Jose"} {
+		if err := s.Students.Create(ctx, newTestStudent(name)); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	students, err := s.Students.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+
+	if len(students) != 2 {
+		t.Fatalf("GetAll returned %d students, want 2", len(students))
+	}
+
+	for _, student := range students {
+		if student.Age < 10 {
+			t.Errorf("%s age = %d, want at least 10", student.FirstName, student.Age)
+		}
+		if student.FullName != student.FirstName+" Reyes Santos" {
+			t.Errorf("full name = %q", student.FullName)
+		}
+	}
+}
+
+func TestStudentStoreGetDropdown(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	enrollment := createTestEnrollment(t, s, "Maria")
+
+	if err := s.Students.Create(ctx, newTestStudent("Jose")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	students, err := s.Students.GetDropdown(ctx)
+	if err != nil {
+		t.Fatalf("GetDropdown: %v", err)
+	}
+
+	if len(students) != 2 {
+		t.Fatalf("GetDropdown returned %d students, want 2", len(students))
+	}
+
+	for _, student := range students {
+		switch student.ID {
+		case enrollment.Student.ID:
+			if student.SchoolYear != testSchoolYear || student.GradeLevel != "grade-1" {
+				t.Errorf("enrolled student = %+v, want latest enrollment", student)
+			}
+		default:
+			if student.SchoolYear != "" || student.GradeLevel != "" {
+				t.Errorf("unenrolled student = %+v, want no enrollment", student)
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/edzhabs/bookkeeping/internal/constants"
+	"github.com/edzhabs/bookkeeping/internal/models"
+	"github.com/google/uuid"
+)
+
+// GetWaitlist lists the waitlisted enrollments of a school year in the order
+// they will be promoted, optionally for a single grade level. Positions are
+// counted per grade level.
+func (s *EnrollmentStore) GetWaitlist(ctx context.Context, schoolYear, gradeLevel string) ([]models.WaitlistEntry, error) {
+	query := `
+		SELECT
+			e.id,
+			s.id,
+			TRIM(CONCAT_WS(' ',
+				s.first_name,
+				CASE
+					WHEN s.middle_name IS NOT NULL AND s.middle_name <> ''
+					THEN LEFT(s.middle_name, 1) || '.'
+					ELSE NULL
+				END,
+				s.last_name,
+				s.suffix
+			)) AS full_name,
+			e.school_year,
+			e.grade_level,
+			ROW_NUMBER() OVER (PARTITION BY e.grade_level ORDER BY e.waitlisted_at, e.id),
+			e.waitlisted_at
+		FROM enrollments e
+		JOIN students s ON s.id = e.student_id
+		WHERE e.deleted_at IS NULL AND e.status = $1 AND e.school_year = $2
+			AND ($3 = '' OR e.grade_level = $3)
+		ORDER BY e.grade_level, e.waitlisted_at, e.id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, constants.Waitlisted, schoolYear, gradeLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var waitlist []models.WaitlistEntry
+
+	for rows.Next() {
+		var entry models.WaitlistEntry
+		err := rows.Scan(
+			&entry.EnrollmentID,
+			&entry.StudentID,
+			&entry.FullName,
+			&entry.SchoolYear,
+			&entry.GradeLevel,
+			&entry.Position,
+			&entry.WaitlistedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		waitlist = append(waitlist, entry)
+	}
+
+	return waitlist, rows.Err()
+}
+
+// Promote enrolls a waitlisted student if their grade level has a free seat,
+// regardless of their place on the waitlist.
+func (s *EnrollmentStore) Promote(ctx context.Context, enrollmentID uuid.UUID) error {
+	return withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		query := `
+			SELECT school_year, grade_level, status
+			FROM enrollments
+			WHERE id = $1 AND deleted_at IS NULL
+			FOR UPDATE
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		var schoolYear, gradeLevel, status string
+		err := tx.QueryRowContext(ctx, query, enrollmentID).Scan(&schoolYear, &gradeLevel, &status)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		if status != constants.Waitlisted {
+			return ErrNotWaitlisted
+		}
+
+		if err := checkSchoolYearOpen(ctx, tx, schoolYear); err != nil {
+			return err
+		}
+
+		seats, err := gradeSeatsLeft(ctx, tx, schoolYear, gradeLevel)
+		if err != nil {
+			return err
+		}
+
+		if seats == 0 {
+			return ErrGradeFull
+		}
+
+		return s.promote(ctx, tx, enrollmentID)
+	})
+}
+
+// PromoteWaitlist fills the free seats of a grade level from the head of its
+// waitlist and returns the enrollments promoted.
+func (s *EnrollmentStore) PromoteWaitlist(ctx context.Context, schoolYear, gradeLevel string) ([]uuid.UUID, error) {
+	promoted := []uuid.UUID{}
+
+	err := withTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := checkSchoolYearOpen(ctx, tx, schoolYear); err != nil {
+			return err
+		}
+
+		seats, err := gradeSeatsLeft(ctx, tx, schoolYear, gradeLevel)
+		if err != nil {
+			return err
+		}
+
+		if seats == 0 {
+			return nil
+		}
+
+		limit := sql.NullInt64{Int64: int64(seats), Valid: seats != unlimitedSeats}
+
+		query := `
+			SELECT id
+			FROM enrollments
+			WHERE deleted_at IS NULL AND status = $1 AND school_year = $2 AND grade_level = $3
+			ORDER BY waitlisted_at, id
+			LIMIT $4
+			FOR UPDATE
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+		defer cancel()
+
+		rows, err := tx.QueryContext(ctx, query, constants.Waitlisted, schoolYear, gradeLevel, limit)
+		if err != nil {
+			return err
+		}
+
+		var ids []uuid.UUID
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if err := s.promote(ctx, tx, id); err != nil {
+				return err
+			}
+			promoted = append(promoted, id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return promoted, nil
+}
+
+// promote enrolls a waitlisted enrollment and posts its billing.
+func (s *EnrollmentStore) promote(ctx context.Context, tx *sql.Tx, enrollmentID uuid.UUID) error {
+	query := `
+		UPDATE enrollments
+		SET
+			status = $1,
+			version = version + 1,
+			updated_at = now()
+		WHERE id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeDuration)
+	defer cancel()
+
+	if _, err := tx.ExecContext(ctx, query, constants.Enrolled, enrollmentID); err != nil {
+		return parsePgError(err)
+	}
+
+	return syncEnrollmentLedger(ctx, tx, enrollmentID)
+}
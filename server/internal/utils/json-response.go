@@ -3,6 +3,7 @@ package utils
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 )
 
 var maxMb = 1_048_578
@@ -24,18 +25,43 @@ func ReadJSON(w http.ResponseWriter, r *http.Request, data any) error {
 	return decoder.Decode(data)
 }
 
-func ErrorJSON(w http.ResponseWriter, status int, message string) error {
+func ResponseJSON(w http.ResponseWriter, status int, data any) error {
 	type envelope struct {
-		Error string `json:"error"`
+		Data any `json:"data"`
 	}
 
-	return WriteJSON(w, status, &envelope{Error: message})
+	return WriteJSON(w, status, &envelope{Data: data})
 }
 
-func ResponseJSON(w http.ResponseWriter, status int, data any) error {
-	type envelope struct {
-		Data any `json:"data"`
+// Problem is an RFC 9457 problem details object. Code, RequestID, Errors and
+// Data are extension members.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	Errors    any    `json:"errors,omitempty"`
+	Data      any    `json:"data,omitempty"`
+}
+
+// NewProblem returns a problem whose type is derived from code, so every
+// occurrence of the same code shares a type and title.
+func NewProblem(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   "/problems/" + strings.ReplaceAll(code, "_", "-"),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
 	}
+}
 
-	return WriteJSON(w, status, &envelope{Data: data})
+func ProblemJSON(w http.ResponseWriter, problem *Problem) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+
+	return json.NewEncoder(w).Encode(problem)
 }
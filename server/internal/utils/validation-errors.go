@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Error codes returned per field. Clients match on these, so they must not
+// change once published.
+const (
+	CodeRequired          = "required"
+	CodeTooLong           = "too_long"
+	CodeTooShort          = "too_short"
+	CodeInvalidFormat     = "invalid_format"
+	CodeInvalidChoice     = "invalid_choice"
+	CodeInvalidType       = "invalid_type"
+	CodeUnknownField      = "unknown_field"
+	CodeUntrimmed         = "untrimmed"
+	CodeInvalidCharacters = "invalid_characters"
+	CodeInvalidSchoolYear = "invalid_school_year"
+	CodeInvalidDiscounts  = "invalid_discounts"
+	CodeInvalidBirthdate  = "invalid_birthdate"
+	CodeNotPositive       = "not_positive"
+	CodeInvalid           = "invalid"
+)
+
+type FieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// embeddedPrefix marks struct fields that encoding/json flattens into their
+// parent, so fieldPath can leave them out.
+const embeddedPrefix = "~"
+
+// jsonFieldName makes validator report fields by their JSON names.
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	switch {
+	case name == "-":
+		return ""
+	case name == "" && field.Anonymous:
+		return embeddedPrefix + field.Name
+	case name == "":
+		return field.Name
+	}
+	return name
+}
+
+// FieldErrors maps request decoding and validation errors to field paths
+// such as "enrollment.school_year". It returns false for any other error.
+func FieldErrors(err error) (map[string]FieldError, bool) {
+	var (
+		validationErrs validator.ValidationErrors
+		typeErr        *json.UnmarshalTypeError
+	)
+
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make(map[string]FieldError, len(validationErrs))
+		for _, fe := range validationErrs {
+			path := fieldPath(fe.Namespace())
+			if _, exists := fields[path]; !exists {
+				fields[path] = describe(fe)
+			}
+		}
+		return fields, true
+	case errors.As(err, &typeErr):
+		return map[string]FieldError{
+			typeErr.Field: {Code: CodeInvalidType, Message: "must be " + jsonType(typeErr.Type)},
+		}, true
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		name := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return map[string]FieldError{
+			name: {Code: CodeUnknownField, Message: "is not a recognised field"},
+		}, true
+	}
+
+	return nil, false
+}
+
+// fieldPath turns a validator namespace into the JSON path of the field,
+// dropping the payload struct name and embedded structs.
+func fieldPath(namespace string) string {
+	segments := strings.Split(namespace, ".")[1:]
+
+	path := segments[:0]
+	for _, segment := range segments {
+		if !strings.HasPrefix(segment, embeddedPrefix) {
+			path = append(path, segment)
+		}
+	}
+
+	return strings.Join(path, ".")
+}
+
+func describe(fe validator.FieldError) FieldError {
+	param := fe.Param()
+
+	switch fe.Tag() {
+	case "required", "required_if", "required_with":
+		return FieldError{CodeRequired, "is required"}
+	case "max":
+		if fe.Kind() == reflect.Slice {
+			return FieldError{CodeTooLong, fmt.Sprintf("must have at most %s items", param)}
+		}
+		return FieldError{CodeTooLong, fmt.Sprintf("must be at most %s characters", param)}
+	case "min":
+		if fe.Kind() == reflect.Slice {
+			return FieldError{CodeTooShort, fmt.Sprintf("must have at least %s items", param)}
+		}
+		return FieldError{CodeTooShort, fmt.Sprintf("must be at least %s characters", param)}
+	case "datetime":
+		return FieldError{CodeInvalidFormat, "must be a date formatted as " + dateFormat(param)}
+	case "numeric":
+		return FieldError{CodeInvalidFormat, "must contain only digits"}
+	case "oneof", "oneofci":
+		return FieldError{CodeInvalidChoice, "must be one of: " + strings.Join(strings.Fields(param), ", ")}
+	case "trimmedSpace":
+		return FieldError{CodeUntrimmed, "must not start or end with spaces"}
+	case "alpha_with_spaces":
+		return FieldError{CodeInvalidCharacters, "must contain only letters and spaces"}
+	case "schoolyear":
+		return FieldError{CodeInvalidSchoolYear, "must be consecutive years formatted as YYYY-YYYY, e.g. 2024-2025"}
+	case "discounts":
+		return FieldError{CodeInvalidDiscounts, "must be rank_1, sibling, full_year, scholar or carpool; scholar, sibling and full_year cannot be combined, and carpool cannot be combined with any other"}
+	case "validBirthdate":
+		return FieldError{CodeInvalidBirthdate, "must be a date formatted as YYYY-MM-DD and not in the future"}
+	case "decimalGt", "gt":
+		return FieldError{CodeNotPositive, "must be greater than zero"}
+	case "sortfq":
+		return FieldError{CodeInvalidChoice, "must be one of: asc, desc"}
+	}
+
+	return FieldError{CodeInvalid, "is invalid"}
+}
+
+func dateFormat(layout string) string {
+	return strings.NewReplacer("2006", "YYYY", "01", "MM", "02", "DD").Replace(layout)
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	default:
+		return "a number"
+	}
+}
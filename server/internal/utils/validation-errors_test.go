@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+type testStudent struct {
+	FirstName string `json:"first_name" validate:"required,alpha_with_spaces,trimmedSpace,max=100"`
+	LastName  string `json:"last_name" validate:"required,alpha_with_spaces,trimmedSpace,max=100"`
+	Birthdate string `json:"birthdate" validate:"required,validBirthdate"`
+}
+
+type testEnrollment struct {
+	SchoolYear     string          `json:"school_year" validate:"required,schoolyear"`
+	GradeLevel     string          `json:"grade_level" validate:"oneofci=grade-1 grade-2"`
+	MonthlyTuition decimal.Decimal `json:"monthly_tuition" validate:"required,decimalGt"`
+	Discounts      []string        `json:"discounts" validate:"omitempty,max=2"`
+}
+
+// testFees is embedded, so encoding/json flattens its fields into the
+// payload and their paths must not mention it.
+type testFees struct {
+	PaymentDate string `json:"payment_date" validate:"required,datetime=2006-01-02"`
+	Invoice     string `json:"invoice_number" validate:"required,numeric,min=3"`
+}
+
+type testPayload struct {
+	testFees
+	Enrollment testEnrollment `json:"enrollment"`
+	Student    testStudent    `json:"student"`
+	Remarks    string         `validate:"max=5"`
+}
+
+func validPayload() testPayload {
+	return testPayload{
+		testFees: testFees{PaymentDate: "2025-06-16", Invoice: "1001"},
+		Enrollment: testEnrollment{
+			SchoolYear:     "2025-2026",
+			GradeLevel:     "grade-1",
+			MonthlyTuition: decimal.NewFromInt(1500),
+		},
+		Student: testStudent{FirstName: "Maria", LastName: "Santos", Birthdate: "2019-03-01"},
+	}
+}
+
+func TestFieldErrorsValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *testPayload)
+		want   map[string]string
+	}{
+		{
+			name:   "valid payload",
+			modify: func(p *testPayload) {},
+			want:   map[string]string{},
+		},
+		{
+			name: "nested fields",
+			modify: func(p *testPayload) {
+				p.Enrollment.SchoolYear = "2025-2027"
+				p.Student.FirstName = ""
+			},
+			want: map[string]string{
+				"enrollment.school_year": CodeInvalidSchoolYear,
+				"student.first_name":     CodeRequired,
+			},
+		},
+		{
+			name: "embedded struct fields are flattened",
+			modify: func(p *testPayload) {
+				p.PaymentDate = "16/06/2025"
+				p.Invoice = "12"
+			},
+			want: map[string]string{
+				"payment_date":   CodeInvalidFormat,
+				"invoice_number": CodeTooShort,
+			},
+		},
+		{
+			name: "first failing rule of a field wins",
+			modify: func(p *testPayload) {
+				p.Student.LastName = "Santos2 "
+			},
+			want: map[string]string{
+				"student.last_name": CodeInvalidCharacters,
+			},
+		},
+		{
+			name: "codes by rule",
+			modify: func(p *testPayload) {
+				p.Enrollment.GradeLevel = "grade-9"
+				p.Enrollment.MonthlyTuition = decimal.NewFromInt(-1)
+				p.Enrollment.Discounts = []string{"rank_1", "sibling", "carpool"}
+				p.Student.Birthdate = "2999-01-01"
+			},
+			want: map[string]string{
+				"enrollment.grade_level":     CodeInvalidChoice,
+				"enrollment.monthly_tuition": CodeNotPositive,
+				"enrollment.discounts":       CodeTooLong,
+				"student.birthdate":          CodeInvalidBirthdate,
+			},
+		},
+		{
+			name: "fields without a JSON name keep their Go name",
+			modify: func(p *testPayload) {
+				p.Remarks = "too long"
+			},
+			want: map[string]string{
+				"Remarks": CodeTooLong,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := validPayload()
+			tt.modify(&payload)
+
+			err := Validate.Struct(payload)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+
+			fields, ok := FieldErrors(err)
+			if !ok {
+				t.Fatalf("FieldErrors(%v) not recognised", err)
+			}
+
+			got := make(map[string]string, len(fields))
+			for path, fe := range fields {
+				got[path] = fe.Code
+				if fe.Message == "" {
+					t.Errorf("%s has no message", path)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("codes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldErrorsDecoding(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want map[string]FieldError
+	}{
+		{
+			name: "unknown field",
+			body: `{"enrollment": {"school_year": "2025-2026"}, "nickname": "Mia"}`,
+			want: map[string]FieldError{
+				"nickname": {Code: CodeUnknownField, Message: "is not a recognised field"},
+			},
+		},
+		{
+			name: "wrong type in a nested object",
+			body: `{"enrollment": {"school_year": 2025}}`,
+			want: map[string]FieldError{
+				"enrollment.school_year": {Code: CodeInvalidType, Message: "must be a string"},
+			},
+		},
+		{
+			name: "object where a list is expected",
+			body: `{"enrollment": {"discounts": {}}}`,
+			want: map[string]FieldError{
+				"enrollment.discounts": {Code: CodeInvalidType, Message: "must be a list"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			var payload testPayload
+			err := ReadJSON(w, r, &payload)
+			if err == nil {
+				t.Fatal("ReadJSON succeeded, want an error")
+			}
+
+			fields, ok := FieldErrors(err)
+			if !ok {
+				t.Fatalf("FieldErrors(%v) not recognised", err)
+			}
+			if !reflect.DeepEqual(fields, tt.want) {
+				t.Errorf("FieldErrors = %v, want %v", fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldErrorsOtherErrors(t *testing.T) {
+	for _, err := range []error{
+		errors.New("boom"),
+		&json.SyntaxError{},
+	} {
+		if fields, ok := FieldErrors(err); ok {
+			t.Errorf("FieldErrors(%v) = %v, want not recognised", err, fields)
+		}
+	}
+}
+
+func TestFieldPath(t *testing.T) {
+	tests := []struct {
+		namespace string
+		want      string
+	}{
+		{"testPayload.enrollment.school_year", "enrollment.school_year"},
+		{"testPayload.~testFees.payment_date", "payment_date"},
+		{"testPayload.student.~Name.first_name", "student.first_name"},
+		{"testPayload.guardians[1].email", "guardians[1].email"},
+	}
+
+	for _, tt := range tests {
+		if got := fieldPath(tt.namespace); got != tt.want {
+			t.Errorf("fieldPath(%q) = %q, want %q", tt.namespace, got, tt.want)
+		}
+	}
+}
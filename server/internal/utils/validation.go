@@ -13,6 +13,7 @@ var Validate *validator.Validate
 
 func init() {
 	Validate = validator.New(validator.WithRequiredStructEnabled())
+	Validate.RegisterTagNameFunc(jsonFieldName)
 	Validate.RegisterValidation("trimmedSpace", trimmedSpace)
 	Validate.RegisterValidation("validBirthdate", validBirthdate)
 	Validate.RegisterValidation("alpha_with_spaces", validateAlphaWithSpaces)